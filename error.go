@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidCommandError = &microerror.Error{
+	Kind: "invalidCommandError",
+}
+
+// IsInvalidCommand asserts invalidCommandError.
+func IsInvalidCommand(err error) bool {
+	return microerror.Cause(err) == invalidCommandError
+}
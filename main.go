@@ -1,3 +1,70 @@
 package main
 
-func main() {}
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/alerts"
+	"github.com/giantswarm/badnodedetector/v3/pkg/detector"
+)
+
+const usage = `Usage: badnodedetector <command> [flags]
+
+Commands:
+  generate-alerts  Print a ConfigMap with Prometheus alerting rules to stdout.
+`
+
+func main() {
+	err := mainE(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func mainE(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return microerror.Maskf(invalidCommandError, "missing command")
+	}
+
+	switch args[0] {
+	case "generate-alerts":
+		return generateAlerts(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return microerror.Maskf(invalidCommandError, "unknown command %q", args[0])
+	}
+}
+
+func generateAlerts(args []string) error {
+	flags := flag.NewFlagSet("generate-alerts", flag.ContinueOnError)
+	namespace := flags.String("namespace", "monitoring", "Namespace of the generated ConfigMap.")
+	threshold := flags.Int("not-ready-tick-threshold", detector.DefaultNotReadyTickThreshold, "NotReadyTickThreshold the detector is configured with.")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return microerror.Maskf(invalidCommandError, "%s", err.Error())
+	}
+
+	configMap, err := alerts.GeneratePrometheusRules(detector.Config{NotReadyTickThreshold: *threshold}, *namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	data, err := yaml.Marshal(configMap)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = os.Stdout.Write(data)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package alerts generates Prometheus alerting rules for the bad node detection.
+package alerts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/detector"
+)
+
+const (
+	// ConfigMapName is the name of the generated ConfigMap.
+	ConfigMapName = "badnodedetector-alerts"
+	// RulesKey is the ConfigMap data key containing the Prometheus rules file.
+	RulesKey = "badnodedetector.rules.yaml"
+
+	// tickCountWarningPercentage defines at which percentage of the tick threshold a warning fires.
+	tickCountWarningPercentage = 80
+	// badNodesDetectedWindow defines the range in which newly detected bad nodes fire an alert.
+	badNodesDetectedWindow = time.Minute * 10
+)
+
+type ruleFile struct {
+	Groups []ruleGroup `json:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string `json:"name"`
+	Rules []rule `json:"rules"`
+}
+
+type rule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// GeneratePrometheusRules returns a ConfigMap in the given namespace containing Prometheus alerting rules
+// for the detector configured with config. The rules expect the metrics detector.BadNodesDetectedMetricName
+// and detector.NodeNotReadyTickCountMetricName to be exposed via the detector Metrics.
+func GeneratePrometheusRules(config detector.Config, namespace string) (*corev1.ConfigMap, error) {
+	if namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "namespace must not be empty")
+	}
+
+	threshold := config.NotReadyTickThreshold
+	if threshold == 0 {
+		threshold = detector.DefaultNotReadyTickThreshold
+	}
+	if threshold < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NotReadyTickThreshold must not be negative", config)
+	}
+
+	rules := ruleFile{
+		Groups: []ruleGroup{
+			{
+				Name: "badnodedetector",
+				Rules: []rule{
+					{
+						Alert: "BadNodesDetected",
+						Expr:  fmt.Sprintf("increase(%s[%s]) > 0", detector.BadNodesDetectedMetricName, promDuration(badNodesDetectedWindow)),
+						Labels: map[string]string{
+							"severity": "notify",
+						},
+						Annotations: map[string]string{
+							"description": "Nodes were marked for termination by the bad node detector.",
+						},
+					},
+					{
+						Alert: "NodeNotReadyTickCountHigh",
+						Expr:  fmt.Sprintf("%s >= %g", detector.NodeNotReadyTickCountMetricName, float64(threshold*tickCountWarningPercentage)/100),
+						Labels: map[string]string{
+							"severity": "notify",
+						},
+						Annotations: map[string]string{
+							"description": fmt.Sprintf("Node {{ $labels.node }} is close to the not ready tick threshold of %d.", threshold),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := yaml.Marshal(rules)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConfigMapName,
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			RulesKey: string(data),
+		},
+	}
+
+	return configMap, nil
+}
+
+// promDuration formats the duration in seconds as accepted by Prometheus.
+func promDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
@@ -0,0 +1,97 @@
+package alerts
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/detector"
+)
+
+func Test_GeneratePrometheusRules(t *testing.T) {
+	testCases := []struct {
+		name             string
+		config           detector.Config
+		namespace        string
+		expectedTickExpr string
+		expectedAlerts   []string
+		errorMatcher     func(error) bool
+	}{
+		{
+			name:             "test 0 - default threshold",
+			config:           detector.Config{},
+			namespace:        "monitoring",
+			expectedTickExpr: "badnodedetector_node_not_ready_tick_count >= 4.8",
+			expectedAlerts:   []string{"BadNodesDetected", "NodeNotReadyTickCountHigh"},
+		},
+		{
+			name:             "test 1 - custom threshold",
+			config:           detector.Config{NotReadyTickThreshold: 10},
+			namespace:        "monitoring",
+			expectedTickExpr: "badnodedetector_node_not_ready_tick_count >= 8",
+			expectedAlerts:   []string{"BadNodesDetected", "NodeNotReadyTickCountHigh"},
+		},
+		{
+			name:         "test 2 - missing namespace",
+			config:       detector.Config{},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "test 3 - negative threshold",
+			config:       detector.Config{NotReadyTickThreshold: -1},
+			namespace:    "monitoring",
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			configMap, err := GeneratePrometheusRules(tc.config, tc.namespace)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if configMap.Namespace != tc.namespace {
+				t.Fatalf("Expected namespace '%s' but got '%s'.\n", tc.namespace, configMap.Namespace)
+			}
+
+			var rules ruleFile
+			err = yaml.Unmarshal([]byte(configMap.Data[RulesKey]), &rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var alerts []string
+			var tickExpr string
+			for _, r := range rules.Groups[0].Rules {
+				alerts = append(alerts, r.Alert)
+				if strings.HasPrefix(r.Expr, "badnodedetector_node_not_ready_tick_count") {
+					tickExpr = r.Expr
+				}
+			}
+
+			if strings.Join(alerts, ",") != strings.Join(tc.expectedAlerts, ",") {
+				t.Fatalf("Expected alerts '%v' but got '%v'.\n", tc.expectedAlerts, alerts)
+			}
+			if tickExpr != tc.expectedTickExpr {
+				t.Fatalf("Expected expression '%s' but got '%s'.\n", tc.expectedTickExpr, tickExpr)
+			}
+		})
+	}
+}
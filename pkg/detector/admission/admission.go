@@ -0,0 +1,217 @@
+// Package admission provides a validating admission webhook for Node objects which rejects
+// tampering with the not ready tick annotation used by the detector.
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultMaxAllowedTickIncrease = 1
+	defaultPort                   = 8443
+	defaultCertFile               = "/etc/webhook/certs/tls.crt"
+	defaultKeyFile                = "/etc/webhook/certs/tls.key"
+	defaultTickAnnotationKey      = "giantswarm.io/node-not-ready-tick"
+
+	// maxRequestBodySize limits the size of an AdmissionReview read from a request.
+	maxRequestBodySize = 3 * 1024 * 1024
+	shutdownTimeout    = time.Second * 10
+)
+
+type Config struct {
+	Logger micrologger.Logger
+
+	// Port defines the port the webhook server listens on. Defaults to 8443.
+	Port int
+	// CertFile and KeyFile define the paths of the TLS certificate and key, usually mounted from a Secret.
+	// Default to `/etc/webhook/certs/tls.crt` and `/etc/webhook/certs/tls.key`.
+	CertFile string
+	KeyFile  string
+	// MaxAllowedTickIncrease defines by how much the tick annotation can be increased by a single request.
+	// Defaults to 1, which is what the detector increases the tick count by in a single run.
+	MaxAllowedTickIncrease int
+	// TickAnnotationKey defines the validated node annotation, it must match the key used by the detector.
+	// Defaults to `giantswarm.io/node-not-ready-tick`.
+	TickAnnotationKey string
+}
+
+// Webhook validates Node UPDATE operations and rejects changes of the tick annotation
+// which increase the tick count more than the detector would do.
+type Webhook struct {
+	logger micrologger.Logger
+
+	port                   int
+	certFile               string
+	keyFile                string
+	maxAllowedTickIncrease int
+	tickAnnotationKey      string
+}
+
+func NewWebhook(config Config) (*Webhook, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Port == 0 {
+		config.Port = defaultPort
+	}
+	if config.CertFile == "" {
+		config.CertFile = defaultCertFile
+	}
+	if config.KeyFile == "" {
+		config.KeyFile = defaultKeyFile
+	}
+	if config.MaxAllowedTickIncrease == 0 {
+		config.MaxAllowedTickIncrease = defaultMaxAllowedTickIncrease
+	}
+	if config.TickAnnotationKey == "" {
+		config.TickAnnotationKey = defaultTickAnnotationKey
+	}
+	if config.Port < 0 || config.Port > 65535 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Port must be a valid port", config)
+	}
+	if config.MaxAllowedTickIncrease < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxAllowedTickIncrease must not be negative", config)
+	}
+
+	w := &Webhook{
+		logger: config.Logger,
+
+		port:                   config.Port,
+		certFile:               config.CertFile,
+		keyFile:                config.KeyFile,
+		maxAllowedTickIncrease: config.MaxAllowedTickIncrease,
+		tickAnnotationKey:      config.TickAnnotationKey,
+	}
+
+	return w, nil
+}
+
+// Run serves the webhook with TLS until the context is cancelled.
+func (w *Webhook) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", w.port),
+		Handler: w,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServeTLS(w.certFile, w.keyFile)
+	}()
+
+	select {
+	case err := <-errs:
+		return microerror.Mask(err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		return nil
+	}
+}
+
+// ServeHTTP handles an AdmissionReview request.
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxRequestBodySize))
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("failed to read request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	err = json.Unmarshal(body, &review)
+	if err != nil || review.Request == nil {
+		http.Error(rw, "failed to decode admission review", http.StatusBadRequest)
+		return
+	}
+
+	response := w.review(ctx, review.Request)
+	response.UID = review.Request.UID
+
+	review.Response = response
+	review.Request = nil
+
+	data, err := json.Marshal(review)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("failed to encode admission review: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_, err = rw.Write(data)
+	if err != nil {
+		w.logger.Errorf(ctx, err, "failed to write admission response")
+	}
+}
+
+// review allows all requests apart from Node updates which increase the tick count too much.
+func (w *Webhook) review(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Update || req.Kind.Kind != "Node" {
+		return allowed()
+	}
+
+	var oldNode, newNode corev1.Node
+	err := json.Unmarshal(req.OldObject.Raw, &oldNode)
+	if err != nil {
+		return denied(fmt.Sprintf("failed to decode old node: %s", err))
+	}
+	err = json.Unmarshal(req.Object.Raw, &newNode)
+	if err != nil {
+		return denied(fmt.Sprintf("failed to decode node: %s", err))
+	}
+
+	oldTick := tickCount(oldNode, w.tickAnnotationKey)
+	newTick := tickCount(newNode, w.tickAnnotationKey)
+	if newTick-oldTick > w.maxAllowedTickIncrease {
+		w.logger.Debugf(ctx, "rejected update of node %s by %s increasing annotation %s from %d to %d", newNode.Name, req.UserInfo.Username, w.tickAnnotationKey, oldTick, newTick)
+		return denied(fmt.Sprintf("annotation %s must not be increased by more than %d, but was increased from %d to %d", w.tickAnnotationKey, w.maxAllowedTickIncrease, oldTick, newTick))
+	}
+
+	return allowed()
+}
+
+// tickCount returns the tick count of the node, missing or invalid values count as 0
+// the same way as the detector treats them.
+func tickCount(n corev1.Node, tickAnnotationKey string) int {
+	tick, err := strconv.Atoi(n.Annotations[tickAnnotationKey])
+	if err != nil {
+		return 0
+	}
+	return tick
+}
+
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: true,
+	}
+}
+
+func denied(reason string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: reason,
+			Code:    http.StatusForbidden,
+		},
+	}
+}
@@ -0,0 +1,248 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func Test_NewWebhook(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       func(logger micrologger.Logger) Config
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "test 0 - defaults",
+			config: func(logger micrologger.Logger) Config {
+				return Config{Logger: logger}
+			},
+		},
+		{
+			name: "test 1 - missing logger",
+			config: func(logger micrologger.Logger) Config {
+				return Config{}
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "test 2 - negative max allowed tick increase",
+			config: func(logger micrologger.Logger) Config {
+				return Config{Logger: logger, MaxAllowedTickIncrease: -1}
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "test 3 - invalid port",
+			config: func(logger micrologger.Logger) Config {
+				return Config{Logger: logger, Port: 70000}
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			_, err := NewWebhook(tc.config(logger))
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_Webhook_ServeHTTP(t *testing.T) {
+	newNode := func(tick string) runtime.RawExtension {
+		node := corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "worker1",
+			},
+		}
+		if tick != "" {
+			node.Annotations = map[string]string{
+				defaultTickAnnotationKey: tick,
+			}
+		}
+
+		data, err := json.Marshal(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+
+	testCases := []struct {
+		name                   string
+		operation              admissionv1.Operation
+		oldTick                string
+		newTick                string
+		maxAllowedTickIncrease int
+		expectedAllowed        bool
+	}{
+		{
+			name:            "test 0 - tick increased by one",
+			operation:       admissionv1.Update,
+			oldTick:         "2",
+			newTick:         "3",
+			expectedAllowed: true,
+		},
+		{
+			name:            "test 1 - tick added",
+			operation:       admissionv1.Update,
+			oldTick:         "",
+			newTick:         "1",
+			expectedAllowed: true,
+		},
+		{
+			name:            "test 2 - tick increased by more than one",
+			operation:       admissionv1.Update,
+			oldTick:         "1",
+			newTick:         "100",
+			expectedAllowed: false,
+		},
+		{
+			name:            "test 3 - tick added with high value",
+			operation:       admissionv1.Update,
+			oldTick:         "",
+			newTick:         "6",
+			expectedAllowed: false,
+		},
+		{
+			name:            "test 4 - tick decreased",
+			operation:       admissionv1.Update,
+			oldTick:         "5",
+			newTick:         "4",
+			expectedAllowed: true,
+		},
+		{
+			name:            "test 5 - tick reset",
+			operation:       admissionv1.Update,
+			oldTick:         "5",
+			newTick:         "0",
+			expectedAllowed: true,
+		},
+		{
+			name:            "test 6 - tick removed",
+			operation:       admissionv1.Update,
+			oldTick:         "5",
+			newTick:         "",
+			expectedAllowed: true,
+		},
+		{
+			name:                   "test 7 - tick increased within custom max allowed tick increase",
+			operation:              admissionv1.Update,
+			oldTick:                "1",
+			newTick:                "4",
+			maxAllowedTickIncrease: 3,
+			expectedAllowed:        true,
+		},
+		{
+			name:            "test 8 - garbage tick replaced",
+			operation:       admissionv1.Update,
+			oldTick:         "asdefg",
+			newTick:         "1",
+			expectedAllowed: true,
+		},
+		{
+			name:            "test 9 - create is not validated",
+			operation:       admissionv1.Create,
+			oldTick:         "",
+			newTick:         "100",
+			expectedAllowed: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			w, err := NewWebhook(Config{
+				Logger:                 logger,
+				MaxAllowedTickIncrease: tc.maxAllowedTickIncrease,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			review := admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admission.k8s.io/v1",
+					Kind:       "AdmissionReview",
+				},
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "705ab4f5-6393-11e8-b7cc-42010a800002",
+					Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Node"},
+					Operation: tc.operation,
+					OldObject: newNode(tc.oldTick),
+					Object:    newNode(tc.newTick),
+				},
+			}
+			body, err := json.Marshal(review)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			w.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("Expected status code '%d' but got '%d'.\n", http.StatusOK, recorder.Code)
+			}
+
+			var response admissionv1.AdmissionReview
+			err = json.Unmarshal(recorder.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Response.UID != review.Request.UID {
+				t.Fatalf("Expected uid '%s' but got '%s'.\n", review.Request.UID, response.Response.UID)
+			}
+			if response.Response.Allowed != tc.expectedAllowed {
+				t.Fatalf("Expected allowed '%t' but got '%t'.\n", tc.expectedAllowed, response.Response.Allowed)
+			}
+			if !tc.expectedAllowed && response.Response.Result.Message == "" {
+				t.Fatalf("Expected a reason for the rejection but got none.\n")
+			}
+		})
+	}
+}
+
+func Test_Webhook_ServeHTTP_invalidRequest(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	w, err := NewWebhook(Config{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	w.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader([]byte("{}"))))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code '%d' but got '%d'.\n", http.StatusBadRequest, recorder.Code)
+	}
+}
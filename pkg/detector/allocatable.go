@@ -0,0 +1,83 @@
+package detector
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// allocatablePressure returns the first resource of the node whose allocatable dropped below the configured
+// fraction of its capacity. Resources without a reported capacity are ignored.
+func allocatablePressure(n corev1.Node, minFractions map[corev1.ResourceName]float64) (corev1.ResourceName, bool) {
+	for _, name := range sortedResourceNames(minFractions) {
+		capacity, ok := n.Status.Capacity[name]
+		if !ok || capacity.IsZero() {
+			continue
+		}
+		allocatable := n.Status.Allocatable[name]
+
+		if float64(allocatable.MilliValue()) < float64(capacity.MilliValue())*minFractions[name] {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// nodeAllocatablePressureSince tracks since when the allocatable of the node is below the configured fraction
+// of its capacity via the allocatable-pressure-since annotation.
+// The returned value indicates if the annotations of the node changed and need to be updated.
+func nodeAllocatablePressureSince(n *corev1.Node, minFractions map[corev1.ResourceName]float64, now time.Time) bool {
+	since, ok := n.Annotations[annotationNodeAllocatablePressureSince]
+
+	if _, pressure := allocatablePressure(*n, minFractions); !pressure {
+		// allocatable recovered, the timestamp is not valid anymore
+		if ok {
+			delete(n.Annotations, annotationNodeAllocatablePressureSince)
+			return true
+		}
+		return false
+	}
+
+	_, err := time.Parse(time.RFC3339, since)
+	// first time we see the pressure or the annotation is a garbage, lets start tracking now
+	if !ok || err != nil {
+		setAnnotation(n, annotationNodeAllocatablePressureSince, now.UTC().Format(time.RFC3339))
+		return true
+	}
+
+	return false
+}
+
+// isNodeAllocatableCollapsed returns true if the allocatable of the node is below the configured fraction
+// of its capacity for at least the allocatable pressure duration.
+func (h nodeHealthCheck) isNodeAllocatableCollapsed(ctx context.Context, logger micrologger.Logger, n corev1.Node) bool {
+	if len(h.minAllocatableFraction) == 0 {
+		return false
+	}
+
+	name, pressure := allocatablePressure(n, h.minAllocatableFraction)
+	if !pressure {
+		return false
+	}
+
+	since, err := time.Parse(time.RFC3339, n.Annotations[annotationNodeAllocatablePressureSince])
+	if err != nil || h.clock.Now().Sub(since) < h.allocatablePressureDuration {
+		return false
+	}
+
+	logger.Debugf(ctx, "node %s is unhealthy because the allocatable %s is below %.2f of its capacity since %s", n.Name, name, h.minAllocatableFraction[name], since.Format(time.RFC3339))
+	return true
+}
+
+func sortedResourceNames(m map[corev1.ResourceName]float64) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
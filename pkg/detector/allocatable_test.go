@@ -0,0 +1,167 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func withMemory(n corev1.Node, capacity, allocatable string) corev1.Node {
+	n.Status.Capacity = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(capacity)}
+	n.Status.Allocatable = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(allocatable)}
+	return n
+}
+
+func Test_nodeAllocatablePressureSince(t *testing.T) {
+	now := testNow
+	minFractions := map[corev1.ResourceName]float64{corev1.ResourceMemory: 0.1}
+
+	testCases := []struct {
+		name                string
+		node                corev1.Node
+		expectedUpdated     bool
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:            "test 0 - allocatable memory healthy",
+			node:            withMemory(testNode("worker1").Build(), "16Gi", "15Gi"),
+			expectedUpdated: false,
+		},
+		{
+			name:            "test 1 - allocatable memory collapsed for the first time",
+			node:            withMemory(testNode("worker1").Build(), "16Gi", "100Mi"),
+			expectedUpdated: true,
+			expectedAnnotations: map[string]string{
+				annotationNodeAllocatablePressureSince: "2023-11-09T12:00:00Z",
+			},
+		},
+		{
+			name: "test 2 - allocatable memory already collapsed",
+			node: withMemory(testNode("worker1").
+				WithAnnotation(annotationNodeAllocatablePressureSince, "2023-11-09T11:55:00Z").
+				Build(), "16Gi", "100Mi"),
+			expectedUpdated: false,
+			expectedAnnotations: map[string]string{
+				annotationNodeAllocatablePressureSince: "2023-11-09T11:55:00Z",
+			},
+		},
+		{
+			name: "test 3 - allocatable memory recovered",
+			node: withMemory(testNode("worker1").
+				WithAnnotation(annotationNodeAllocatablePressureSince, "2023-11-09T11:55:00Z").
+				Build(), "16Gi", "15Gi"),
+			expectedUpdated:     true,
+			expectedAnnotations: map[string]string{},
+		},
+		{
+			name:            "test 4 - node without reported capacity",
+			node:            testNode("worker1").Build(),
+			expectedUpdated: false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			updated := nodeAllocatablePressureSince(&tc.node, minFractions, now)
+			if updated != tc.expectedUpdated {
+				t.Fatalf("Expected updated '%t' but got '%t'.\n", tc.expectedUpdated, updated)
+			}
+
+			for k, v := range tc.expectedAnnotations {
+				if tc.node.Annotations[k] != v {
+					t.Fatalf("Expected annotation %s '%s' but got '%s'.\n", k, v, tc.node.Annotations[k])
+				}
+			}
+			if len(tc.node.Annotations) != len(tc.expectedAnnotations) {
+				t.Fatalf("Expected '%d' annotations but got '%d'.\n", len(tc.expectedAnnotations), len(tc.node.Annotations))
+			}
+		})
+	}
+}
+
+func Test_nodeHealthCheck_allocatableCollapsed(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		node                   corev1.Node
+		minAllocatableFraction map[corev1.ResourceName]float64
+		expectedUnhealthy      bool
+	}{
+		{
+			name: "test 0 - collapsed allocatable memory - heuristic disabled",
+			node: withMemory(testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute).
+				WithAnnotation(annotationNodeAllocatablePressureSince, "2023-11-09T11:55:00Z").
+				Build(), "16Gi", "100Mi"),
+			minAllocatableFraction: nil,
+			expectedUnhealthy:      false,
+		},
+		{
+			name: "test 1 - collapsed allocatable memory for longer than the duration",
+			node: withMemory(testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute).
+				WithAnnotation(annotationNodeAllocatablePressureSince, "2023-11-09T11:55:00Z").
+				Build(), "16Gi", "100Mi"),
+			minAllocatableFraction: map[corev1.ResourceName]float64{corev1.ResourceMemory: 0.1},
+			expectedUnhealthy:      true,
+		},
+		{
+			name: "test 2 - collapsed allocatable memory not sustained yet",
+			node: withMemory(testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute).
+				WithAnnotation(annotationNodeAllocatablePressureSince, "2023-11-09T11:59:50Z").
+				Build(), "16Gi", "100Mi"),
+			minAllocatableFraction: map[corev1.ResourceName]float64{corev1.ResourceMemory: 0.1},
+			expectedUnhealthy:      false,
+		},
+		{
+			name: "test 3 - healthy allocatable memory",
+			node: withMemory(testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute).
+				WithAnnotation(annotationNodeAllocatablePressureSince, "2023-11-09T11:55:00Z").
+				Build(), "16Gi", "15Gi"),
+			minAllocatableFraction: map[corev1.ResourceName]float64{corev1.ResourceMemory: 0.1},
+			expectedUnhealthy:      false,
+		},
+		{
+			name: "test 4 - collapsed allocatable memory without tracking annotation",
+			node: withMemory(testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute).
+				Build(), "16Gi", "100Mi"),
+			minAllocatableFraction: map[corev1.ResourceName]float64{corev1.ResourceMemory: 0.1},
+			expectedUnhealthy:      false,
+		},
+		{
+			name: "test 5 - collapsed allocatable memory - only cpu is checked",
+			node: withMemory(testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute).
+				WithAnnotation(annotationNodeAllocatablePressureSince, "2023-11-09T11:55:00Z").
+				Build(), "16Gi", "100Mi"),
+			minAllocatableFraction: map[corev1.ResourceName]float64{corev1.ResourceCPU: 0.5},
+			expectedUnhealthy:      false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			h := testHealthCheck()
+			h.minAllocatableFraction = tc.minAllocatableFraction
+			h.allocatablePressureDuration = time.Minute
+
+			result := h.isNodeUnhealthy(context.Background(), logger, tc.node)
+			if result != tc.expectedUnhealthy {
+				t.Fatalf("Expected '%t' but got '%t'.\n", tc.expectedUnhealthy, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package detector
+
+import (
+	"context"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// AuditTickAnnotations returns the nodes which are healthy but carry a nonzero or invalid tick annotation,
+// ie: left behind by failed runs. The nodes are not modified, use ResetTickCounters to clean them up.
+func (d *Detector) AuditTickAnnotations(ctx context.Context) ([]corev1.Node, error) {
+	var orphanedNodes []corev1.Node
+	err := d.forEachNodePage(ctx, func(nodes []corev1.Node) error {
+		for _, n := range nodes {
+			tick, ok := n.Annotations[d.tickAnnotationKey]
+			if !ok || tick == "0" {
+				continue
+			}
+			if d.healthCheck.isNodeUnhealthy(ctx, d.logger, n) {
+				continue
+			}
+			orphanedNodes = append(orphanedNodes, n)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return orphanedNodes, nil
+}
+
+// ListNodesWithHighTicks returns the nodes with a tick count of at least threshold sorted by tick count in descending order,
+// ie: for dashboards. Nodes without or with an invalid tick annotation are not returned. The nodes are not modified.
+func (d *Detector) ListNodesWithHighTicks(ctx context.Context, threshold int) ([]corev1.Node, error) {
+	var highTickNodes []corev1.Node
+	err := d.forEachNodePage(ctx, func(nodes []corev1.Node) error {
+		for _, n := range nodes {
+			tick, err := parseTickCount(n.Annotations[d.tickAnnotationKey])
+			if err != nil || tick < threshold {
+				continue
+			}
+			highTickNodes = append(highTickNodes, n)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	sort.SliceStable(highTickNodes, func(i, j int) bool {
+		ti := nodeTickCount(highTickNodes[i], d.tickAnnotationKey)
+		tj := nodeTickCount(highTickNodes[j], d.tickAnnotationKey)
+		if ti != tj {
+			return ti > tj
+		}
+		return highTickNodes[i].Name < highTickNodes[j].Name
+	})
+
+	return highTickNodes, nil
+}
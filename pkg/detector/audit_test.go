@@ -0,0 +1,168 @@
+package detector
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_AuditTickAnnotations(t *testing.T) {
+	newNode := func(name string, tick string, ready bool) client.Object {
+		b := testNode(name)
+		if tick != "" {
+			b.WithAnnotation(annotationNodeNotReadyTick, tick)
+		}
+		if ready {
+			b.WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0)
+		} else {
+			b.WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10)
+		}
+		node := b.Build()
+		return &node
+	}
+
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		newNode("healthy-without-tick", "", true),
+		newNode("healthy-zero-tick", "0", true),
+		newNode("healthy-stale-tick", "3", true),
+		newNode("healthy-invalid-tick", "asdefg", true),
+		newNode("unhealthy-tick", "3", false),
+		newNode("unhealthy-without-tick", "", false),
+	).Build()
+
+	d, err := NewDetector(Config{
+		Clock:     &FakeClock{Time: testNow},
+		Logger:    logger,
+		K8sClient: k8sClient,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := d.AuditTickAnnotations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	sort.Strings(names)
+
+	expected := []string{"healthy-invalid-tick", "healthy-stale-tick"}
+	if !cmp.Equal(names, expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, names))
+	}
+
+	// the audit must not change any node
+	var node corev1.Node
+	err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "healthy-stale-tick"}, &node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Annotations[annotationNodeNotReadyTick] != "3" {
+		t.Fatalf("Expected tick count '3' but got '%s'.\n", node.Annotations[annotationNodeNotReadyTick])
+	}
+}
+
+func Test_ListNodesWithHighTicks(t *testing.T) {
+	newNode := func(name string, tick string) client.Object {
+		b := testNode(name).
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10)
+		if tick != "" {
+			b.WithAnnotation(annotationNodeNotReadyTick, tick)
+		}
+		node := b.Build()
+		return &node
+	}
+
+	testCases := []struct {
+		name          string
+		threshold     int
+		expectedNodes []string
+	}{
+		{
+			name:          "test 0 - nodes above threshold sorted by tick count",
+			threshold:     3,
+			expectedNodes: []string{"worker5", "worker3a", "worker3b"},
+		},
+		{
+			name:          "test 1 - zero threshold skips nodes without valid tick",
+			threshold:     0,
+			expectedNodes: []string{"worker5", "worker3a", "worker3b", "worker1", "worker0"},
+		},
+		{
+			name:          "test 2 - no nodes above threshold",
+			threshold:     6,
+			expectedNodes: nil,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			var calls []string
+			k8sClient := &recordingClient{
+				Client: fake.NewClientBuilder().WithObjects(
+					newNode("worker0", "0"),
+					newNode("worker1", "1"),
+					newNode("worker3b", "3"),
+					newNode("worker3a", "3"),
+					newNode("worker5", "5"),
+					newNode("without-tick", ""),
+					newNode("invalid-tick", "asdefg"),
+				).Build(),
+				calls: &calls,
+			}
+
+			d, err := NewDetector(Config{
+				Clock:     &FakeClock{Time: testNow},
+				Logger:    logger,
+				K8sClient: k8sClient,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			nodes, err := d.ListNodesWithHighTicks(context.Background(), tc.threshold)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, n := range nodes {
+				names = append(names, n.Name)
+			}
+			if !cmp.Equal(names, tc.expectedNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodes, names))
+			}
+
+			// the query must not change any node
+			if len(calls) != 0 {
+				t.Fatalf("Expected '%d' node updates but got '%d'.\n", 0, len(calls))
+			}
+			var node corev1.Node
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker3a"}, &node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if node.Annotations[annotationNodeNotReadyTick] != "3" {
+				t.Fatalf("Expected tick count '3' but got '%s'.\n", node.Annotations[annotationNodeNotReadyTick])
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package detector
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// nodeConditionCache remembers the conditions of nodes which were healthy with a zero tick count in the previous run
+// and whose conditions all had the healthy status. Nodes with an unhealthy status within its duration are not cached,
+// as they become unhealthy once the duration passed.
+// As long as the status of these conditions does not change, the health check would come to the same result,
+// so the evaluation can be skipped. The heartbeat times are not compared as they do not affect the result
+// for healthy conditions, but change on every kubelet status update.
+type nodeConditionCache struct {
+	mutex      sync.Mutex
+	conditions map[types.UID][]corev1.NodeCondition
+}
+
+func newNodeConditionCache() *nodeConditionCache {
+	return &nodeConditionCache{
+		conditions: map[types.UID][]corev1.NodeCondition{},
+	}
+}
+
+// unchanged returns true if the node was healthy in the previous run and its conditions did not change since.
+func (c *nodeConditionCache) unchanged(n corev1.Node) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cached, ok := c.conditions[n.UID]
+	if !ok || len(cached) != len(n.Status.Conditions) {
+		return false
+	}
+	for i := range cached {
+		if cached[i].Type != n.Status.Conditions[i].Type || cached[i].Status != n.Status.Conditions[i].Status {
+			return false
+		}
+	}
+	return true
+}
+
+// set remembers the conditions of the healthy node.
+func (c *nodeConditionCache) set(n corev1.Node) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.conditions[n.UID] = n.Status.Conditions
+}
+
+// delete forgets the node.
+func (c *nodeConditionCache) delete(n corev1.Node) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.conditions, n.UID)
+}
+
+// prune forgets all nodes which are not part of seen, ie: nodes removed from the cluster.
+func (c *nodeConditionCache) prune(seen map[types.UID]struct{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for uid := range c.conditions {
+		if _, ok := seen[uid]; !ok {
+			delete(c.conditions, uid)
+		}
+	}
+}
+
+// hasZeroTickCount returns true if the node has no tick annotation or a tick count of zero.
+func hasZeroTickCount(n corev1.Node, tickAnnotationKey string) bool {
+	tick, ok := n.Annotations[tickAnnotationKey]
+	return !ok || tick == "0"
+}
@@ -0,0 +1,239 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_nodeConditionCache(t *testing.T) {
+	cachedNode := testNode("worker1").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+		WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse, 0).
+		Build()
+	cachedNode.UID = "uid-1"
+
+	testCases := []struct {
+		name              string
+		node              corev1.Node
+		uid               types.UID
+		expectedUnchanged bool
+	}{
+		{
+			name: "test 0 - same conditions",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse, 0).
+				Build(),
+			uid:               "uid-1",
+			expectedUnchanged: true,
+		},
+		{
+			name: "test 1 - heartbeat changed",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, -time.Minute).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse, -time.Minute).
+				Build(),
+			uid:               "uid-1",
+			expectedUnchanged: true,
+		},
+		{
+			name: "test 2 - status changed",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, 0).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse, 0).
+				Build(),
+			uid:               "uid-1",
+			expectedUnchanged: false,
+		},
+		{
+			name: "test 3 - condition added",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse, 0).
+				WithCondition("DiskFullKubelet", corev1.ConditionTrue, 0).
+				Build(),
+			uid:               "uid-1",
+			expectedUnchanged: false,
+		},
+		{
+			name: "test 4 - unknown node",
+			node: testNode("worker2").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse, 0).
+				Build(),
+			uid:               "uid-2",
+			expectedUnchanged: false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			c := newNodeConditionCache()
+			c.set(cachedNode)
+
+			tc.node.UID = tc.uid
+			if c.unchanged(tc.node) != tc.expectedUnchanged {
+				t.Fatalf("Expected unchanged '%t' but got '%t'.\n", tc.expectedUnchanged, !tc.expectedUnchanged)
+			}
+		})
+	}
+
+	c := newNodeConditionCache()
+	c.set(cachedNode)
+	c.prune(map[types.UID]struct{}{"uid-2": {}})
+	if c.unchanged(cachedNode) {
+		t.Fatalf("Expected pruned node to be removed from the cache.\n")
+	}
+}
+
+func Test_DetectBadNodes_conditionCache(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	node := testNode("worker1").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+		Build()
+	node.UID = "uid-1"
+	k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+
+	d, err := NewDetector(Config{
+		Clock:     &FakeClock{Time: testNow},
+		Logger:    logger,
+		K8sClient: k8sClient,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the node becomes NotReady after the first run and stays NotReady without new heartbeats
+	expectedTickCounts := []string{"", "1", "2", "3"}
+	for i, expected := range expectedTickCounts {
+		_, err = d.DetectBadNodes(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Annotations[annotationNodeNotReadyTick] != expected {
+			t.Fatalf("Expected tick count '%s' after run %d but got '%s'.\n", expected, i, node.Annotations[annotationNodeNotReadyTick])
+		}
+
+		if i == 0 {
+			node.Status.Conditions[0].Status = corev1.ConditionFalse
+			node.Status.Conditions[0].LastHeartbeatTime.Time = testNow.Add(-time.Minute * 10)
+			err = k8sClient.Update(context.Background(), &node)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func Test_DetectBadNodes_conditionCacheThresholdCrossing(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	// the status is already unknown, but the heartbeat is still within the threshold
+	node := testNode("worker1").
+		WithCondition(corev1.NodeReady, corev1.ConditionUnknown, time.Second*45).
+		Build()
+	node.UID = "uid-1"
+	k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+
+	clock := &FakeClock{Time: testNow}
+	d, err := NewDetector(Config{
+		Clock:                     clock,
+		Logger:                    logger,
+		K8sClient:                 k8sClient,
+		NodeReadyUnknownThreshold: time.Minute * 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the statuses do not change, only the clock passes the threshold after the first run
+	expectedTickCounts := []string{"", "1", "2"}
+	for i, expected := range expectedTickCounts {
+		_, err = d.DetectBadNodes(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Annotations[annotationNodeNotReadyTick] != expected {
+			t.Fatalf("Expected tick count '%s' after run %d but got '%s'.\n", expected, i, node.Annotations[annotationNodeNotReadyTick])
+		}
+
+		clock.Time = clock.Time.Add(time.Minute * 3)
+	}
+}
+
+func benchmarkProcessNode(b *testing.B, disableHealthCheckCache bool) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	var nodes []corev1.Node
+	var objects []client.Object
+	for i := 0; i < 100; i++ {
+		node := testNode(fmt.Sprintf("worker%d", i)).
+			WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+			WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse, 0).
+			WithCondition("DiskFullKubelet", corev1.ConditionFalse, 0).
+			Build()
+		node.UID = types.UID(fmt.Sprintf("uid-%d", i))
+		nodes = append(nodes, node)
+		objects = append(objects, &node)
+	}
+
+	d, err := NewDetector(Config{
+		Clock:                   &FakeClock{Time: testNow},
+		Logger:                  logger,
+		K8sClient:               fake.NewClientBuilder().WithObjects(objects...).Build(),
+		DisableHealthCheckCache: disableHealthCheckCache,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	r := &detectionRun{
+		logger:     logger,
+		threshold:  d.notReadyTickThreshold,
+		now:        testNow,
+		seen:       map[types.UID]struct{}{},
+		tickCounts: map[string]int{},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for j := range nodes {
+			_, err := d.processNode(context.Background(), r, &nodes[j])
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func Benchmark_processNode_healthCheckCache(b *testing.B) {
+	benchmarkProcessNode(b, false)
+}
+
+func Benchmark_processNode_withoutHealthCheckCache(b *testing.B) {
+	benchmarkProcessNode(b, true)
+}
@@ -0,0 +1,28 @@
+package detector
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// selectCanaryNode returns only the node with the highest tick count, ties are broken by the node name,
+// so at most a single node is terminated per run in canary mode.
+func (d *Detector) selectCanaryNode(ctx context.Context, r *detectionRun, badNodes []corev1.Node) []corev1.Node {
+	if len(badNodes) == 0 {
+		return badNodes
+	}
+
+	selected := badNodes[0]
+	for _, n := range badNodes[1:] {
+		tick := nodeTickCount(n, d.tickAnnotationKey)
+		selectedTick := nodeTickCount(selected, d.tickAnnotationKey)
+		if tick > selectedTick || (tick == selectedTick && n.Name < selected.Name) {
+			selected = n
+		}
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "selected a single node for termination", "canaryMode", true, "selectedNode", selected.Name)
+
+	return []corev1.Node{selected}
+}
@@ -0,0 +1,87 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodes_canaryMode(t *testing.T) {
+	testCases := []struct {
+		name             string
+		canaryMode       bool
+		tickCount        func(i int) int
+		expectedBadNodes int
+		expectedNode     string
+	}{
+		{
+			name:       "test 0 - highest tick count is selected",
+			canaryMode: true,
+			// the ticks are increased by the run before the selection
+			tickCount:        func(i int) int { return 5 + i%5 },
+			expectedBadNodes: 1,
+			expectedNode:     "worker04",
+		},
+		{
+			name:             "test 1 - ties are broken by name",
+			canaryMode:       true,
+			tickCount:        func(i int) int { return 5 },
+			expectedBadNodes: 1,
+			expectedNode:     "worker00",
+		},
+		{
+			name:             "test 2 - all nodes without canary mode",
+			canaryMode:       false,
+			tickCount:        func(i int) int { return 5 },
+			expectedBadNodes: 50,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var nodes []client.Object
+			for j := 0; j < 50; j++ {
+				node := testNode(fmt.Sprintf("worker%02d", j)).
+					WithRole(labelNodeRoleWorker).
+					WithAnnotation(annotationNodeNotReadyTick, strconv.Itoa(tc.tickCount(j))).
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build()
+				nodes = append(nodes, &node)
+			}
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    fake.NewClientBuilder().WithObjects(nodes...).Build(),
+				MaxNodeTerminationPercentage: 1,
+				CanaryMode:                   tc.canaryMode,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(badNodes) != tc.expectedBadNodes {
+				t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", tc.expectedBadNodes, len(badNodes))
+			}
+			if tc.expectedNode != "" && badNodes[0].Name != tc.expectedNode {
+				t.Fatalf("Expected node '%s' but got '%s'.\n", tc.expectedNode, badNodes[0].Name)
+			}
+		})
+	}
+}
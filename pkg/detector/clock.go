@@ -0,0 +1,28 @@
+package detector
+
+import (
+	"time"
+)
+
+// Clock provides the current time. It allows to replace the wall clock in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is a Clock returning the wall clock time.
+type RealClock struct{}
+
+// Now returns the current wall clock time.
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock is a Clock returning a fixed time which can be set by tests.
+type FakeClock struct {
+	Time time.Time
+}
+
+// Now returns the time the clock is set to.
+func (c *FakeClock) Now() time.Time {
+	return c.Time
+}
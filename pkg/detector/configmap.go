@@ -0,0 +1,86 @@
+package detector
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// updateConfigMap reads the data of the ConfigMap, calls fn and writes the data returned by fn back.
+// The ConfigMap is created if it does not exist. Nothing is written when fn did not change the data.
+// Conflicting writes of other replicas are retried with the latest data of the ConfigMap, so fn can be called multiple times.
+func updateConfigMap(ctx context.Context, k8sClient client.Client, namespace string, name string, fn func(data map[string]string) (map[string]string, error)) error {
+	err := retry.OnError(retry.DefaultRetry, isConfigMapWriteConflict, func() error {
+		var configMap corev1.ConfigMap
+		exists := true
+		{
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &configMap)
+			if apierrors.IsNotFound(err) {
+				exists = false
+				configMap = corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: namespace,
+						Name:      name,
+					},
+				}
+			} else if err != nil {
+				return err
+			}
+		}
+
+		// fn gets a copy, so the data it changed in place can be compared with the current data
+		data, err := fn(copyConfigMapData(configMap.Data))
+		if err != nil {
+			return err
+		}
+		if sameConfigMapData(configMap.Data, data) {
+			return nil
+		}
+		configMap.Data = data
+
+		if !exists {
+			return k8sClient.Create(ctx, &configMap)
+		}
+		return k8sClient.Update(ctx, &configMap)
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// isConfigMapWriteConflict returns true if the ConfigMap was changed or created by another writer in the meantime.
+func isConfigMapWriteConflict(err error) bool {
+	return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+}
+
+// copyConfigMapData returns a copy of the data, nil stays nil.
+func copyConfigMapData(data map[string]string) map[string]string {
+	if data == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(data))
+	for k, v := range data {
+		copied[k] = v
+	}
+	return copied
+}
+
+// sameConfigMapData returns true if both data contain the same entries, nil equals empty data.
+func sameConfigMapData(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,207 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// concurrentWriterClient wraps a client and runs write before the first given number of ConfigMap updates,
+// ie: to simulate another replica changing the ConfigMap in the meantime.
+type concurrentWriterClient struct {
+	client.Client
+
+	conflicts int
+	write     func(ctx context.Context, c client.Client) error
+}
+
+func (c *concurrentWriterClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if _, ok := obj.(*corev1.ConfigMap); ok && c.conflicts > 0 {
+		c.conflicts--
+		err := c.write(ctx, c.Client)
+		if err != nil {
+			return err
+		}
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// writeCountingClient wraps a client and counts the ConfigMap creations and updates.
+type writeCountingClient struct {
+	client.Client
+
+	writes int
+}
+
+func (c *writeCountingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if _, ok := obj.(*corev1.ConfigMap); ok {
+		c.writes++
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *writeCountingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if _, ok := obj.(*corev1.ConfigMap); ok {
+		c.writes++
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func Test_updateConfigMap_unchanged(t *testing.T) {
+	testCases := []struct {
+		name           string
+		objects        []client.Object
+		fn             func(data map[string]string) map[string]string
+		expectedWrites int
+	}{
+		{
+			name: "test 0 - unchanged data is not written",
+			objects: []client.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: defaultTerminationHistoryNamespace, Name: "history"},
+					Data:       map[string]string{"pool": "1"},
+				},
+			},
+			fn: func(data map[string]string) map[string]string {
+				return data
+			},
+			expectedWrites: 0,
+		},
+		{
+			name: "test 1 - data changed in place is written",
+			objects: []client.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: defaultTerminationHistoryNamespace, Name: "history"},
+					Data:       map[string]string{"pool": "1"},
+				},
+			},
+			fn: func(data map[string]string) map[string]string {
+				data["pool"] = "2"
+				return data
+			},
+			expectedWrites: 1,
+		},
+		{
+			name: "test 2 - missing ConfigMap without data is not created",
+			fn: func(data map[string]string) map[string]string {
+				return map[string]string{}
+			},
+			expectedWrites: 0,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			k8sClient := &writeCountingClient{
+				Client: fake.NewClientBuilder().WithObjects(tc.objects...).Build(),
+			}
+
+			err := updateConfigMap(context.Background(), k8sClient, defaultTerminationHistoryNamespace, "history", func(data map[string]string) (map[string]string, error) {
+				return tc.fn(data), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if k8sClient.writes != tc.expectedWrites {
+				t.Fatalf("Expected '%d' writes but got '%d'.\n", tc.expectedWrites, k8sClient.writes)
+			}
+		})
+	}
+}
+
+func Test_updateConfigMap_conflict(t *testing.T) {
+	testCases := []struct {
+		name           string
+		conflicts      int
+		expectedCalls  int
+		expectedCounts map[string]string
+	}{
+		{
+			name:          "test 0 - no concurrent writer",
+			conflicts:     0,
+			expectedCalls: 1,
+			expectedCounts: map[string]string{
+				"pool": "2",
+			},
+		},
+		{
+			name:          "test 1 - concurrent writer changed the ConfigMap",
+			conflicts:     1,
+			expectedCalls: 2,
+			expectedCounts: map[string]string{
+				"pool": "3",
+			},
+		},
+		{
+			name:          "test 2 - multiple concurrent writes",
+			conflicts:     3,
+			expectedCalls: 4,
+			expectedCounts: map[string]string{
+				"pool": "5",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: defaultTerminationHistoryNamespace,
+					Name:      "history",
+				},
+				Data: map[string]string{
+					"pool": "1",
+				},
+			}
+			k8sClient := &concurrentWriterClient{
+				Client:    fake.NewClientBuilder().WithObjects(configMap).Build(),
+				conflicts: tc.conflicts,
+				write: func(ctx context.Context, c client.Client) error {
+					var current corev1.ConfigMap
+					err := c.Get(ctx, client.ObjectKey{Namespace: defaultTerminationHistoryNamespace, Name: "history"}, &current)
+					if err != nil {
+						return err
+					}
+					count, _ := strconv.Atoi(current.Data["pool"])
+					current.Data["pool"] = strconv.Itoa(count + 1)
+					return c.Update(ctx, &current)
+				},
+			}
+
+			store := NewConfigMapTerminationHistoryStore(k8sClient, defaultTerminationHistoryNamespace, "history")
+
+			calls := 0
+			err := store.Update(context.Background(), func(counts map[string]int) {
+				calls++
+				counts["pool"]++
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if calls != tc.expectedCalls {
+				t.Fatalf("Expected '%d' calls but got '%d'.\n", tc.expectedCalls, calls)
+			}
+
+			var result corev1.ConfigMap
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Namespace: defaultTerminationHistoryNamespace, Name: "history"}, &result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(result.Data, tc.expectedCounts) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedCounts, result.Data))
+			}
+		})
+	}
+}
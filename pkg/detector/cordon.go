@@ -0,0 +1,84 @@
+package detector
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// nodeCordonedAt tracks since when the node is cordoned via the cordoned-at annotation and returns the timestamp.
+// The returned timestamp is zero for nodes which are not cordoned.
+// The second return value indicates if the annotations of the node changed and need to be updated.
+func nodeCordonedAt(n *corev1.Node, now time.Time) (time.Time, bool) {
+	cordonedAt, ok := n.Annotations[annotationNodeCordonedAt]
+
+	if !n.Spec.Unschedulable {
+		// node got uncordoned, the timestamp is not valid anymore
+		if ok {
+			delete(n.Annotations, annotationNodeCordonedAt)
+			return time.Time{}, true
+		}
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339, cordonedAt)
+	// first time we see the node cordoned or the annotation is a garbage, lets start tracking now
+	if !ok || err != nil {
+		setAnnotation(n, annotationNodeCordonedAt, now.UTC().Format(time.RFC3339))
+		return now, true
+	}
+
+	return t, false
+}
+
+// nodeCordonDwellElapsed returns true if the node can be terminated with regards to the cordon dwell duration.
+// Nodes which are not cordoned can always be terminated.
+func nodeCordonDwellElapsed(n corev1.Node, cordonedAt time.Time, dwellDuration time.Duration, now time.Time) bool {
+	if dwellDuration == 0 || !n.Spec.Unschedulable {
+		return true
+	}
+	return now.Sub(cordonedAt) >= dwellDuration
+}
+
+// isNodeIdleCordoned returns true if the node is cordoned for at least idleDuration and has no active pods.
+func isNodeIdleCordoned(n corev1.Node, cordonedAt time.Time, idleDuration time.Duration, activePods int, now time.Time) bool {
+	if idleDuration == 0 || !n.Spec.Unschedulable {
+		return false
+	}
+	return activePods == 0 && now.Sub(cordonedAt) >= idleDuration
+}
+
+// activePodsPerNode counts the pods per node which are not finished and not managed by a DaemonSet or the node itself.
+func (d *Detector) activePodsPerNode(ctx context.Context) (map[string]int, error) {
+	var podList corev1.PodList
+	{
+		err := d.k8sClient.List(ctx, &podList)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	activePods := map[string]int{}
+	for _, p := range podList.Items {
+		if p.Spec.NodeName == "" || p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if isDaemonSetOrStaticPod(p) {
+			continue
+		}
+		activePods[p.Spec.NodeName]++
+	}
+	return activePods, nil
+}
+
+// isDaemonSetOrStaticPod returns true if the pod is owned by a DaemonSet or is a static pod owned by the node.
+func isDaemonSetOrStaticPod(p corev1.Pod) bool {
+	for _, o := range p.OwnerReferences {
+		if o.Kind == "DaemonSet" || o.Kind == "Node" {
+			return true
+		}
+	}
+	return false
+}
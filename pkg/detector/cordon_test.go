@@ -0,0 +1,324 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_nodeCordonedAt(t *testing.T) {
+	now := testNow
+
+	testCases := []struct {
+		name                string
+		node                corev1.Node
+		expectedCordonedAt  time.Time
+		expectedUpdated     bool
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:               "test 0 - node not cordoned",
+			node:               corev1.Node{},
+			expectedCordonedAt: time.Time{},
+			expectedUpdated:    false,
+		},
+		{
+			name: "test 1 - node cordoned for the first time",
+			node: corev1.Node{
+				Spec: corev1.NodeSpec{
+					Unschedulable: true,
+				},
+			},
+			expectedCordonedAt: now,
+			expectedUpdated:    true,
+			expectedAnnotations: map[string]string{
+				annotationNodeCordonedAt: "2023-11-09T12:00:00Z",
+			},
+		},
+		{
+			name: "test 2 - node already cordoned",
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						annotationNodeCordonedAt: "2023-11-09T11:55:00Z",
+					},
+				},
+				Spec: corev1.NodeSpec{
+					Unschedulable: true,
+				},
+			},
+			expectedCordonedAt: now.Add(-time.Minute * 5),
+			expectedUpdated:    false,
+			expectedAnnotations: map[string]string{
+				annotationNodeCordonedAt: "2023-11-09T11:55:00Z",
+			},
+		},
+		{
+			name: "test 3 - node uncordoned",
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						annotationNodeCordonedAt: "2023-11-09T11:55:00Z",
+					},
+				},
+			},
+			expectedCordonedAt:  time.Time{},
+			expectedUpdated:     true,
+			expectedAnnotations: map[string]string{},
+		},
+		{
+			name: "test 4 - invalid timestamp restarts tracking",
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						annotationNodeCordonedAt: "yesterday",
+					},
+				},
+				Spec: corev1.NodeSpec{
+					Unschedulable: true,
+				},
+			},
+			expectedCordonedAt: now,
+			expectedUpdated:    true,
+			expectedAnnotations: map[string]string{
+				annotationNodeCordonedAt: "2023-11-09T12:00:00Z",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cordonedAt, updated := nodeCordonedAt(&tc.node, now)
+			if !cordonedAt.Equal(tc.expectedCordonedAt) {
+				t.Fatalf("Expected cordoned at '%s' but got '%s'.\n", tc.expectedCordonedAt, cordonedAt)
+			}
+			if updated != tc.expectedUpdated {
+				t.Fatalf("Expected updated '%t' but got '%t'.\n", tc.expectedUpdated, updated)
+			}
+
+			for k, v := range tc.expectedAnnotations {
+				if tc.node.Annotations[k] != v {
+					t.Fatalf("Expected annotation %s '%s' but got '%s'.\n", k, v, tc.node.Annotations[k])
+				}
+			}
+			if len(tc.node.Annotations) != len(tc.expectedAnnotations) {
+				t.Fatalf("Expected '%d' annotations but got '%d'.\n", len(tc.expectedAnnotations), len(tc.node.Annotations))
+			}
+		})
+	}
+}
+
+func Test_nodeCordonDwellElapsed(t *testing.T) {
+	now := testNow
+
+	testCases := []struct {
+		name            string
+		unschedulable   bool
+		cordonedAt      time.Time
+		dwellDuration   time.Duration
+		expectedElapsed bool
+	}{
+		{
+			name:            "test 0 - dwell disabled",
+			unschedulable:   true,
+			cordonedAt:      now,
+			dwellDuration:   0,
+			expectedElapsed: true,
+		},
+		{
+			name:            "test 1 - node not cordoned",
+			unschedulable:   false,
+			dwellDuration:   time.Minute * 10,
+			expectedElapsed: true,
+		},
+		{
+			name:            "test 2 - node cordoned before dwell elapsed",
+			unschedulable:   true,
+			cordonedAt:      now.Add(-time.Minute * 5),
+			dwellDuration:   time.Minute * 10,
+			expectedElapsed: false,
+		},
+		{
+			name:            "test 3 - node cordoned after dwell elapsed",
+			unschedulable:   true,
+			cordonedAt:      now.Add(-time.Minute * 15),
+			dwellDuration:   time.Minute * 10,
+			expectedElapsed: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			n := corev1.Node{
+				Spec: corev1.NodeSpec{
+					Unschedulable: tc.unschedulable,
+				},
+			}
+
+			elapsed := nodeCordonDwellElapsed(n, tc.cordonedAt, tc.dwellDuration, now)
+			if elapsed != tc.expectedElapsed {
+				t.Fatalf("Expected elapsed '%t' but got '%t'.\n", tc.expectedElapsed, elapsed)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_idleCordonedNode(t *testing.T) {
+	cordonedAt := testNow.Add(-time.Hour * 2).UTC().Format(time.RFC3339)
+	readyConditions := []corev1.NodeCondition{
+		{
+			Type:              corev1.NodeReady,
+			Status:            corev1.ConditionTrue,
+			LastHeartbeatTime: metav1.NewTime(testNow),
+		},
+	}
+
+	testCases := []struct {
+		name             string
+		node             *corev1.Node
+		pods             []*corev1.Pod
+		expectedBadNodes int
+	}{
+		{
+			name: "test 0 - long cordoned empty node",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "worker1",
+					Annotations: map[string]string{
+						annotationNodeCordonedAt: cordonedAt,
+					},
+				},
+				Spec: corev1.NodeSpec{
+					Unschedulable: true,
+				},
+				Status: corev1.NodeStatus{
+					Conditions: readyConditions,
+				},
+			},
+			pods: []*corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "node-exporter",
+						Namespace: "kube-system",
+						OwnerReferences: []metav1.OwnerReference{
+							{
+								APIVersion: "apps/v1",
+								Kind:       "DaemonSet",
+								Name:       "node-exporter",
+							},
+						},
+					},
+					Spec: corev1.PodSpec{
+						NodeName: "worker1",
+					},
+					Status: corev1.PodStatus{
+						Phase: corev1.PodRunning,
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "job",
+						Namespace: "default",
+					},
+					Spec: corev1.PodSpec{
+						NodeName: "worker1",
+					},
+					Status: corev1.PodStatus{
+						Phase: corev1.PodSucceeded,
+					},
+				},
+			},
+			expectedBadNodes: 1,
+		},
+		{
+			name: "test 1 - long cordoned node with running pods",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "worker1",
+					Annotations: map[string]string{
+						annotationNodeCordonedAt: cordonedAt,
+					},
+				},
+				Spec: corev1.NodeSpec{
+					Unschedulable: true,
+				},
+				Status: corev1.NodeStatus{
+					Conditions: readyConditions,
+				},
+			},
+			pods: []*corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "app",
+						Namespace: "default",
+					},
+					Spec: corev1.PodSpec{
+						NodeName: "worker1",
+					},
+					Status: corev1.PodStatus{
+						Phase: corev1.PodRunning,
+					},
+				},
+			},
+			expectedBadNodes: 0,
+		},
+		{
+			name: "test 2 - recently cordoned empty node",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "worker1",
+				},
+				Spec: corev1.NodeSpec{
+					Unschedulable: true,
+				},
+				Status: corev1.NodeStatus{
+					Conditions: readyConditions,
+				},
+			},
+			expectedBadNodes: 0,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			objects := []client.Object{tc.node}
+			for _, p := range tc.pods {
+				objects = append(objects, p)
+			}
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:                    &FakeClock{Time: testNow},
+				Logger:                   logger,
+				K8sClient:                fake.NewClientBuilder().WithObjects(objects...).Build(),
+				IdleCordonedNodeDuration: time.Hour,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(badNodes) != tc.expectedBadNodes {
+				t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", tc.expectedBadNodes, len(badNodes))
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package detector
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// unhealthyDebounce counts the consecutive runs in which a node was observed unhealthy, so a status which
+// is only briefly unhealthy, ie: because of an inconsistent informer cache, does not increase the tick count.
+type unhealthyDebounce struct {
+	mutex        sync.Mutex
+	runs         int
+	observations map[types.UID]int
+}
+
+func newUnhealthyDebounce(runs int) *unhealthyDebounce {
+	return &unhealthyDebounce{
+		runs:         runs,
+		observations: map[types.UID]int{},
+	}
+}
+
+// observe records whether the node was observed unhealthy in the current run and returns true
+// once it was observed unhealthy in enough consecutive runs.
+func (u *unhealthyDebounce) observe(n corev1.Node, unhealthy bool) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if !unhealthy {
+		delete(u.observations, n.UID)
+		return false
+	}
+
+	u.observations[n.UID]++
+	return u.observations[n.UID] >= u.runs
+}
+
+// prune forgets all nodes which are not part of seen, ie: nodes removed from the cluster.
+func (u *unhealthyDebounce) prune(seen map[types.UID]struct{}) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	for uid := range u.observations {
+		if _, ok := seen[uid]; !ok {
+			delete(u.observations, uid)
+		}
+	}
+}
+
+// snapshot returns a copy of the observations keyed by node UID.
+func (u *unhealthyDebounce) snapshot() map[string]int {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	observations := map[string]int{}
+	for uid, count := range u.observations {
+		observations[string(uid)] = count
+	}
+	return observations
+}
+
+// restore replaces the observations with the given ones keyed by node UID.
+func (u *unhealthyDebounce) restore(observations map[string]int) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.observations = map[types.UID]int{}
+	for uid, count := range observations {
+		u.observations[types.UID(uid)] = count
+	}
+}
@@ -0,0 +1,108 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodes_consecutiveUnhealthyRuns(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		consecutiveUnhealthyRuns int
+		// statuses contains the Ready status of the node observed in each run
+		statuses           []corev1.ConditionStatus
+		expectedTickCounts []string
+	}{
+		{
+			name:                     "test 0 - disabled debounce increases the tick count immediately",
+			consecutiveUnhealthyRuns: 0,
+			statuses:                 []corev1.ConditionStatus{corev1.ConditionFalse, corev1.ConditionTrue, corev1.ConditionFalse},
+			expectedTickCounts:       []string{"1", "0", "1"},
+		},
+		{
+			name:                     "test 1 - brief cache inconsistency is ignored",
+			consecutiveUnhealthyRuns: 2,
+			statuses:                 []corev1.ConditionStatus{corev1.ConditionFalse, corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionTrue},
+			expectedTickCounts:       []string{"0", "0", "0", "0"},
+		},
+		{
+			name:                     "test 2 - consistently unhealthy node increases the tick count",
+			consecutiveUnhealthyRuns: 2,
+			statuses:                 []corev1.ConditionStatus{corev1.ConditionFalse, corev1.ConditionFalse, corev1.ConditionFalse},
+			expectedTickCounts:       []string{"0", "1", "2"},
+		},
+		{
+			name:                     "test 3 - healthy observation starts the count over",
+			consecutiveUnhealthyRuns: 3,
+			statuses:                 []corev1.ConditionStatus{corev1.ConditionFalse, corev1.ConditionFalse, corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionFalse, corev1.ConditionFalse},
+			expectedTickCounts:       []string{"0", "0", "0", "0", "0", "1"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			node := testNode("worker1").
+				WithRole(labelNodeRoleWorker).
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				Build()
+			node.UID = types.UID("worker1")
+
+			logger, _ := micrologger.New(micrologger.Config{})
+			k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                    &FakeClock{Time: testNow},
+				Logger:                   logger,
+				K8sClient:                k8sClient,
+				ConsecutiveUnhealthyRuns: tc.consecutiveUnhealthyRuns,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var tickCounts []string
+			for _, status := range tc.statuses {
+				var n corev1.Node
+				err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				n.Status.Conditions[0].Status = status
+				err = k8sClient.Update(context.Background(), &n)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				_, err = d.DetectBadNodes(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tick, ok := n.Annotations[annotationNodeNotReadyTick]
+				if !ok {
+					tick = "0"
+				}
+				tickCounts = append(tickCounts, tick)
+			}
+
+			if !cmp.Equal(tickCounts, tc.expectedTickCounts) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedTickCounts, tickCounts))
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DegradedModeResult describes a result returned in degraded mode.
+type DegradedModeResult struct {
+	// CachedAt is the time at which the returned result was cached.
+	CachedAt time.Time
+	// Cause is the error which prevented the detection run.
+	Cause error
+}
+
+// degradedModeCache keeps the result of the last successful run to return it when the nodes can not be listed.
+type degradedModeCache struct {
+	mutex    sync.Mutex
+	ttl      time.Duration
+	result   *DetectBadNodesResult
+	cachedAt time.Time
+}
+
+func newDegradedModeCache(ttl time.Duration) *degradedModeCache {
+	return &degradedModeCache{
+		ttl: ttl,
+	}
+}
+
+// set caches the result of a successful run.
+func (c *degradedModeCache) set(result DetectBadNodesResult, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cached := copyDetectBadNodesResult(result)
+	c.result = &cached
+	c.cachedAt = now
+}
+
+// get returns a copy of the cached result and the time it was cached, if it is not older than the ttl.
+func (c *degradedModeCache) get(now time.Time) (DetectBadNodesResult, time.Time, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.result == nil || now.Sub(c.cachedAt) > c.ttl {
+		return DetectBadNodesResult{}, time.Time{}, false
+	}
+	return copyDetectBadNodesResult(*c.result), c.cachedAt, true
+}
+
+// degradedResult returns the cached result and true when the run failed because the nodes could not be listed
+// and a result which is not older than DegradedModeCacheTTL is cached.
+func (d *Detector) degradedResult(ctx context.Context, err error) (DetectBadNodesResult, bool) {
+	if !IsListNodes(err) {
+		return DetectBadNodesResult{}, false
+	}
+
+	result, cachedAt, ok := d.degradedMode.get(d.clock.Now())
+	if !ok {
+		d.logger.Errorf(ctx, err, "failed to list nodes and no result cached within %s", d.degradedMode.ttl)
+		return DetectBadNodesResult{}, false
+	}
+
+	d.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("failed to list nodes, returning the result of the last successful run at %s: %s", cachedAt.Format(time.RFC3339), err.Error()), "degradedMode", true)
+
+	result.DegradedMode = &DegradedModeResult{
+		CachedAt: cachedAt,
+		Cause:    err,
+	}
+	return result, true
+}
+
+// copyDetectBadNodesResult returns a deep copy of the result, so callers can not change the cached result.
+func copyDetectBadNodesResult(result DetectBadNodesResult) DetectBadNodesResult {
+	c := result
+	c.BadNodes = nil
+	for _, b := range result.BadNodes {
+		b.Node = *b.Node.DeepCopy()
+		b.Conditions = append([]string(nil), b.Conditions...)
+		c.BadNodes = append(c.BadNodes, b)
+	}
+	c.LifecycleStateCounts = map[NodeLifecycleState]int{}
+	for state, count := range result.LifecycleStateCounts {
+		c.LifecycleStateCounts[state] = count
+	}
+	return c
+}
@@ -0,0 +1,219 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodesWithResult_degradedMode(t *testing.T) {
+	testCases := []struct {
+		name             string
+		degradedMode     bool
+		successfulRun    bool
+		cacheAge         time.Duration
+		expectedDegraded bool
+	}{
+		{
+			name:             "test 0 - fresh cached result is returned",
+			degradedMode:     true,
+			successfulRun:    true,
+			cacheAge:         time.Minute * 5,
+			expectedDegraded: true,
+		},
+		{
+			name:             "test 1 - result cached exactly the ttl ago is returned",
+			degradedMode:     true,
+			successfulRun:    true,
+			cacheAge:         time.Minute * 10,
+			expectedDegraded: true,
+		},
+		{
+			name:             "test 2 - expired cached result is not returned",
+			degradedMode:     true,
+			successfulRun:    true,
+			cacheAge:         time.Minute * 11,
+			expectedDegraded: false,
+		},
+		{
+			name:             "test 3 - no cached result",
+			degradedMode:     true,
+			successfulRun:    false,
+			expectedDegraded: false,
+		},
+		{
+			name:             "test 4 - degraded mode disabled",
+			degradedMode:     false,
+			successfulRun:    true,
+			cacheAge:         time.Minute,
+			expectedDegraded: false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			node := testNode("worker1").
+				WithRole(labelNodeRoleWorker).
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build()
+
+			logger, _ := micrologger.New(micrologger.Config{})
+			clock := &FakeClock{Time: testNow}
+			k8sClient := &errorClient{Client: fake.NewClientBuilder().WithObjects(&node).Build()}
+
+			d, err := NewDetector(Config{
+				Clock:                        clock,
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+				DegradedMode:                 tc.degradedMode,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.successfulRun {
+				_, err = d.DetectBadNodesWithResult(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			clock.Time = testNow.Add(tc.cacheAge)
+			k8sClient.listError = errors.New("api server unavailable")
+
+			result, err := d.DetectBadNodesWithResult(context.Background())
+			if !tc.expectedDegraded {
+				if !IsListNodes(err) {
+					t.Fatalf("Expected list nodes error but got '%v'.\n", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.DegradedMode == nil {
+				t.Fatalf("Expected degraded mode result.\n")
+			}
+			if !result.DegradedMode.CachedAt.Equal(testNow) {
+				t.Fatalf("Expected cached at '%s' but got '%s'.\n", testNow, result.DegradedMode.CachedAt)
+			}
+			if len(result.BadNodes) != 1 || result.BadNodes[0].Node.Name != "worker1" {
+				t.Fatalf("Expected cached bad node '%s' but got '%v'.\n", "worker1", result.Nodes())
+			}
+
+			// no annotations are updated in degraded mode
+			var n corev1.Node
+			err = k8sClient.Client.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.Annotations[annotationNodeNotReadyTick] != "6" {
+				t.Fatalf("Expected tick count '%s' but got '%s'.\n", "6", n.Annotations[annotationNodeNotReadyTick])
+			}
+		})
+	}
+}
+
+// failingListClient wraps a client and fails the node list requests for which fail returns true.
+type failingListClient struct {
+	client.Client
+
+	calls int
+	fail  func(call int) bool
+}
+
+func (c *failingListClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if _, ok := list.(*corev1.NodeList); ok {
+		c.calls++
+		if c.fail(c.calls) {
+			return errors.New("api server unavailable")
+		}
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func Test_DetectBadNodesWithResult_degradedModeListFailure(t *testing.T) {
+	testCases := []struct {
+		name              string
+		fail              func(call int) bool
+		expectedError     bool
+		expectedTickCount string
+	}{
+		{
+			name: "test 0 - transient failure is retried",
+			fail: func(call int) bool {
+				return call == 1
+			},
+			expectedError:     false,
+			expectedTickCount: "6",
+		},
+		{
+			name: "test 1 - failure of the second page reverts the changes of the first page",
+			fail: func(call int) bool {
+				return call > 1
+			},
+			expectedError:     true,
+			expectedTickCount: "5",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var objects []client.Object
+			for _, name := range []string{"worker1", "worker2"} {
+				node := testNode(name).
+					WithRole(labelNodeRoleWorker).
+					WithAnnotation(annotationNodeNotReadyTick, "5").
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build()
+				objects = append(objects, &node)
+			}
+
+			logger, _ := micrologger.New(micrologger.Config{})
+			fakeClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    &failingListClient{Client: &pagingClient{Client: fakeClient}, fail: tc.fail},
+				MaxNodeTerminationPercentage: 1,
+				ListPageSize:                 1,
+				DegradedMode:                 true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = d.DetectBadNodesWithResult(context.Background())
+			if tc.expectedError && !IsListNodes(err) {
+				t.Fatalf("Expected list nodes error but got '%v'.\n", err)
+			} else if !tc.expectedError && err != nil {
+				t.Fatal(err)
+			}
+
+			// the first node was updated before the second page failed
+			var n corev1.Node
+			err = fakeClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.Annotations[annotationNodeNotReadyTick] != tc.expectedTickCount {
+				t.Fatalf("Expected tick count '%s' but got '%s'.\n", tc.expectedTickCount, n.Annotations[annotationNodeNotReadyTick])
+			}
+		})
+	}
+}
@@ -4,61 +4,434 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/nodepool"
 )
 
 const (
 	defaultMaxNodeTerminationPercentage = 0.10
 	defaultNotReadyTickThreshold        = 6
 	defaultPauseBetweenTermination      = time.Minute * 10
+	defaultNewNodeGracePeriod           = time.Minute * 30
+	defaultEstablishedNodeAge           = time.Hour * 24
+	defaultTerminationHistoryNamespace  = "kube-system"
+	defaultStateNamespace               = "kube-system"
+	defaultRecentTerminationNamespace   = "kube-system"
+	defaultTerminationHistoryWindow     = time.Hour
+	defaultMaxMasterTerminations        = 1
+	defaultMasterCountNamespace         = "kube-system"
+	defaultParallelism                  = 1
+	defaultDegradedModeCacheTTL         = time.Minute * 10
+
+	// maxPlausiblePauseBetweenTermination is the longest pause between terminations which is not logged as a warning.
+	maxPlausiblePauseBetweenTermination = time.Hour * 24
 
 	nodeNotReadyDuration = time.Second * 30
 
+	runIDLength = 16
+
+	// maxTickAnnotationLength bounds the length of the tick annotation which is parsed,
+	// longer values are treated as invalid.
+	maxTickAnnotationLength = 10
+
 	annotationNodeNotReadyTick = "giantswarm.io/node-not-ready-tick"
+	annotationNodeCordonedAt   = "giantswarm.io/node-cordoned-at"
 	labelNodeRole              = "role"
 	labelNodeRoleMaster        = "master"
 	labelNodeRoleWorker        = "worker"
+
+	annotationNodeAllocatablePressureSince = "giantswarm.io/node-allocatable-pressure-since"
 )
 
-var trueConditions = []string{
-	string(corev1.NodeReady),
-}
-var falseConditions = []string{
-	// Custom conditionx generated by https://github.com/giantswarm/node-problem-detector-app
-	"DiskFullKubelet",
-	"DiskFullContainerd",
-	"DiskFullVarLog",
-}
+const (
+	// DefaultNotReadyTickThreshold is the NotReadyTickThreshold used when it is not configured.
+	DefaultNotReadyTickThreshold = defaultNotReadyTickThreshold
+
+	// NodeNotReadyFingerprintAnnotation records the unhealthy conditions of a node at the moment it reached the tick threshold.
+	NodeNotReadyFingerprintAnnotation = "giantswarm.io/node-not-ready-fingerprint"
+
+	// NodeTerminationDiagnosticsAnnotation records the diagnostic context of a node returned for termination as json,
+	// see TerminationDiagnostics.
+	NodeTerminationDiagnosticsAnnotation = "giantswarm.io/node-termination-diagnostics"
+
+	// NodeSkipAnnotation excludes a node from the detection when set to `true`, see DisableNode.
+	NodeSkipAnnotation = "giantswarm.io/bad-node-detector-skip"
+	// NodeSkipReasonAnnotation records why the detection of a node was disabled.
+	NodeSkipReasonAnnotation = "giantswarm.io/bad-node-detector-skip-reason"
+
+	// LastDetectionRunAnnotation records the id and time of the last run which changed the tick count of a node,
+	// ie: `Ab3dE5gH7jK9mN1p 2023-11-09T12:00:00Z`. The id matches the `run` field of the log lines of the run.
+	LastDetectionRunAnnotation = "giantswarm.io/last-detection-run"
+
+	// ExpectedMasterCountAnnotation defines the number of master nodes of the cluster on the master count ConfigMap,
+	// see MasterCountConfigMap.
+	ExpectedMasterCountAnnotation = "giantswarm.io/expected-master-count"
+
+	// NodeLifetimeTickAnnotation records the cumulative number of tick increases of a node, which is never decreased,
+	// see LifetimeTickThreshold.
+	NodeLifetimeTickAnnotation = "giantswarm.io/node-not-ready-lifetime-tick"
+
+	// ExcludeFromExternalLoadBalancersLabel is the well-known label of nodes excluded from external load balancers,
+	// which is commonly set on nodes in a special operational state, see OperationalExclusionLabels.
+	ExcludeFromExternalLoadBalancersLabel = "node.kubernetes.io/exclude-from-external-load-balancers"
+)
 
 type Config struct {
 	Logger    micrologger.Logger
 	K8sClient client.Client
+	// Clock provides the current time, defaults to RealClock.
+	Clock Clock
 
 	// MaxNodeTerminationPercentage defines a maximum percentage of nodes that will be returned as 'marked for termination'
 	// ie: if the value is 0.5 and cluster have 10 nodes, than `DetectBadNodes`can only return maximum of 5 nodes
 	// marked for termination at single run
 	MaxNodeTerminationPercentage float64
+	// MaxNodeTerminationsPerRun defines an absolute maximum of nodes returned as 'marked for termination' at single run
+	// regardless of the cluster size, ie: 1 to remediate bad nodes one by one. Disabled when zero.
+	MaxNodeTerminationsPerRun int
+	// MaxMasterTerminations defines a maximum number of master nodes returned as 'marked for termination' at single run.
+	// Defaults to 1.
+	MaxMasterTerminations int
+	// MasterCountConfigMap enables deriving the maximum number of master node terminations from the
+	// ExpectedMasterCountAnnotation of the ConfigMap with the given name, ie: 2 for 7 master nodes to keep the etcd quorum.
+	// MaxMasterTerminations is used when the ConfigMap or the annotation does not exist.
+	MasterCountConfigMap string
+	// MasterCountNamespace defines the namespace of the master count ConfigMap. Defaults to `kube-system`.
+	MasterCountNamespace string
 	// NotReadyTickThreshold defines a how many times the node must bee seen as NotReady in order to return it as 'marked for termination'
 	NotReadyTickThreshold int
+	// NotReadyThresholdDuration defines the tick threshold as a duration instead, so tuning does not depend on how
+	// often DetectBadNodes is called. It is converted to ticks using RunInterval, rounded up, and takes precedence
+	// over NotReadyTickThreshold. Disabled when zero.
+	NotReadyThresholdDuration time.Duration
+	// RunInterval defines how often DetectBadNodes is called. Required when NotReadyThresholdDuration is set.
+	// NodeReconciler uses it as the minimum time between two ticks of a node, defaults to one minute there.
+	RunInterval time.Duration
 	// PauseBetweenTermination defines a pause between 2 intervals where node termination can occur.
 	// This is a safeguard to prevent nodes being terminated over and over or to not terminate too much at once.
 	// ie: if the value is 5m it means once it returned nodes for termination it wont return another nodes for another 5 min.
 	PauseBetweenTermination time.Duration
+	// TickAnnotationKey defines the node annotation used to store the not ready tick counter.
+	// Detectors running side by side in the same cluster must use different keys to not interfere with each other.
+	// Defaults to `giantswarm.io/node-not-ready-tick`.
+	TickAnnotationKey string
+	// DegradedMode enables returning the result of the last successful run when the nodes can not be listed
+	// after a few retries, ie: when some api server replicas fail. No annotations are updated in degraded mode,
+	// the annotations changed for the pages listed before the failure are reverted as with RollbackOnError.
+	DegradedMode bool
+	// DegradedModeCacheTTL defines how long the result of the last successful run is used in degraded mode.
+	// Defaults to 10m.
+	DegradedModeCacheTTL time.Duration
+	// BadNodeLabel enables setting the label with the given key to `true` on nodes which are 'marked for termination',
+	// so they can be selected, ie: `kubectl get nodes -l <key>=true`. The label reports the nodes over the threshold:
+	// it is set before the termination limits of DetectBadNodes apply, so labeled nodes held back by the limits are
+	// not returned. The label is removed once the node is not marked anymore and reverted by RollbackOnError.
+	// Disabled when empty.
+	BadNodeLabel string
+	// LoggerFields defines key value pairs which are added to every log line emitted by the detector.
+	// ie: the cluster name or lock name, which helps to correlate log lines of multiple detectors.
+	// Additionally every run of `DetectBadNodes` logs a generated `run` id.
+	LoggerFields map[string]string
+	// CordonDwellDuration defines how long a node must be cordoned before it can be returned as 'marked for termination'.
+	// This gives pods running on a cordoned node time to reschedule before the node is terminated.
+	// Nodes which are not cordoned are unaffected. Disabled when zero.
+	CordonDwellDuration time.Duration
+	// IdleCordonedNodeDuration enables marking nodes for termination which are cordoned for longer than the duration
+	// and do not run any pods apart from DaemonSet and static pods. Disabled when zero.
+	IdleCordonedNodeDuration time.Duration
+	// NeverReadyTimeout enables marking nodes for termination which did not become Ready within the duration
+	// after their creation. This allows recycling broken new nodes without waiting for the tick threshold.
+	// Disabled when zero.
+	NeverReadyTimeout time.Duration
+	// DynamicThreshold enables adjusting the NotReadyTickThreshold based on the current cluster size.
+	// ie: small clusters should act faster as a single bad node is a bigger part of the capacity.
+	DynamicThreshold bool
+	// ThresholdFormula calculates the effective tick threshold from the node count when DynamicThreshold is enabled.
+	// Defaults to `max(2, min(NotReadyTickThreshold, nodeCount/10))`.
+	ThresholdFormula func(nodeCount int) int
+	// NodePoolLabel defines the node label which identifies the node pool a node belongs to.
+	NodePoolLabel string
+	// NodePoolConfigs overrides the detection settings per node pool, keyed by the value of NodePoolLabel.
+	// Zero values of a pool config fall back to the detector settings.
+	// Pools without config are processed with the detector settings. Requires NodePoolLabel.
+	NodePoolConfigs map[string]nodepool.PoolConfig
+	// MinReadyNodesPerPool defines a minimum number of Ready nodes that must remain in a node pool.
+	// Bad nodes are not returned as 'marked for termination' when the pool would be left with fewer Ready nodes.
+	// Requires NodePoolLabel to be set. Disabled when zero.
+	MinReadyNodesPerPool int
+	// NodeOS limits the detection to nodes with the given `kubernetes.io/os` label, ie: `linux` or `windows`.
+	// This allows running separate detectors with different thresholds per operating system.
+	NodeOS string
+	// NodeArch limits the detection to nodes with the given `kubernetes.io/arch` label, ie: `amd64` or `arm64`.
+	NodeArch string
+	// ListPageSize defines how many nodes are listed per request, which bounds the memory used on big clusters.
+	// All nodes are listed at once when zero.
+	ListPageSize int64
+	// DisableLegacyConditionSupport disables checking the `OutOfDisk` node condition
+	// which was replaced by `DiskPressure` in Kubernetes 1.9. Legacy conditions are checked by default.
+	DisableLegacyConditionSupport bool
+	// RollbackOnError reverts all annotation changes made during a `DetectBadNodes` run when the run fails,
+	// so the tick counters are not changed by a run which did not return a result.
+	RollbackOnError bool
+	// DisableRecovery disables decreasing the tick counter of nodes which are seen as healthy again,
+	// so the tick counter of a flaky node only ever accumulates until it is reset via `ResetTickCounters`.
+	DisableRecovery bool
+	// StaleHeartbeatDuration enables considering nodes as unhealthy when the latest heartbeat of all node conditions
+	// is older than the duration, even if the node reports to be Ready. This detects kubelets which silently
+	// stopped reporting. Disabled when zero.
+	StaleHeartbeatDuration time.Duration
+	// ConsecutiveUnhealthyRuns defines in how many consecutive runs a node must be observed unhealthy before its
+	// tick count is increased, ie: to not act on a brief inconsistency of an informer backed client cache.
+	// Healthy observations in between start the count over. Defaults to 1.
+	ConsecutiveUnhealthyRuns int
+	// NodeReadyUnknownThreshold defines how long the Ready condition of a node must be `Unknown` before the node
+	// is considered unhealthy. The node controller sets the status to `Unknown` when the kubelet did not report
+	// within the node monitor grace period, ie: because it can not reach the api server. Defaults to 30s.
+	NodeReadyUnknownThreshold time.Duration
+	// DiskFullDuration defines how long a disk condition, ie: DiskPressure or DiskFullKubelet, must be true
+	// before the node is considered unhealthy. Defaults to 30s.
+	DiskFullDuration time.Duration
+	// MinAllocatableFraction enables a heuristic which considers a node unhealthy when the allocatable of a resource
+	// drops below the given fraction of its capacity, ie: `{corev1.ResourceMemory: 0.1}` for a node which reports
+	// less than 10% of its memory as allocatable. Fractions must be greater than 0 and at most 1. Disabled when empty.
+	MinAllocatableFraction map[corev1.ResourceName]float64
+	// AllocatablePressureDuration defines how long the allocatable must be below MinAllocatableFraction
+	// before the node is considered unhealthy. Defaults to 30s.
+	AllocatablePressureDuration time.Duration
+	// NodeFilters defines an ordered list of filters, only nodes included by all filters are handled by the detector.
+	// ie: `[]NodeFilter{ExcludeLabelFilter("example.com/ignore", ""), MinAgeFilter(RealClock{}, time.Minute*10)}`
+	// Nodes disabled by DisableNode are always skipped.
+	NodeFilters []NodeFilter
+	// CanaryMode limits the nodes 'marked for termination' to the single node with the highest tick count per run,
+	// regardless of all other limits. Ties are broken by the node name.
+	CanaryMode bool
+	// ExtraFilters defines an ordered list of filters applied to the nodes 'marked for termination' after the master
+	// and node pool limits, ie: `[]NodeListFilter{ZoneLimitFilter("topology.kubernetes.io/zone", 1)}`.
+	// The termination limit of MaxNodeTerminationPercentage and MaxNodeTerminationsPerRun is applied afterwards.
+	ExtraFilters []NodeListFilter
+	// OperationalExclusionLabels defines label keys of nodes in a special operational state which are skipped by the
+	// detector regardless of the label value, ie: `[]string{ExcludeFromExternalLoadBalancersLabel}`. Defaults to none.
+	OperationalExclusionLabels []string
+	// OperationalExclusionTaints defines taint keys of nodes in a special operational state which are skipped by the
+	// detector regardless of the taint effect. Defaults to none.
+	OperationalExclusionTaints []string
+	// NewNodeGracePeriod defines the age until a node is considered to be in the `Provisioning` lifecycle state.
+	// Defaults to 30m.
+	NewNodeGracePeriod time.Duration
+	// EstablishedNodeAge defines the age after which a node is considered to be in the `Established` lifecycle state.
+	// Defaults to 24h.
+	EstablishedNodeAge time.Duration
+	// SortBadNodesByTickCount sorts the nodes 'marked for termination' by their tick count in descending order,
+	// so the nodes which are bad for the longest time are preferred when the termination is limited.
+	// Nodes with the same tick count are ordered by their lifecycle state preferring `Established` nodes
+	// over `Provisioning` nodes, as provisioning nodes might still heal on their own.
+	SortBadNodesByTickCount bool
+	// Strategy selects the nodes 'marked for termination' when there are more bad nodes than the termination limits
+	// of the cluster and the node pools allow. Defaults to HighestTickFirstStrategy.
+	Strategy TerminationStrategy
+	// Metrics receives the duration and the results of every DetectBadNodes run and the number of requests
+	// sent to the Kubernetes API.
+	Metrics Metrics
+	// TracerProvider creates the OpenTelemetry spans around every DetectBadNodes run and the requests sent to the Kubernetes API.
+	// Tracing is disabled when no provider is set.
+	TracerProvider trace.TracerProvider
+	// ShouldTerminate decides if a node with the given not ready tick count is 'marked for termination'.
+	// It allows to encode custom policies, ie: to only terminate nodes of a specific pool.
+	// Defaults to comparing the tick count with the effective tick threshold.
+	ShouldTerminate func(node corev1.Node, tick int) bool
+	// DisableHealthCheckCache disables skipping the health check of nodes which were healthy in the previous run
+	// and did not change their conditions since.
+	DisableHealthCheckCache bool
+	// UnhealthyPredicate replaces the evaluation of the node conditions to decide if a node is unhealthy,
+	// ie: to combine conditions and labels with AndPredicate, OrPredicate and NotPredicate. StaleHeartbeatDuration and ExternalHealth still apply.
+	// Use ConditionsPredicate to extend the flat list of conditions evaluated by default.
+	UnhealthyPredicate Predicate
+	// ExternalHealth reports the health of a node from a source outside of the node conditions, ie: an external
+	// monitoring api. When set, it is consulted in addition to the node conditions and an unhealthy result increases
+	// the tick count. Errors are logged and leave the tick count unchanged.
+	ExternalHealth func(ctx context.Context, n corev1.Node) (healthy bool, err error)
+	// UpdateRateLimit limits the node updates per second to stay within the api server rate limits on big clusters.
+	// Updates are not limited when zero.
+	UpdateRateLimit rate.Limit
+	// UpdateBurst defines how many node updates can be sent at once when UpdateRateLimit is set. Defaults to 1.
+	UpdateBurst int
+	// Parallelism defines how many nodes of a page are evaluated and updated concurrently on big clusters.
+	// The returned nodes do not depend on it. ExternalHealth and ShouldTerminate must be safe for concurrent use
+	// when it is greater than 1. Defaults to 1.
+	Parallelism int
+	// AnnotateTerminationDiagnostics writes the NodeTerminationDiagnosticsAnnotation to every node returned for termination,
+	// so an external collector can snapshot the diagnostics before the node is deleted.
+	AnnotateTerminationDiagnostics bool
+	// RecordLastDetectionRun writes the LastDetectionRunAnnotation whenever a run changes the tick count of a node.
+	// NodeReconciler always writes it to tick a node at most once per RunInterval.
+	RecordLastDetectionRun bool
+	// LifetimeTickThreshold enables marking flapping nodes for termination once the cumulative number of tick increases
+	// recorded in the NodeLifetimeTickAnnotation reaches the threshold, regardless of the current tick count.
+	// ie: nodes which are repeatedly NotReady for a while, but recover before reaching NotReadyTickThreshold.
+	// Disabled when zero.
+	LifetimeTickThreshold int
+	// TerminationHistoryConfigMap enables persisting the number of nodes 'marked for termination' per node pool
+	// in the ConfigMap with the given name, so MaxTerminationsPerPool is enforced across restarts and replicas.
+	// The pools are identified by NodePoolLabel, all nodes belong to the same pool when it is not set.
+	TerminationHistoryConfigMap string
+	// TerminationHistoryNamespace defines the namespace of the termination history ConfigMap.
+	// Defaults to `kube-system`.
+	TerminationHistoryNamespace string
+	// TerminationHistoryStore replaces the ConfigMap store of the termination history, ie: with a fake in tests.
+	TerminationHistoryStore TerminationHistoryStore
+	// SelfNodeName defines the name of the node the detector runs on, ie: `spec.nodeName` exposed with the downward API.
+	// The node is never 'marked for termination' to not terminate the detector during a run, but its tick count
+	// is still updated. Disabled when empty.
+	SelfNodeName string
+	// RecentTerminationWindow enables suppressing bad nodes which match a node 'marked for termination' within the window,
+	// ie: replacements which reuse the name or provider id of the terminated node and are still bootstrapping.
+	// Requires RecentTerminationConfigMap or RecentTerminationStore. Disabled when zero.
+	RecentTerminationWindow time.Duration
+	// RecentTerminationConfigMap defines the name of the ConfigMap recording the nodes 'marked for termination'.
+	RecentTerminationConfigMap string
+	// RecentTerminationNamespace defines the namespace of the recent termination ConfigMap. Defaults to `kube-system`.
+	RecentTerminationNamespace string
+	// RecentTerminationStore replaces the ConfigMap store of the recent terminations, ie: with a fake in tests.
+	RecentTerminationStore RecentTerminationStore
+	// RecentTerminationMatch decides if a node matches a recent termination. Defaults to MatchRecentTermination.
+	RecentTerminationMatch func(n corev1.Node, termination RecentTermination) bool
+	// MaxNodeDataStaleness enables refusing to act on stale node data. When the last sync reported by NodeDataFreshness
+	// is longer ago, the run fails with an error matched by IsStaleNodeData without changing any node
+	// or returning nodes for termination. Disabled when zero.
+	MaxNodeDataStaleness time.Duration
+	// NodeDataFreshness returns the time the node data was last synced, ie: the last sync time of the informer
+	// backing K8sClient. A zero time means the data was never synced. Required by MaxNodeDataStaleness.
+	NodeDataFreshness func(ctx context.Context) (lastSync time.Time, err error)
+	// StateConfigMap enables persisting the bookkeeping of the detector, ie: the consecutive unhealthy observations
+	// of ConsecutiveUnhealthyRuns, in the ConfigMap with the given name, so it is restored after a restart.
+	StateConfigMap string
+	// StateNamespace defines the namespace of the state ConfigMap. Defaults to `kube-system`.
+	StateNamespace string
+	// StateStore replaces the ConfigMap store of the detector state, ie: with a fake in tests.
+	StateStore StateStore
+	// TerminationHistoryWindow defines the time window the terminations are counted in. Defaults to 1h.
+	TerminationHistoryWindow time.Duration
+	// MaxTerminationsPerPool defines how many nodes of a single pool can be 'marked for termination'
+	// within a termination history window. Terminations are only recorded when zero.
+	MaxTerminationsPerPool int
+	// EscalationTerminations enables a circuit breaker raising the tick threshold of a node pool to EscalatedTickThreshold
+	// once the given number of its nodes were 'marked for termination' within the TerminationHistoryWindow,
+	// as repeated terminations without recovery point to a systemic issue. The threshold is restored with the next window
+	// or once all nodes of the pool recovered, ie: have a zero tick count, and only later terminations count again.
+	// Requires the termination history. Disabled when zero.
+	EscalationTerminations int
+	// EscalatedTickThreshold defines the tick threshold of escalated node pools. Defaults to twice the NotReadyTickThreshold.
+	EscalatedTickThreshold int
+}
+
+// DefaultConfig returns a Config with all default values populated, so callers only need to set
+// Logger and K8sClient and override the fields they want to change.
+func DefaultConfig() Config {
+	return Config{
+		Clock: RealClock{},
+
+		MaxNodeTerminationPercentage: defaultMaxNodeTerminationPercentage,
+		NotReadyTickThreshold:        defaultNotReadyTickThreshold,
+		PauseBetweenTermination:      defaultPauseBetweenTermination,
+		TickAnnotationKey:            annotationNodeNotReadyTick,
+		NodeReadyUnknownThreshold:    nodeNotReadyDuration,
+		DiskFullDuration:             nodeNotReadyDuration,
+		AllocatablePressureDuration:  nodeNotReadyDuration,
+		NewNodeGracePeriod:           defaultNewNodeGracePeriod,
+		EstablishedNodeAge:           defaultEstablishedNodeAge,
+		TerminationHistoryNamespace:  defaultTerminationHistoryNamespace,
+		StateNamespace:               defaultStateNamespace,
+		RecentTerminationNamespace:   defaultRecentTerminationNamespace,
+		TerminationHistoryWindow:     defaultTerminationHistoryWindow,
+		MaxMasterTerminations:        defaultMaxMasterTerminations,
+		MasterCountNamespace:         defaultMasterCountNamespace,
+		Parallelism:                  defaultParallelism,
+	}
 }
 
 type Detector struct {
 	logger    micrologger.Logger
 	k8sClient client.Client
+	clock     Clock
+	metrics   Metrics
+	tracer    trace.Tracer
+
+	healthCheck    nodeHealthCheck
+	conditionCache *nodeConditionCache
+	updateLimiter  limiter
+	parallelism    int
+	stateEvents    nodeStateEvents
+	degradedMode   *degradedModeCache
+
+	unhealthyDebounce *unhealthyDebounce
+
+	stateStore  StateStore
+	stateMutex  sync.Mutex
+	stateLoaded bool
+	// nodeCount is the node count of the previous run or of the loaded state, guarded by stateMutex.
+	nodeCount int
+
+	maxNodeDataStaleness time.Duration
+	nodeDataFreshness    func(ctx context.Context) (time.Time, error)
+	selfNodeName         string
+	runInterval          time.Duration
+
+	recentTerminations      RecentTerminationStore
+	recentTerminationWindow time.Duration
+	recentTerminationMatch  func(n corev1.Node, termination RecentTermination) bool
 
 	maxNodeTerminationPercentage float64
+	maxNodeTerminationsPerRun    int
+	maxMasterTerminations        int
+	masterCountConfigMap         string
+	masterCountNamespace         string
 	notReadyTickThreshold        int
 	pauseBetweenTermination      time.Duration
+	tickAnnotationKey            string
+	badNodeLabel                 string
+	cordonDwellDuration          time.Duration
+	idleCordonedNodeDuration     time.Duration
+	neverReadyTimeout            time.Duration
+	lifetimeTickThreshold        int
+	thresholdFormula             func(nodeCount int) int
+	nodePoolLabel                string
+	minReadyNodesPerPool         int
+	nodePoolConfigs              map[string]nodepool.PoolConfig
+	nodeSelector                 client.MatchingLabels
+	listPageSize                 int64
+	rollbackOnError              bool
+	disableRecovery              bool
+	nodeFilters                  NodeSelector
+	extraFilters                 FilterChain
+	canaryMode                   bool
+	newNodeGracePeriod           time.Duration
+	establishedNodeAge           time.Duration
+	sortBadNodesByTickCount      bool
+	strategy                     TerminationStrategy
+	shouldTerminate              func(node corev1.Node, tick int) bool
+	terminationHistory           TerminationHistoryStore
+	terminationHistoryWindow     time.Duration
+	maxTerminationsPerPool       int
+	escalationTerminations       int
+	escalatedTickThreshold       int
+	terminationDiagnostics       bool
+	recordLastDetectionRun       bool
 }
 
 func NewDetector(config Config) (*Detector, error) {
@@ -69,135 +442,863 @@ func NewDetector(config Config) (*Detector, error) {
 		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
 	}
 
+	if config.Clock == nil {
+		config.Clock = RealClock{}
+	}
+	if config.Metrics == nil {
+		config.Metrics = noopMetrics{}
+	}
+	k8sClient := config.K8sClient
+	if config.TracerProvider == nil {
+		config.TracerProvider = trace.NewNoopTracerProvider()
+	} else {
+		k8sClient = tracingClient{Client: k8sClient, tracer: config.TracerProvider.Tracer(tracerName)}
+	}
+
 	if config.MaxNodeTerminationPercentage == 0 {
 		config.MaxNodeTerminationPercentage = defaultMaxNodeTerminationPercentage
 	}
+	if config.NotReadyThresholdDuration < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NotReadyThresholdDuration must not be negative", config)
+	}
+	if config.RunInterval < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.RunInterval must not be negative", config)
+	}
+	if config.NotReadyThresholdDuration > 0 {
+		if config.RunInterval == 0 {
+			return nil, microerror.Maskf(invalidConfigError, "%T.RunInterval must not be empty when %T.NotReadyThresholdDuration is set", config, config)
+		}
+		config.NotReadyTickThreshold = thresholdFromDuration(config.NotReadyThresholdDuration, config.RunInterval)
+	}
 	if config.NotReadyTickThreshold == 0 {
 		config.NotReadyTickThreshold = defaultNotReadyTickThreshold
 	}
 	if config.PauseBetweenTermination == 0 {
 		config.PauseBetweenTermination = defaultPauseBetweenTermination
 	}
+	if config.PauseBetweenTermination < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.PauseBetweenTermination must not be negative", config)
+	}
+	if config.PauseBetweenTermination > maxPlausiblePauseBetweenTermination {
+		config.Logger.Log("level", "warning", "message", fmt.Sprintf("%T.PauseBetweenTermination of %s is longer than %s, nodes will rarely be terminated", config, config.PauseBetweenTermination, maxPlausiblePauseBetweenTermination))
+	}
+	if config.TickAnnotationKey == "" {
+		config.TickAnnotationKey = annotationNodeNotReadyTick
+	}
+	if config.Strategy == nil {
+		config.Strategy = HighestTickFirstStrategy{TickAnnotationKey: config.TickAnnotationKey}
+	}
+	if config.NodeReadyUnknownThreshold == 0 {
+		config.NodeReadyUnknownThreshold = nodeNotReadyDuration
+	}
+	if config.DiskFullDuration == 0 {
+		config.DiskFullDuration = nodeNotReadyDuration
+	}
+	if config.AllocatablePressureDuration == 0 {
+		config.AllocatablePressureDuration = nodeNotReadyDuration
+	}
+	if config.NewNodeGracePeriod == 0 {
+		config.NewNodeGracePeriod = defaultNewNodeGracePeriod
+	}
+	if config.EstablishedNodeAge == 0 {
+		config.EstablishedNodeAge = defaultEstablishedNodeAge
+	}
+	if config.TerminationHistoryNamespace == "" {
+		config.TerminationHistoryNamespace = defaultTerminationHistoryNamespace
+	}
+	if config.MaxNodeDataStaleness < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxNodeDataStaleness must not be negative", config)
+	}
+	if config.MaxNodeDataStaleness > 0 && config.NodeDataFreshness == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NodeDataFreshness must not be empty when %T.MaxNodeDataStaleness is set", config, config)
+	}
+	if config.RecentTerminationWindow < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.RecentTerminationWindow must not be negative", config)
+	}
+	if config.RecentTerminationWindow > 0 && config.RecentTerminationStore == nil && config.RecentTerminationConfigMap == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.RecentTerminationConfigMap must not be empty when %T.RecentTerminationWindow is set", config, config)
+	}
+	if config.RecentTerminationNamespace == "" {
+		config.RecentTerminationNamespace = defaultRecentTerminationNamespace
+	}
+	if config.RecentTerminationMatch == nil {
+		config.RecentTerminationMatch = MatchRecentTermination
+	}
+	if config.StateNamespace == "" {
+		config.StateNamespace = defaultStateNamespace
+	}
+	if config.TerminationHistoryWindow == 0 {
+		config.TerminationHistoryWindow = defaultTerminationHistoryWindow
+	}
+	if config.MaxMasterTerminations == 0 {
+		config.MaxMasterTerminations = defaultMaxMasterTerminations
+	}
+	if config.MaxMasterTerminations < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxMasterTerminations must not be negative", config)
+	}
+	if config.MasterCountNamespace == "" {
+		config.MasterCountNamespace = defaultMasterCountNamespace
+	}
+	if config.DynamicThreshold && config.ThresholdFormula == nil {
+		config.ThresholdFormula = defaultThresholdFormula(config.NotReadyTickThreshold)
+	}
+	if !config.DynamicThreshold {
+		config.ThresholdFormula = nil
+	}
+	if config.UpdateRateLimit < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.UpdateRateLimit must not be negative", config)
+	}
+	if config.UpdateBurst < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.UpdateBurst must not be negative", config)
+	}
+	if config.UpdateRateLimit > 0 && config.UpdateBurst == 0 {
+		config.UpdateBurst = 1
+	}
+	if config.Parallelism < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Parallelism must not be negative", config)
+	}
+	if config.Parallelism == 0 {
+		config.Parallelism = defaultParallelism
+	}
+	if config.MaxNodeTerminationsPerRun < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxNodeTerminationsPerRun must not be negative", config)
+	}
+	if config.MinReadyNodesPerPool < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MinReadyNodesPerPool must not be negative", config)
+	}
+	if config.MinReadyNodesPerPool > 0 && config.NodePoolLabel == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NodePoolLabel must not be empty when %T.MinReadyNodesPerPool is set", config, config)
+	}
+	if len(config.NodePoolConfigs) > 0 && config.NodePoolLabel == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NodePoolLabel must not be empty when %T.NodePoolConfigs is set", config, config)
+	}
+	for pool, c := range config.NodePoolConfigs {
+		if c.MaxTerminationPercentage < 0 || c.NotReadyTickThreshold < 0 || c.MaxMasterTerminations < 0 {
+			return nil, microerror.Maskf(invalidConfigError, "%T.NodePoolConfigs of pool %#q must not be negative", config, pool)
+		}
+	}
+	if config.CordonDwellDuration < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CordonDwellDuration must not be negative", config)
+	}
+	if config.ListPageSize < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ListPageSize must not be negative", config)
+	}
+	if config.IdleCordonedNodeDuration < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.IdleCordonedNodeDuration must not be negative", config)
+	}
+	if config.LifetimeTickThreshold < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.LifetimeTickThreshold must not be negative", config)
+	}
+	if config.NeverReadyTimeout < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NeverReadyTimeout must not be negative", config)
+	}
+	if config.NodeReadyUnknownThreshold < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NodeReadyUnknownThreshold must not be negative", config)
+	}
+	if config.DegradedModeCacheTTL < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DegradedModeCacheTTL must not be negative", config)
+	}
+	if config.DegradedMode && config.DegradedModeCacheTTL == 0 {
+		config.DegradedModeCacheTTL = defaultDegradedModeCacheTTL
+	}
+	if config.ConsecutiveUnhealthyRuns < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ConsecutiveUnhealthyRuns must not be negative", config)
+	}
+	if config.DiskFullDuration < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DiskFullDuration must not be negative", config)
+	}
+	if config.AllocatablePressureDuration < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.AllocatablePressureDuration must not be negative", config)
+	}
+	for name, fraction := range config.MinAllocatableFraction {
+		if fraction <= 0 || fraction > 1 {
+			return nil, microerror.Maskf(invalidConfigError, "%T.MinAllocatableFraction for %s must be greater than 0 and at most 1", config, name)
+		}
+	}
+	if config.StaleHeartbeatDuration < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.StaleHeartbeatDuration must not be negative", config)
+	}
+	if config.NewNodeGracePeriod < 0 || config.NewNodeGracePeriod > config.EstablishedNodeAge {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NewNodeGracePeriod must be between 0 and %T.EstablishedNodeAge", config, config)
+	}
+	if config.TerminationHistoryWindow < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.TerminationHistoryWindow must not be negative", config)
+	}
+	if config.MaxTerminationsPerPool < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxTerminationsPerPool must not be negative", config)
+	}
+	if config.MaxTerminationsPerPool > 0 && config.TerminationHistoryStore == nil && config.TerminationHistoryConfigMap == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.TerminationHistoryConfigMap must not be empty when %T.MaxTerminationsPerPool is set", config, config)
+	}
+	if config.EscalationTerminations < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.EscalationTerminations must not be negative", config)
+	}
+	if config.EscalatedTickThreshold < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.EscalatedTickThreshold must not be negative", config)
+	}
+	if config.EscalationTerminations > 0 && config.TerminationHistoryStore == nil && config.TerminationHistoryConfigMap == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.TerminationHistoryConfigMap must not be empty when %T.EscalationTerminations is set", config, config)
+	}
+	if config.EscalatedTickThreshold == 0 {
+		config.EscalatedTickThreshold = config.NotReadyTickThreshold * 2
+	}
+	for _, f := range config.NodeFilters {
+		if f == nil {
+			return nil, microerror.Maskf(invalidConfigError, "%T.NodeFilters must not contain empty filters", config)
+		}
+	}
+	for _, f := range config.ExtraFilters {
+		if f == nil {
+			return nil, microerror.Maskf(invalidConfigError, "%T.ExtraFilters must not contain empty filters", config)
+		}
+	}
+	nodeFilters := NodeSelector{ExcludeAnnotationFilter(NodeSkipAnnotation, "true")}
+	for _, key := range config.OperationalExclusionLabels {
+		if key == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.OperationalExclusionLabels must not contain empty keys", config)
+		}
+		nodeFilters = append(nodeFilters, ExcludeLabelFilter(key, ""))
+	}
+	for _, key := range config.OperationalExclusionTaints {
+		if key == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.OperationalExclusionTaints must not contain empty keys", config)
+		}
+		nodeFilters = append(nodeFilters, ExcludeTaintFilter(key, ""))
+	}
+	nodeFilters = append(nodeFilters, config.NodeFilters...)
+	if len(config.LoggerFields) > 0 {
+		config.Logger = config.Logger.With(loggerKeyVals(config.LoggerFields)...)
+	}
+	if errs := validation.IsQualifiedName(config.TickAnnotationKey); len(errs) > 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.TickAnnotationKey must be a valid annotation key: %s", config, strings.Join(errs, ", "))
+	}
+	if config.BadNodeLabel != "" {
+		if errs := validation.IsQualifiedName(config.BadNodeLabel); len(errs) > 0 {
+			return nil, microerror.Maskf(invalidConfigError, "%T.BadNodeLabel must be a valid label key: %s", config, strings.Join(errs, ", "))
+		}
+	}
+
+	healthCheck := newNodeHealthCheck(config.Clock, !config.DisableLegacyConditionSupport)
+	healthCheck.staleHeartbeatDuration = config.StaleHeartbeatDuration
+	healthCheck.readyUnknownDuration = config.NodeReadyUnknownThreshold
+	healthCheck.diskFullDuration = config.DiskFullDuration
+	healthCheck.minAllocatableFraction = config.MinAllocatableFraction
+	healthCheck.allocatablePressureDuration = config.AllocatablePressureDuration
+	healthCheck.externalHealth = config.ExternalHealth
+	healthCheck.predicate = config.UnhealthyPredicate
+
+	nodeSelector := client.MatchingLabels{}
+	if config.NodeOS != "" {
+		nodeSelector[corev1.LabelOSStable] = config.NodeOS
+	}
+	if config.NodeArch != "" {
+		nodeSelector[corev1.LabelArchStable] = config.NodeArch
+	}
 
 	d := &Detector{
 		logger:    config.Logger,
-		k8sClient: config.K8sClient,
+		k8sClient: metricsClient{Client: k8sClient, metrics: config.Metrics},
+		clock:     config.Clock,
+		metrics:   config.Metrics,
+		tracer:    config.TracerProvider.Tracer(tracerName),
+
+		healthCheck: healthCheck,
+		parallelism: config.Parallelism,
 
 		maxNodeTerminationPercentage: config.MaxNodeTerminationPercentage,
+		maxNodeTerminationsPerRun:    config.MaxNodeTerminationsPerRun,
+		maxMasterTerminations:        config.MaxMasterTerminations,
+		masterCountConfigMap:         config.MasterCountConfigMap,
+		masterCountNamespace:         config.MasterCountNamespace,
 		notReadyTickThreshold:        config.NotReadyTickThreshold,
 		pauseBetweenTermination:      config.PauseBetweenTermination,
+		tickAnnotationKey:            config.TickAnnotationKey,
+		badNodeLabel:                 config.BadNodeLabel,
+		cordonDwellDuration:          config.CordonDwellDuration,
+		idleCordonedNodeDuration:     config.IdleCordonedNodeDuration,
+		neverReadyTimeout:            config.NeverReadyTimeout,
+		lifetimeTickThreshold:        config.LifetimeTickThreshold,
+		thresholdFormula:             config.ThresholdFormula,
+		nodePoolLabel:                config.NodePoolLabel,
+		minReadyNodesPerPool:         config.MinReadyNodesPerPool,
+		nodePoolConfigs:              config.NodePoolConfigs,
+		nodeSelector:                 nodeSelector,
+		listPageSize:                 config.ListPageSize,
+		rollbackOnError:              config.RollbackOnError || config.DegradedMode,
+		disableRecovery:              config.DisableRecovery,
+		nodeFilters:                  nodeFilters,
+		extraFilters:                 config.ExtraFilters,
+		canaryMode:                   config.CanaryMode,
+		newNodeGracePeriod:           config.NewNodeGracePeriod,
+		establishedNodeAge:           config.EstablishedNodeAge,
+		sortBadNodesByTickCount:      config.SortBadNodesByTickCount,
+		strategy:                     config.Strategy,
+		shouldTerminate:              config.ShouldTerminate,
+		terminationHistory:           config.TerminationHistoryStore,
+		terminationHistoryWindow:     config.TerminationHistoryWindow,
+		maxTerminationsPerPool:       config.MaxTerminationsPerPool,
+		escalationTerminations:       config.EscalationTerminations,
+		escalatedTickThreshold:       config.EscalatedTickThreshold,
+		terminationDiagnostics:       config.AnnotateTerminationDiagnostics,
+		recordLastDetectionRun:       config.RecordLastDetectionRun,
+		stateStore:                   config.StateStore,
+		maxNodeDataStaleness:         config.MaxNodeDataStaleness,
+		nodeDataFreshness:            config.NodeDataFreshness,
+		selfNodeName:                 config.SelfNodeName,
+		runInterval:                  config.RunInterval,
+		recentTerminations:           config.RecentTerminationStore,
+		recentTerminationWindow:      config.RecentTerminationWindow,
+		recentTerminationMatch:       config.RecentTerminationMatch,
+	}
+
+	if config.ConsecutiveUnhealthyRuns > 1 {
+		d.unhealthyDebounce = newUnhealthyDebounce(config.ConsecutiveUnhealthyRuns)
+	}
+	if config.DegradedMode {
+		d.degradedMode = newDegradedModeCache(config.DegradedModeCacheTTL)
+	}
+	if config.UpdateRateLimit > 0 {
+		d.updateLimiter = rate.NewLimiter(config.UpdateRateLimit, config.UpdateBurst)
+	}
+
+	// the stale heartbeat check depends on the heartbeat times, which the cache does not compare
+	// and the external health can change without any change of the node
+	// and the unhealthy predicate can depend on more than the condition status, ie: labels
+	// and the allocatable pressure depends on the node status resources
+	if !config.DisableHealthCheckCache && config.StaleHeartbeatDuration == 0 && config.ExternalHealth == nil && config.UnhealthyPredicate == nil && len(config.MinAllocatableFraction) == 0 {
+		d.conditionCache = newNodeConditionCache()
+	}
+	if d.terminationHistory == nil && config.TerminationHistoryConfigMap != "" {
+		d.terminationHistory = NewConfigMapTerminationHistoryStore(d.k8sClient, config.TerminationHistoryNamespace, config.TerminationHistoryConfigMap)
+	}
+	if d.recentTerminations == nil && config.RecentTerminationWindow > 0 {
+		d.recentTerminations = NewConfigMapRecentTerminationStore(d.k8sClient, config.RecentTerminationNamespace, config.RecentTerminationConfigMap)
+	}
+	if d.stateStore == nil && config.StateConfigMap != "" {
+		d.stateStore = NewConfigMapStateStore(d.k8sClient, config.StateNamespace, config.StateConfigMap)
 	}
 
 	return d, nil
 }
 
 // DetectBadNodes will return list of nodes that should be terminated which in documentation terminology is used as 'marked for termination'.
+// When the context is cancelled during the run, the nodes processed so far are returned together with the context error.
+// The partial result is limited the same way as a complete result, but it does not consider the nodes not processed yet.
 func (d *Detector) DetectBadNodes(ctx context.Context) ([]corev1.Node, error) {
-	var nodeList corev1.NodeList
-	{
-		err := d.k8sClient.List(ctx, &nodeList)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
+	result, err := d.DetectBadNodesWithResult(ctx)
+	if err != nil {
+		return result.Nodes(), microerror.Mask(err)
+	}
+
+	return result.Nodes(), nil
+}
+
+// DetectBadNodesWithResult works like DetectBadNodes, but returns details like the tick count
+// and the lifecycle state of every node 'marked for termination'.
+// With DegradedMode enabled, failures to list the nodes return the result of the last successful run,
+// see DegradedModeResult.
+func (d *Detector) DetectBadNodesWithResult(ctx context.Context) (DetectBadNodesResult, error) {
+	ctx, span := d.tracer.Start(ctx, spanDetectBadNodes)
+	defer span.End()
+
+	result, err := d.detectBadNodesWithDegradedMode(ctx, span)
+	endDetectionSpan(span, result, err, err != nil && ctx.Err() != nil)
+
+	return result, microerror.Mask(err)
+}
+
+func (d *Detector) detectBadNodesWithDegradedMode(ctx context.Context, span trace.Span) (DetectBadNodesResult, error) {
+	result, err := d.detectBadNodesWithResult(ctx, span)
+	if d.degradedMode == nil {
+		return result, microerror.Mask(err)
+	}
+	if err == nil {
+		d.degradedMode.set(result, d.clock.Now())
+		return result, nil
+	}
+	if degraded, ok := d.degradedResult(ctx, err); ok {
+		return degraded, nil
+	}
+
+	return result, microerror.Mask(err)
+}
+
+func (d *Detector) detectBadNodesWithResult(ctx context.Context, span trace.Span) (DetectBadNodesResult, error) {
+	start := d.clock.Now()
+	defer func() {
+		d.metrics.ObserveDetectionDuration(d.clock.Now().Sub(start))
+	}()
+
+	// the channel requested by NodeStateEvents is closed when the run returns
+	events := d.takeNodeStateEvents()
+	if events != nil {
+		defer close(events)
+	}
+
+	// every log line of this run carries the same run id so all lines of a single detection pass can be correlated
+	runID := rand.String(runIDLength)
+	logger := d.logger.With("run", runID)
+
+	// restore the bookkeeping of a previous detector before the first run
+	d.loadState(ctx, logger)
+
+	// acting on stale node data could terminate nodes which recovered already
+	err := d.checkNodeDataFreshness(ctx, d.clock.Now())
+	if err != nil {
+		return DetectBadNodesResult{}, microerror.Mask(err)
 	}
 
+	r, err := d.newDetectionRun(ctx, runID, logger, events)
+	if err != nil {
+		return DetectBadNodesResult{}, microerror.Mask(err)
+	}
+	span.SetAttributes(attribute.Int(spanAttributeThreshold, r.threshold))
+
 	// badNodes list will contain all nodes that reached tick threshold and are 'marked for termination'
 	var badNodes []corev1.Node
-	for i, n := range nodeList.Items {
-		notReadyTickCount, updated := nodeNotReadyTickCount(ctx, d.logger, n)
+	// nodeCount and readyNodesPerPool are accumulated over all pages of the node list
+	// and include the nodes excluded by the node filters, so filtering nodes does not lower the termination limits
+	nodeCount := 0
+	nodesPerPool := map[string]int{}
+	readyNodesPerPool := map[string]int{}
+	// the cluster health score includes the nodes excluded by the node filters as well
+	var health healthScoreAccumulator
+	firstPage := true
+	err = d.forEachNodeList(ctx, func(nodeList corev1.NodeList) error {
+		nodes := nodeList.Items
+		// the threshold is computed once from the listing itself instead of listing all nodes upfront,
+		// the static threshold is kept when the node count is unknown
+		if firstPage {
+			if estimated, ok := d.estimateNodeCount(nodeList); ok {
+				r.threshold = d.effectiveThreshold(estimated)
+			}
+			logger.LogCtx(ctx, "level", "debug", "message", "computed effective tick threshold", "effectiveThreshold", r.threshold)
+			span.SetAttributes(attribute.Int(spanAttributeThreshold, r.threshold))
+			firstPage = false
+		}
+
+		nodeCount += len(nodes)
+		countNodesPerPool(nodesPerPool, nodes, d.nodePoolLabel)
+		countReadyNodesPerPool(readyNodesPerPool, nodes, d.nodePoolLabel)
 
-		if notReadyTickCount >= d.notReadyTickThreshold {
-			badNodes = append(badNodes, n)
+		bad, err := d.processNodes(ctx, r, d.handledNodes(ctx, nodes))
+		badNodes = append(badNodes, bad...)
+		if err != nil {
+			return microerror.Mask(err)
 		}
 
-		// if the tick counter changed, we need to update the value in the k8s api
-		if updated {
-			// update the tick count on the node
-			n.Annotations[annotationNodeNotReadyTick] = fmt.Sprintf("%d", notReadyTickCount)
-			err := d.k8sClient.Update(ctx, &nodeList.Items[i])
-			if err != nil {
-				return nil, microerror.Mask(err)
+		for _, n := range nodes {
+			tick, ok := r.tickCount(n)
+			if !ok {
+				tick = nodeTickCount(n, d.tickAnnotationKey)
 			}
-			d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updated not ready tick count to %d/%d for node %s", notReadyTickCount, d.notReadyTickThreshold, n.Name))
+			health.add(tick)
 		}
+		return nil
+	})
+	// a cancelled context returns the partial result instead of failing the run
+	cancelled := err != nil && ctx.Err() != nil
+	if err == nil {
+		d.setNodeCount(nodeCount)
+	}
+	if err == nil && d.conditionCache != nil {
+		// forget nodes which are gone
+		d.conditionCache.prune(r.seen)
+	}
+	if err == nil && d.unhealthyDebounce != nil {
+		d.unhealthyDebounce.prune(r.seen)
+	}
+	if err == nil {
+		d.saveState(ctx, logger, r.now, nodeCount)
+		d.metrics.SetNodeNotReadyTickCounts(r.tickCounts)
+		d.metrics.SetClusterHealthScore(health.clusterScore(r.threshold))
+	}
+	if err == nil && len(r.escalatedPools) > 0 {
+		err = d.resetRecoveredPools(ctx, r)
+	}
+	if err != nil && !cancelled {
+		// revert the annotations changed so far to leave the cluster in the state before the run
+		if r.rollback != nil {
+			d.rollbackAnnotations(ctx, r)
+		}
+		return DetectBadNodesResult{}, microerror.Mask(err)
 	}
 
-	// remove additional master nodes to avoid multiple master node termination at the same time
-	badNodes = removeMultipleMasterNodes(badNodes)
-	d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d nodes marked for termination", len(badNodes)))
+	// keep enough Ready nodes in each node pool to avoid emptying a pool
+	if d.minReadyNodesPerPool > 0 {
+		count := len(badNodes)
+		badNodes = limitPoolTerminations(readyNodesPerPool, badNodes, d.nodePoolLabel, d.minReadyNodesPerPool)
+		if len(badNodes) < count {
+			logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("held back %d nodes to keep at least %d Ready nodes per node pool", count-len(badNodes), d.minReadyNodesPerPool))
+		}
+	}
+
+	// the node the detector runs on must not take up a termination slot
+	badNodes = d.removeSelfNode(ctx, logger, badNodes)
+
+	// replacements of recently terminated nodes are still bootstrapping and must not be terminated again
+	if d.recentTerminationWindow > 0 && !cancelled {
+		badNodes, err = d.suppressRecentTerminations(ctx, logger, badNodes, r.now)
+		if err != nil {
+			if r.rollback != nil {
+				d.rollbackAnnotations(ctx, r)
+			}
+			return DetectBadNodesResult{}, microerror.Mask(err)
+		}
+	}
+
+	// prefer terminating the nodes which are bad for the longest time when the termination is limited
+	if d.sortBadNodesByTickCount {
+		d.sortBadNodes(badNodes, r.now)
+	}
+
+	// the master node limit only considers the role label, so contradicting role labels need attention
+	logAmbiguousNodeRoles(ctx, logger, badNodes)
+
+	// remove additional master nodes to avoid too many master node terminations at the same time
+	// and apply the termination limits of the configured node pools
+	maxMasterTerminations := d.maxMasterTerminations
+	if !cancelled {
+		maxMasterTerminations, err = d.effectiveMaxMasterTerminations(ctx, logger)
+		if err != nil {
+			if r.rollback != nil {
+				d.rollbackAnnotations(ctx, r)
+			}
+			return DetectBadNodesResult{}, microerror.Mask(err)
+		}
+	}
+	badNodes = d.limitNodePools(badNodes, nodesPerPool, maxMasterTerminations)
+	badNodes = d.extraFilters.Apply(badNodes)
+	logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d nodes marked for termination", len(badNodes)))
+
+	// terminate a single node at most per run in canary mode
+	if d.canaryMode {
+		badNodes = d.selectCanaryNode(ctx, r, badNodes)
+	}
 
 	// check for node termination limit, to prevent termination of all nodes at once
-	maxNodeTermination := maximumNodeTermination(len(nodeList.Items), d.maxNodeTerminationPercentage)
+	maxNodeTermination := d.maxNodeTermination(nodeCount)
 	if len(badNodes) > maxNodeTermination {
-		badNodes = badNodes[:maxNodeTermination]
-		d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("limited node termination to %d nodes", maxNodeTermination))
+		badNodes = d.strategy.Select(badNodes, maxNodeTermination)
+		logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("limited node termination to %d nodes", maxNodeTermination))
+	}
+
+	// enforce the termination limits of the node pools across restarts
+	if d.terminationHistory != nil {
+		count := len(badNodes)
+		badNodes, err = d.limitTerminationHistory(ctx, badNodes, r.now)
+		if err != nil {
+			if r.rollback != nil {
+				d.rollbackAnnotations(ctx, r)
+			}
+			return DetectBadNodesResult{}, microerror.Mask(err)
+		}
+		if len(badNodes) < count {
+			logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("held back %d nodes to not exceed %d terminations per node pool within %s", count-len(badNodes), d.maxTerminationsPerPool, d.terminationHistoryWindow))
+		}
 	}
 
-	return badNodes, nil
+	// remember the nodes 'marked for termination' to suppress their replacements
+	if d.recentTerminationWindow > 0 && !cancelled {
+		err = d.recordRecentTerminations(ctx, badNodes, r.now)
+		if err != nil {
+			if r.rollback != nil {
+				d.rollbackAnnotations(ctx, r)
+			}
+			return DetectBadNodesResult{}, microerror.Mask(err)
+		}
+	}
+
+	// capture the diagnostic context before the nodes are terminated
+	if d.terminationDiagnostics && !cancelled {
+		d.annotateTerminationDiagnostics(ctx, r, badNodes)
+	}
+
+	result := d.newDetectBadNodesResult(badNodes, r)
+	d.publishRunSummary(ctx, r, nodeCount, result)
+	span.SetAttributes(attribute.Int(spanAttributeNodeCount, nodeCount))
+
+	if cancelled {
+		logger.LogCtx(ctx, "level", "debug", "message", "context is done, returning partial result")
+		return result, microerror.Mask(ctx.Err())
+	}
+
+	d.metrics.AddBadNodesDetected(len(badNodes))
+
+	return result, nil
 }
 
-// ResetTickCounters will reset tick counters to zero on all k8s nodes in a cluster
-func (d *Detector) ResetTickCounters(ctx context.Context) error {
-	var nodeList corev1.NodeList
-	{
-		err := d.k8sClient.List(ctx, &nodeList)
+// newDetectionRun computes the effective tick threshold and collects the data shared by all nodes of a run.
+func (d *Detector) newDetectionRun(ctx context.Context, runID string, logger micrologger.Logger, events chan NodeStateEvent) (*detectionRun, error) {
+	// DetectBadNodes computes the threshold again from the first page of the node list
+	threshold := d.notReadyTickThreshold
+	if previous := d.previousNodeCount(); previous > 0 {
+		threshold = d.effectiveThreshold(previous)
+	}
+
+	// activePods is only needed to detect cordoned nodes which are idle
+	var activePods map[string]int
+	if d.idleCordonedNodeDuration > 0 {
+		var err error
+		activePods, err = d.activePodsPerNode(ctx)
 		if err != nil {
-			return microerror.Mask(err)
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	r := d.initDetectionRun(runID, logger, threshold, activePods, events)
+	if d.escalationTerminations > 0 {
+		escalatedPools, err := d.escalatedPools(ctx, r.now)
+		if err != nil {
+			return nil, microerror.Mask(err)
 		}
+		r.escalatedPools = escalatedPools
+		d.logEscalatedPools(ctx, r)
 	}
 
-	for i, node := range nodeList.Items {
-		if _, ok := node.GetAnnotations()[annotationNodeNotReadyTick]; ok {
-			node.Annotations[annotationNodeNotReadyTick] = "0"
+	return r, nil
+}
 
-			err := d.k8sClient.Update(ctx, &nodeList.Items[i])
-			if err != nil {
-				return microerror.Mask(err)
-			}
+// initDetectionRun returns a run with the given cluster-wide data and empty per node state.
+func (d *Detector) initDetectionRun(runID string, logger micrologger.Logger, threshold int, activePods map[string]int, events chan NodeStateEvent) *detectionRun {
+	r := &detectionRun{
+		id:                     runID,
+		logger:                 logger,
+		threshold:              threshold,
+		now:                    d.clock.Now(),
+		activePods:             activePods,
+		reasons:                map[string]BadNodeReason{},
+		seen:                   map[types.UID]struct{}{},
+		events:                 events,
+		unhealthyPools:         map[string]bool{},
+		tickCounts:             map[string]int{},
+		recordLastDetectionRun: d.recordLastDetectionRun,
+	}
+	if d.rollbackOnError {
+		r.rollback = newAnnotationRollback()
+	}
+	return r
+}
+
+// detectionRun holds the state of a single DetectBadNodes run.
+type detectionRun struct {
+	id         string
+	logger     micrologger.Logger
+	threshold  int
+	now        time.Time
+	activePods map[string]int
+	// reasons contains the reason why a node was 'marked for termination' by node name.
+	reasons map[string]BadNodeReason
+	// escalatedPools contains the node pools with an escalated tick threshold.
+	escalatedPools map[string]bool
+	// unhealthyPools contains the node pools with a node with a nonzero tick count.
+	unhealthyPools map[string]bool
+	// tickCounts contains the tick count of every processed node by node name.
+	tickCounts map[string]int
+	// rollback tracks the changed annotations when RollbackOnError is enabled.
+	rollback *annotationRollback
+	// seen contains the uids of all processed nodes to prune the condition cache and the unhealthy debounce.
+	seen map[types.UID]struct{}
+	// events receives the state of every processed node when requested by NodeStateEvents.
+	events chan NodeStateEvent
+	// recordLastDetectionRun writes the LastDetectionRunAnnotation with the tick count.
+	recordLastDetectionRun bool
+	// patch writes the changed annotations and labels with a merge patch instead of an update.
+	patch bool
+
+	// mutex guards reasons, seen and rollback when nodes are processed concurrently.
+	mutex sync.Mutex
+}
+
+// processNode updates the tick counter and the other tracking annotations of the node
+// and returns true if the node should be 'marked for termination'.
+func (d *Detector) processNode(ctx context.Context, r *detectionRun, n *corev1.Node) (bool, error) {
+	logger := r.logger
+	threshold := d.nodeThreshold(r, *n)
+	now := r.now
+
+	// keep the annotations before any change to be able to revert them
+	original := n.DeepCopy()
+
+	// track since when the allocatable of the node is collapsed, the health check depends on it
+	var pressureUpdated bool
+	if len(d.healthCheck.minAllocatableFraction) > 0 {
+		pressureUpdated = nodeAllocatablePressureSince(n, d.healthCheck.minAllocatableFraction, now)
+	}
+
+	// nodes which were healthy in the previous run and did not change since keep a zero tick count
+	cached := d.conditionCache != nil && hasZeroTickCount(*n, d.tickAnnotationKey) && d.conditionCache.unchanged(*n)
+
+	var notReadyTickCount int
+	var updated bool
+	if !cached {
+		notReadyTickCount, updated = nodeNotReadyTickCount(ctx, logger, d.healthCheck, *n, d.tickAnnotationKey, d.disableRecovery)
+	}
+	r.markSeen(*n)
+
+	// the tick count is only increased once the node was observed unhealthy in enough consecutive runs
+	var debounced bool
+	if d.unhealthyDebounce != nil {
+		previousTickCount := nodeTickCount(*original, d.tickAnnotationKey)
+		increased := updated && notReadyTickCount > previousTickCount
+		if increased && !d.unhealthyDebounce.observe(*n, true) {
+			logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("node %s is unhealthy but not for %d consecutive runs yet", n.Name, d.unhealthyDebounce.runs))
+			notReadyTickCount, updated, debounced = previousTickCount, false, true
+		} else if !increased {
+			d.unhealthyDebounce.observe(*n, false)
 		}
 	}
 
-	return nil
+	r.setTickCount(*n, notReadyTickCount)
+
+	// escalated pools are reset once all of their nodes recovered
+	if notReadyTickCount > 0 && d.escalationTerminations > 0 {
+		r.markUnhealthyPool(n.Labels[d.nodePoolLabel])
+	}
+
+	if d.conditionCache != nil {
+		// a zero tick count which did not change means the node was healthy, but only nodes with healthy
+		// condition statuses stay healthy as long as the statuses do not change
+		if notReadyTickCount == 0 && !updated && !debounced && d.healthCheck.hasHealthyConditionStatus(*n) {
+			d.conditionCache.set(*n)
+		} else {
+			d.conditionCache.delete(*n)
+		}
+	}
+	if updated {
+		setAnnotation(n, d.tickAnnotationKey, fmt.Sprintf("%d", notReadyTickCount))
+		if r.recordLastDetectionRun {
+			setAnnotation(n, LastDetectionRunAnnotation, fmt.Sprintf("%s %s", r.id, now.Format(time.RFC3339)))
+		}
+		// flapping nodes accumulate tick increases although their tick count recovers in between
+		if d.lifetimeTickThreshold > 0 && notReadyTickCount > nodeTickCount(*original, d.tickAnnotationKey) {
+			increaseLifetimeTickCount(n)
+		}
+	}
+
+	// record which conditions caused the node to reach the tick threshold
+	fingerprintUpdated := updateNodeFingerprint(n, d.healthCheck, notReadyTickCount, threshold)
+
+	// track since when the node is cordoned
+	var cordonedAt time.Time
+	var cordonUpdated bool
+	if d.cordonDwellDuration > 0 || d.idleCordonedNodeDuration > 0 {
+		cordonedAt, cordonUpdated = nodeCordonedAt(n, now)
+	}
+
+	bad := d.isNodeMarkedForTermination(ctx, r, n, notReadyTickCount, threshold, cordonedAt)
+
+	// the bad node label is changed with the same update as the annotations
+	var labelUpdated bool
+	if d.badNodeLabel != "" {
+		labelUpdated = setBadNodeLabel(n, d.badNodeLabel, bad)
+	}
+
+	// if the annotations or the label changed, we need to update the values in the k8s api
+	if updated || fingerprintUpdated || cordonUpdated || pressureUpdated || labelUpdated {
+		err := d.waitForUpdate(ctx)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		if r.patch {
+			err = d.k8sClient.Patch(ctx, n, client.MergeFrom(original))
+		} else {
+			err = d.k8sClient.Update(ctx, n)
+		}
+		if err != nil {
+			return false, microerror.Maskf(nodeUpdateError, "failed to update node %s: %s", n.Name, err.Error())
+		}
+		r.trackRollback(*original, *n)
+		if updated {
+			logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updated not ready tick count to %d/%d for node %s", notReadyTickCount, threshold, n.Name))
+		}
+		if labelUpdated && bad {
+			logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("set label %s on node %s", d.badNodeLabel, n.Name))
+		} else if labelUpdated {
+			logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("removed label %s from node %s", d.badNodeLabel, n.Name))
+		}
+	}
+
+	return bad, nil
 }
 
-// isNodeUnhealthy returns true of the node is not ready for certain period of time
-// this is used to detect bad nodes
-func isNodeUnhealthy(ctx context.Context, logger micrologger.Logger, n corev1.Node) bool {
-	// trueConditions have to be true, otherwise node has to be considered unhealthy.
-	for _, trueCondition := range trueConditions {
-		for _, c := range n.Status.Conditions {
-			if string(c.Type) == trueCondition && c.Status != corev1.ConditionTrue {
-				// We want condition to be true, but it's not.
-				if time.Since(c.LastHeartbeatTime.Time) >= nodeNotReadyDuration {
-					logger.Debugf(ctx, "node %s is unhealthy because we expected condition %s to be true, but was false", n.Name, c.Type)
-					return true
-				}
-			}
+// isNodeMarkedForTermination returns true if the node should be 'marked for termination' and records the reason.
+func (d *Detector) isNodeMarkedForTermination(ctx context.Context, r *detectionRun, n *corev1.Node, notReadyTickCount int, threshold int, cordonedAt time.Time) bool {
+	logger := r.logger
+	now := r.now
+
+	if d.neverReadyTimeout > 0 && isNodeNeverReady(*n, now, d.neverReadyTimeout) {
+		logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("node %s did not become Ready within %s after its creation", n.Name, d.neverReadyTimeout))
+		r.setReason(*n, BadNodeReasonNeverReady)
+		return true
+	}
+
+	if d.lifetimeTickThreshold > 0 && nodeLifetimeTickCount(*n) >= d.lifetimeTickThreshold {
+		logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("node %s reached lifetime tick threshold %d", n.Name, d.lifetimeTickThreshold))
+		r.setReason(*n, BadNodeReasonLifetimeTickThreshold)
+		return true
+	}
+
+	reachedThreshold := notReadyTickCount >= threshold
+	if d.shouldTerminate != nil {
+		reachedThreshold = d.shouldTerminate(*n, notReadyTickCount)
+	}
+
+	if reachedThreshold {
+		// cordoned nodes have to stay cordoned for a while before they can be terminated
+		if !nodeCordonDwellElapsed(*n, cordonedAt, d.cordonDwellDuration, now) {
+			logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("node %s reached tick threshold but is not cordoned for %s yet", n.Name, d.cordonDwellDuration))
+			return false
 		}
+		r.setReason(*n, BadNodeReasonTickThreshold)
+		return true
 	}
 
-	// falseConditions have to be false, otherwise node has to be considered unhealthy.
-	for _, falseCondition := range falseConditions {
-		for _, c := range n.Status.Conditions {
-			if string(c.Type) == falseCondition && c.Status == corev1.ConditionTrue {
-				// we want condition to be false, but it's not.
-				if time.Since(c.LastHeartbeatTime.Time) >= nodeNotReadyDuration {
-					logger.Debugf(ctx, "node %s is unhealthy because we expected condition %s to be false, but was true", n.Name, c.Type)
-					return true
+	if isNodeIdleCordoned(*n, cordonedAt, d.idleCordonedNodeDuration, r.activePods[n.Name], now) {
+		logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("node %s is cordoned for more than %s without active pods", n.Name, d.idleCordonedNodeDuration))
+		r.setReason(*n, BadNodeReasonIdleCordoned)
+		return true
+	}
+
+	return false
+}
+
+// ResetTickCounters will reset tick counters to zero on all k8s nodes in a cluster
+func (d *Detector) ResetTickCounters(ctx context.Context) error {
+	err := d.forEachNodePage(ctx, func(nodes []corev1.Node) error {
+		for i, node := range nodes {
+			if _, ok := node.GetAnnotations()[d.tickAnnotationKey]; ok {
+				node.Annotations[d.tickAnnotationKey] = "0"
+
+				err := d.waitForUpdate(ctx)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+
+				err = d.k8sClient.Update(ctx, &nodes[i])
+				if err != nil {
+					return microerror.Maskf(nodeUpdateError, "failed to update node %s: %s", node.Name, err.Error())
 				}
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
 	}
-	return false
+
+	return nil
 }
 
 // updateNodeNotReadyTickAnnotations will update annotations on the node
 // depending if the node is Ready or not
 // the annotation is used to track how many times node was seen as not ready
 // and in case it will reach a threshold, the node will be marked for termination.
-// Each run of this function can increase or decrease the tick count by 1.
+// Each run of this function can increase or decrease the tick count by 1,
+// with disabled recovery the tick count is never decreased.
 // function return a tick counter (int) and a bool indicating if the value changed
-func nodeNotReadyTickCount(ctx context.Context, logger micrologger.Logger, n corev1.Node) (int, bool) {
+func nodeNotReadyTickCount(ctx context.Context, logger micrologger.Logger, healthCheck nodeHealthCheck, n corev1.Node, tickAnnotationKey string, disableRecovery bool) (int, bool) {
 	var err error
 	updated := false
 
@@ -205,9 +1306,9 @@ func nodeNotReadyTickCount(ctx context.Context, logger micrologger.Logger, n cor
 	// if there is no annotation yet, the value will be 0
 	notReadyTickCount := 0
 	{
-		tick, ok := n.Annotations[annotationNodeNotReadyTick]
+		tick, ok := n.Annotations[tickAnnotationKey]
 		if ok {
-			notReadyTickCount, err = strconv.Atoi(tick)
+			notReadyTickCount, err = parseTickCount(tick)
 			// in case the annotation is a garbage lets reset to 0 and update it
 			if err != nil {
 				notReadyTickCount = 0
@@ -216,11 +1317,18 @@ func nodeNotReadyTickCount(ctx context.Context, logger micrologger.Logger, n cor
 		}
 	}
 
+	// an unknown external health keeps the tick count as it is
+	unhealthy := healthCheck.isNodeUnhealthy(ctx, logger, n)
+	known := true
+	if !unhealthy {
+		unhealthy, known = healthCheck.isNodeExternallyUnhealthy(ctx, logger, n)
+	}
+
 	// increase or decrease the tick count depending on the node status
-	if isNodeUnhealthy(ctx, logger, n) {
+	if unhealthy {
 		notReadyTickCount++
 		updated = true
-	} else if notReadyTickCount > 0 {
+	} else if known && notReadyTickCount > 0 && !disableRecovery {
 		notReadyTickCount--
 		updated = true
 	}
@@ -228,6 +1336,55 @@ func nodeNotReadyTickCount(ctx context.Context, logger micrologger.Logger, n cor
 	return notReadyTickCount, updated
 }
 
+// parseTickCount parses the value of the tick annotation.
+// Values longer than maxTickAnnotationLength are rejected without parsing them, ie: tampered annotations.
+func parseTickCount(tick string) (int, error) {
+	if len(tick) > maxTickAnnotationLength {
+		return 0, microerror.Maskf(invalidTickAnnotationError, "tick annotation must not be longer than %d characters", maxTickAnnotationLength)
+	}
+
+	count, err := strconv.Atoi(tick)
+	if err != nil {
+		return 0, microerror.Maskf(invalidTickAnnotationError, "%s", err.Error())
+	}
+	return count, nil
+}
+
+// effectiveThreshold returns the tick threshold for the given cluster size.
+// Without dynamic threshold the configured NotReadyTickThreshold is used.
+func (d *Detector) effectiveThreshold(nodeCount int) int {
+	if d.thresholdFormula == nil {
+		return d.notReadyTickThreshold
+	}
+
+	threshold := d.thresholdFormula(nodeCount)
+	// a threshold below 1 would mark every node for termination
+	if threshold < 1 {
+		threshold = 1
+	}
+	return threshold
+}
+
+// thresholdFromDuration returns the number of runs at the given interval needed to cover the duration.
+func thresholdFromDuration(duration time.Duration, interval time.Duration) int {
+	return int(math.Ceil(float64(duration) / float64(interval)))
+}
+
+// defaultThresholdFormula returns a formula scaling the tick threshold with the cluster size
+// between 2 and the configured notReadyTickThreshold.
+func defaultThresholdFormula(notReadyTickThreshold int) func(nodeCount int) int {
+	return func(nodeCount int) int {
+		threshold := nodeCount / 10
+		if threshold > notReadyTickThreshold {
+			threshold = notReadyTickThreshold
+		}
+		if threshold < 2 {
+			threshold = 2
+		}
+		return threshold
+	}
+}
+
 // maximumNodeTermination calculates the maximum number of nodes that can be terminated on single run
 // the number is calculated with help of maxNodeTerminationPercentage
 // which determines how much percentage of nodes can be terminated
@@ -241,28 +1398,88 @@ func maximumNodeTermination(nodeCount int, maxNodeTerminationPercentage float64)
 	return int(limit)
 }
 
+// MaxTerminations returns the maximum number of nodes a single run can return as 'marked for termination'
+// for the current cluster size, without running the detection.
+func (d *Detector) MaxTerminations(ctx context.Context) (int, error) {
+	nodeCount, err := d.countNodes(ctx)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	return d.maxNodeTermination(nodeCount), nil
+}
+
+// maxNodeTermination returns the termination limit for the given cluster size,
+// which is the percentage limit capped by the absolute limit per run.
+func (d *Detector) maxNodeTermination(nodeCount int) int {
+	maxNodeTermination := maximumNodeTermination(nodeCount, d.maxNodeTerminationPercentage)
+	if d.maxNodeTerminationsPerRun > 0 && d.maxNodeTerminationsPerRun < maxNodeTermination {
+		maxNodeTermination = d.maxNodeTerminationsPerRun
+	}
+	return maxNodeTermination
+}
+
 // removeMultipleMasterNodes removes multiple master nodes from the list to avoid more than 1 master node termination at same time
 // worker nodes in the list are unaffected
+// the master node with the lexicographically smallest name is kept, so the result does not depend on the order of the list
 func removeMultipleMasterNodes(nodeList []corev1.Node) []corev1.Node {
-	foundMasterNode := false
-	// filteredNodes list will contain maximum 1 master node and unlimited number of worker nodes at the end of the function
+	return limitMasterNodes(nodeList, 1)
+}
+
+// limitMasterNodes removes master nodes from the list to keep at most maxMasterNodes master nodes
+// worker nodes in the list are unaffected
+// the master nodes with the lexicographically smallest names are kept, so the result does not depend on the order of the list
+func limitMasterNodes(nodeList []corev1.Node, maxMasterNodes int) []corev1.Node {
+	// find the master nodes which are kept
+	var masterNodes []string
+	for _, n := range nodeList {
+		if n.Labels[labelNodeRole] == labelNodeRoleMaster {
+			masterNodes = append(masterNodes, n.Name)
+		}
+	}
+	sort.Strings(masterNodes)
+
+	keptMasterNodes := map[string]bool{}
+	for i := 0; i < len(masterNodes) && i < maxMasterNodes; i++ {
+		keptMasterNodes[masterNodes[i]] = true
+	}
+
+	// filteredNodes list will contain maximum maxMasterNodes master nodes and unlimited number of worker nodes at the end of the function
 	var filteredNodes []corev1.Node
 
 	for _, n := range nodeList {
 		if n.Labels[labelNodeRole] == labelNodeRoleMaster {
-			// append only the first master that is found in the list
-			// any following master is not appended to the final list
-			if !foundMasterNode {
-				filteredNodes = append(filteredNodes, n)
-				foundMasterNode = true
-			} else {
-				// removing additional master nodes from the list
+			// removing additional master nodes from the list
+			if !keptMasterNodes[n.Name] {
 				continue
 			}
-		} else {
-			// append all non-master nodes
-			filteredNodes = append(filteredNodes, n)
 		}
+		// append all non-master nodes and the kept master nodes
+		filteredNodes = append(filteredNodes, n)
 	}
 	return filteredNodes
 }
+
+// loggerKeyVals converts the given fields into key value pairs accepted by micrologger.
+// Keys are sorted to keep the order of the fields stable across log lines.
+func loggerKeyVals(fields map[string]string) []interface{} {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	keyVals := make([]interface{}, 0, len(fields)*2)
+	for _, k := range keys {
+		keyVals = append(keyVals, k, fields[k])
+	}
+	return keyVals
+}
+
+// setAnnotation sets the annotation on the node and initializes the annotations if needed.
+func setAnnotation(n *corev1.Node, key string, value string) {
+	if n.Annotations == nil {
+		n.Annotations = map[string]string{}
+	}
+	n.Annotations[key] = value
+}
@@ -1,8 +1,14 @@
 package detector
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,280 +16,1168 @@ import (
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/detector/testutil"
 )
 
-func Test_removeMultipleMasterNodes(t *testing.T) {
+func Test_NewDetector_tickAnnotationKey(t *testing.T) {
+	testCases := []struct {
+		name                      string
+		tickAnnotationKey         string
+		expectedTickAnnotationKey string
+		errorMatcher              func(error) bool
+	}{
+		{
+			name:                      "test 0 - default annotation key",
+			tickAnnotationKey:         "",
+			expectedTickAnnotationKey: annotationNodeNotReadyTick,
+		},
+		{
+			name:                      "test 1 - prefixed annotation key",
+			tickAnnotationKey:         "example.com/storage-not-ready-tick",
+			expectedTickAnnotationKey: "example.com/storage-not-ready-tick",
+		},
+		{
+			name:                      "test 2 - annotation key without prefix",
+			tickAnnotationKey:         "storage-not-ready-tick",
+			expectedTickAnnotationKey: "storage-not-ready-tick",
+		},
+		{
+			name:              "test 3 - invalid annotation key",
+			tickAnnotationKey: "example.com/storage not ready",
+			errorMatcher:      IsInvalidConfig,
+		},
+		{
+			name:              "test 4 - invalid annotation key prefix",
+			tickAnnotationKey: "example_com/storage-not-ready-tick",
+			errorMatcher:      IsInvalidConfig,
+		},
+		{
+			name:              "test 5 - annotation key prefix with uppercase letters",
+			tickAnnotationKey: "Example.com/storage-not-ready-tick",
+			errorMatcher:      IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:             &FakeClock{Time: testNow},
+				Logger:            logger,
+				K8sClient:         fake.NewClientBuilder().Build(),
+				TickAnnotationKey: tc.tickAnnotationKey,
+			})
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if d.tickAnnotationKey != tc.expectedTickAnnotationKey {
+				t.Fatalf("Expected tick annotation key '%s' but got '%s'.\n", tc.expectedTickAnnotationKey, d.tickAnnotationKey)
+			}
+		})
+	}
+}
+
+// testNow is the time all test clocks are frozen at.
+var testNow = time.Date(2023, 11, 9, 12, 0, 0, 0, time.UTC)
+
+// testHealthCheck returns the default node health check with a clock frozen at testNow.
+func testHealthCheck() nodeHealthCheck {
+	return newNodeHealthCheck(&FakeClock{Time: testNow}, true)
+}
+
+// testNode returns a node builder with conditions relative to testNow.
+func testNode(name string) *testutil.NodeBuilder {
+	return testutil.NewNode(name).WithReferenceTime(testNow)
+}
+
+func Test_DetectBadNodes_loggerFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := micrologger.New(micrologger.Config{IOWriter: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "worker1",
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{
+					Type:              corev1.NodeReady,
+					Status:            corev1.ConditionFalse,
+					LastHeartbeatTime: metav1.NewTime(testNow.Add(-time.Minute * 10)),
+				},
+			},
+		},
+	}
+
+	d, err := NewDetector(Config{
+		Clock:     &FakeClock{Time: testNow},
+		Logger:    logger,
+		K8sClient: fake.NewClientBuilder().WithObjects(node).Build(),
+		LoggerFields: map[string]string{
+			"cluster": "abc12",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var runIDs []string
+	for i := 0; i < 2; i++ {
+		buf.Reset()
+
+		_, err = d.DetectBadNodes(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) == 0 || lines[0] == "" {
+			t.Fatalf("Expected log lines for run %d but got none.\n", i)
+		}
+
+		var runID string
+		for _, line := range lines {
+			var fields map[string]interface{}
+			err = json.Unmarshal([]byte(line), &fields)
+			if err != nil {
+				t.Fatalf("Expected JSON log line but got '%s'.\n", line)
+			}
+
+			if fields["cluster"] != "abc12" {
+				t.Fatalf("Expected field cluster 'abc12' but got '%v' in line '%s'.\n", fields["cluster"], line)
+			}
+
+			id, _ := fields["run"].(string)
+			if id == "" {
+				t.Fatalf("Expected field run to be set in line '%s'.\n", line)
+			}
+			if runID == "" {
+				runID = id
+			}
+			if id != runID {
+				t.Fatalf("Expected run id '%s' but got '%s'.\n", runID, id)
+			}
+		}
+		runIDs = append(runIDs, runID)
+	}
+
+	if runIDs[0] == runIDs[1] {
+		t.Fatalf("Expected different run ids for different runs but got '%s' twice.\n", runIDs[0])
+	}
+}
+
+func Test_removeMultipleMasterNodes(t *testing.T) {
+	worker1 := testNode("worker1").WithRole(labelNodeRoleWorker).Build()
+	worker2 := testNode("worker2").WithRole(labelNodeRoleWorker).Build()
+	worker3 := testNode("worker3").WithRole(labelNodeRoleWorker).Build()
+	worker4 := testNode("worker4").WithRole(labelNodeRoleWorker).Build()
+	master1 := testNode("master1").WithRole(labelNodeRoleMaster).Build()
+	master2 := testNode("master2").WithRole(labelNodeRoleMaster).Build()
+	master3 := testNode("master3").WithRole(labelNodeRoleMaster).Build()
+	noRole := testNode("node1").Build()
+
+	testCases := []struct {
+		name          string
+		nodes         []corev1.Node
+		expectedNodes []corev1.Node
+	}{
+		{
+			name:          "test 0 - 1 worker node",
+			nodes:         []corev1.Node{worker1},
+			expectedNodes: []corev1.Node{worker1},
+		},
+		{
+			name:          "test 1 - 1 worker node, 1 master node",
+			nodes:         []corev1.Node{worker1, master1},
+			expectedNodes: []corev1.Node{worker1, master1},
+		},
+		{
+			name:          "test 2 - 1 worker node, 2 master nodes",
+			nodes:         []corev1.Node{worker1, master1, master2},
+			expectedNodes: []corev1.Node{worker1, master1},
+		},
+		{
+			name:          "test 3 - 1 worker node, 3 master nodes",
+			nodes:         []corev1.Node{worker1, master1, master2, master3},
+			expectedNodes: []corev1.Node{worker1, master1},
+		},
+		{
+			name:          "test 4 - 4 worker nodes, 3 master nodes",
+			nodes:         []corev1.Node{worker1, master1, worker2, master2, worker3, master3, worker4},
+			expectedNodes: []corev1.Node{worker1, master1, worker2, worker3, worker4},
+		},
+		{
+			name:          "test 5 - no nodes",
+			nodes:         nil,
+			expectedNodes: nil,
+		},
+		{
+			name:          "test 6 - 3 master nodes only",
+			nodes:         []corev1.Node{master1, master2, master3},
+			expectedNodes: []corev1.Node{master1},
+		},
+		{
+			name:          "test 7 - master node with smallest name is kept",
+			nodes:         []corev1.Node{master3, worker1, master1},
+			expectedNodes: []corev1.Node{worker1, master1},
+		},
+		{
+			name:          "test 8 - nodes without role label are kept",
+			nodes:         []corev1.Node{noRole, master1, master2},
+			expectedNodes: []corev1.Node{noRole, master1},
+		},
+		{
+			name:          "test 9 - master nodes in reverse order",
+			nodes:         []corev1.Node{master3, master2, master1, worker1},
+			expectedNodes: []corev1.Node{master1, worker1},
+		},
+		{
+			name:          "test 10 - master nodes in mixed order",
+			nodes:         []corev1.Node{worker2, master2, worker1, master3, master1},
+			expectedNodes: []corev1.Node{worker2, worker1, master1},
+		},
+		{
+			name:          "test 11 - master node with smallest name missing",
+			nodes:         []corev1.Node{master3, worker1, master2},
+			expectedNodes: []corev1.Node{worker1, master2},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			filteredNodes := removeMultipleMasterNodes(tc.nodes)
+
+			if len(filteredNodes) != len(tc.expectedNodes) {
+				t.Fatalf("Expected '%d' nodes but got '%d'.\n", len(tc.expectedNodes), len(filteredNodes))
+			}
+
+			if !cmp.Equal(filteredNodes, tc.expectedNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodes, filteredNodes))
+
+			}
+		})
+	}
+}
+
+func Test_removeMultipleMasterNodes_stableChoice(t *testing.T) {
+	masters := []corev1.Node{
+		testNode("master-c").WithRole(labelNodeRoleMaster).Build(),
+		testNode("master-a").WithRole(labelNodeRoleMaster).Build(),
+		testNode("master-b").WithRole(labelNodeRoleMaster).Build(),
+	}
+
+	// feed all permutations of the master nodes
+	permutations := [][]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+
+	for i, p := range permutations {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var nodes []corev1.Node
+			for _, j := range p {
+				nodes = append(nodes, masters[j])
+			}
+
+			filteredNodes := removeMultipleMasterNodes(nodes)
+
+			if len(filteredNodes) != 1 {
+				t.Fatalf("Expected '1' nodes but got '%d'.\n", len(filteredNodes))
+			}
+			if filteredNodes[0].Name != "master-a" {
+				t.Fatalf("Expected master node 'master-a' but got '%s'.\n", filteredNodes[0].Name)
+			}
+		})
+	}
+}
+
+func Test_maximumNodeTermination(t *testing.T) {
+	testCases := []struct {
+		name                         string
+		nodeCount                    int
+		maxNodeTerminationPercentage float64
+		expectedNodeCount            int
+	}{
+		{
+			name:                         "test 0 - basic test",
+			nodeCount:                    10,
+			maxNodeTerminationPercentage: 0.5,
+			expectedNodeCount:            5,
+		},
+		{
+			name:                         "test 1 - test minimal limit",
+			nodeCount:                    10,
+			maxNodeTerminationPercentage: 0.01,
+			expectedNodeCount:            1,
+		},
+		{
+			name:                         "test 2 - test minimal limit",
+			nodeCount:                    3,
+			maxNodeTerminationPercentage: 0.10,
+			expectedNodeCount:            1,
+		},
+		{
+			name:                         "test 3 - test rounding",
+			nodeCount:                    10,
+			maxNodeTerminationPercentage: 0.23,
+			expectedNodeCount:            2,
+		},
+		{
+			name:                         "test 4 - lot of nodes",
+			nodeCount:                    1000,
+			maxNodeTerminationPercentage: 0.25,
+			expectedNodeCount:            250,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			maxNodeCount := maximumNodeTermination(tc.nodeCount, tc.maxNodeTerminationPercentage)
+
+			if maxNodeCount != tc.expectedNodeCount {
+				t.Fatalf("Expected '%d' nodes but got '%d'.\n", tc.expectedNodeCount, maxNodeCount)
+			}
+		})
+	}
+}
+
+func Test_MaxTerminations(t *testing.T) {
+	testCases := []struct {
+		name                         string
+		nodeCount                    int
+		maxNodeTerminationPercentage float64
+		maxNodeTerminationsPerRun    int
+		expectedMaxTerminations      int
+	}{
+		{
+			name:                         "test 0 - empty cluster",
+			nodeCount:                    0,
+			maxNodeTerminationPercentage: 0.1,
+			expectedMaxTerminations:      1,
+		},
+		{
+			name:                         "test 1 - small cluster",
+			nodeCount:                    3,
+			maxNodeTerminationPercentage: 0.1,
+			expectedMaxTerminations:      1,
+		},
+		{
+			name:                         "test 2 - medium cluster",
+			nodeCount:                    20,
+			maxNodeTerminationPercentage: 0.25,
+			expectedMaxTerminations:      5,
+		},
+		{
+			name:                         "test 3 - big cluster",
+			nodeCount:                    100,
+			maxNodeTerminationPercentage: 0.1,
+			expectedMaxTerminations:      10,
+		},
+		{
+			name:                         "test 4 - big cluster with absolute limit",
+			nodeCount:                    100,
+			maxNodeTerminationPercentage: 0.1,
+			maxNodeTerminationsPerRun:    3,
+			expectedMaxTerminations:      3,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			var objects []client.Object
+			for j := 0; j < tc.nodeCount; j++ {
+				node := testNode(fmt.Sprintf("worker%d", j)).Build()
+				objects = append(objects, &node)
+			}
+
+			d, err := NewDetector(Config{
+				Logger:                       logger,
+				K8sClient:                    fake.NewClientBuilder().WithObjects(objects...).Build(),
+				MaxNodeTerminationPercentage: tc.maxNodeTerminationPercentage,
+				MaxNodeTerminationsPerRun:    tc.maxNodeTerminationsPerRun,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			maxTerminations, err := d.MaxTerminations(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if maxTerminations != tc.expectedMaxTerminations {
+				t.Fatalf("Expected '%d' nodes but got '%d'.\n", tc.expectedMaxTerminations, maxTerminations)
+			}
+		})
+	}
+}
+
+func Test_isNodeUnhealthy(t *testing.T) {
+	const diskFullCondition corev1.NodeConditionType = "DiskFullKubelet"
+
+	testCases := []struct {
+		name                 string
+		node                 corev1.Node
+		expectedNodeNotReady bool
+	}{
+		{
+			name: "test 0 - node ready",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			expectedNodeNotReady: false,
+		},
+		{
+			name: "test 1 - node not ready",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			expectedNodeNotReady: true,
+		},
+		{
+			name: "test 2 - not ready but only for short time",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Second*10).
+				Build(),
+			expectedNodeNotReady: false,
+		},
+		{
+			name: "test 3 - ready but disk full",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				WithCondition(diskFullCondition, corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			expectedNodeNotReady: true,
+		},
+		{
+			name: "test 4 - ready but disk full for a short time",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				WithCondition(diskFullCondition, corev1.ConditionTrue, time.Second*10).
+				Build(),
+			expectedNodeNotReady: false,
+		},
+		{
+			name: "test 5 - node ready status unknown",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionUnknown, time.Minute*10).
+				Build(),
+			expectedNodeNotReady: true,
+		},
+		{
+			name: "test 6 - not ready exactly for the not ready duration",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, nodeNotReadyDuration).
+				Build(),
+			expectedNodeNotReady: true,
+		},
+		{
+			name: "test 7 - ready with disk pressure",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			expectedNodeNotReady: true,
+		},
+		{
+			name: "test 8 - ready without disk pressure",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			expectedNodeNotReady: false,
+		},
+		{
+			name: "test 9 - ready with memory pressure is ignored",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				WithCondition(corev1.NodeMemoryPressure, corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			expectedNodeNotReady: false,
+		},
+		{
+			name: "test 10 - cordoned and tainted but ready",
+			node: testNode("").
+				WithTaint(corev1.TaintNodeUnschedulable, "", corev1.TaintEffectNoSchedule).
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			expectedNodeNotReady: false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			result := testHealthCheck().isNodeUnhealthy(context.Background(), logger, tc.node)
+			if result != tc.expectedNodeNotReady {
+				t.Fatalf("Expected '%t' but got '%t'.\n", tc.expectedNodeNotReady, result)
+			}
+		})
+	}
+}
+
+func Test_nodeNotReadyTickCount(t *testing.T) {
+	testCases := []struct {
+		name              string
+		node              corev1.Node
+		tickAnnotationKey string
+		disableRecovery   bool
+		expectedTickCount int
+		shouldUpdate      bool
+	}{
+		{
+			name: "test 0 - tick counter not changed - empty annotation",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			expectedTickCount: 0,
+			shouldUpdate:      false,
+		},
+		{
+			name: "test 1 - tick counter not changed",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "0").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			expectedTickCount: 0,
+			shouldUpdate:      false,
+		},
+		{
+			name: "test 2 - tick counter increase - no annotation",
+			node: testNode("").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			expectedTickCount: 1,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 3 - tick counter increase",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			expectedTickCount: 6,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 4 - tick counter decrease",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			expectedTickCount: 4,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 5 - invalid tick counter - increase",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "asdefg").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			expectedTickCount: 1,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 6 - invalid tick counter - reset to zero",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "asdefg").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			expectedTickCount: 0,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 7 - tick counter increase - custom annotation key",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithAnnotation("example.com/storage-not-ready-tick", "2").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			tickAnnotationKey: "example.com/storage-not-ready-tick",
+			expectedTickCount: 3,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 8 - tick counter not decreased - recovery disabled",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			disableRecovery:   true,
+			expectedTickCount: 5,
+			shouldUpdate:      false,
+		},
+		{
+			name: "test 9 - tick counter increase - recovery disabled",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			disableRecovery:   true,
+			expectedTickCount: 6,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 10 - tick counter decrease - not ready for a short time",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "3").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Second*10).
+				Build(),
+			expectedTickCount: 2,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 11 - tick counter increase - disk pressure",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			expectedTickCount: 2,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 12 - tick counter not changed - custom annotation key missing",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			tickAnnotationKey: "example.com/storage-not-ready-tick",
+			expectedTickCount: 0,
+			shouldUpdate:      false,
+		},
+		{
+			name: "test 13 - negative tick counter - not decreased",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, "-1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			expectedTickCount: -1,
+			shouldUpdate:      false,
+		},
+		{
+			name: "test 14 - too long numeric tick counter - increase",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, strings.Repeat("1", 1000)).
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			expectedTickCount: 1,
+			shouldUpdate:      true,
+		},
+		{
+			name: "test 15 - too long invalid tick counter - reset",
+			node: testNode("").
+				WithAnnotation(annotationNodeNotReadyTick, strings.Repeat("a", 1000)).
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			expectedTickCount: 0,
+			shouldUpdate:      true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			tickAnnotationKey := tc.tickAnnotationKey
+			if tickAnnotationKey == "" {
+				tickAnnotationKey = annotationNodeNotReadyTick
+			}
+
+			tickCounter, updated := nodeNotReadyTickCount(context.Background(), logger, testHealthCheck(), tc.node, tickAnnotationKey, tc.disableRecovery)
+			if tickCounter != tc.expectedTickCount {
+				t.Fatalf("Expected tick counter '%d' but got '%d'.\n", tc.expectedTickCount, tickCounter)
+			}
+
+			if updated != tc.shouldUpdate {
+				t.Fatalf("Expected tick counter updated value to be '%t' but got '%t'.\n", tc.shouldUpdate, updated)
+			}
+
+		})
+	}
+}
+
+func Test_effectiveThreshold(t *testing.T) {
 	testCases := []struct {
-		name          string
-		nodes         []corev1.Node
-		expectedNodes []corev1.Node
+		name                  string
+		notReadyTickThreshold int
+		dynamicThreshold      bool
+		thresholdFormula      func(nodeCount int) int
+		nodeCount             int
+		expectedThreshold     int
 	}{
 		{
-			name: "test 0 - 1 worker node",
-			nodes: []corev1.Node{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
+			name:                  "test 0 - dynamic threshold disabled",
+			notReadyTickThreshold: 6,
+			nodeCount:             3,
+			expectedThreshold:     6,
+		},
+		{
+			name:                  "test 1 - small cluster",
+			notReadyTickThreshold: 6,
+			dynamicThreshold:      true,
+			nodeCount:             3,
+			expectedThreshold:     2,
+		},
+		{
+			name:                  "test 2 - medium cluster",
+			notReadyTickThreshold: 6,
+			dynamicThreshold:      true,
+			nodeCount:             40,
+			expectedThreshold:     4,
+		},
+		{
+			name:                  "test 3 - big cluster",
+			notReadyTickThreshold: 6,
+			dynamicThreshold:      true,
+			nodeCount:             1000,
+			expectedThreshold:     6,
+		},
+		{
+			name:                  "test 4 - empty cluster",
+			notReadyTickThreshold: 6,
+			dynamicThreshold:      true,
+			nodeCount:             0,
+			expectedThreshold:     2,
+		},
+		{
+			name:                  "test 5 - custom formula",
+			notReadyTickThreshold: 6,
+			dynamicThreshold:      true,
+			thresholdFormula: func(nodeCount int) int {
+				return nodeCount / 2
 			},
-			expectedNodes: []corev1.Node{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
+			nodeCount:         20,
+			expectedThreshold: 10,
+		},
+		{
+			name:                  "test 6 - custom formula below minimum",
+			notReadyTickThreshold: 6,
+			dynamicThreshold:      true,
+			thresholdFormula: func(nodeCount int) int {
+				return 0
 			},
+			nodeCount:         20,
+			expectedThreshold: 1,
 		},
 		{
-			name: "test 1 - 1 worker node, 1 master node",
-			nodes: []corev1.Node{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
+			name:                  "test 7 - custom formula ignored when dynamic threshold disabled",
+			notReadyTickThreshold: 6,
+			thresholdFormula: func(nodeCount int) int {
+				return 1
 			},
-			expectedNodes: []corev1.Node{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
+			nodeCount:         20,
+			expectedThreshold: 6,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:                 &FakeClock{Time: testNow},
+				Logger:                logger,
+				K8sClient:             fake.NewClientBuilder().Build(),
+				NotReadyTickThreshold: tc.notReadyTickThreshold,
+				DynamicThreshold:      tc.dynamicThreshold,
+				ThresholdFormula:      tc.thresholdFormula,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			threshold := d.effectiveThreshold(tc.nodeCount)
+			if threshold != tc.expectedThreshold {
+				t.Fatalf("Expected threshold '%d' but got '%d'.\n", tc.expectedThreshold, threshold)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_nodeOS(t *testing.T) {
+	newNode := func(name string, os string, arch string) *corev1.Node {
+		node := testNode(name).
+			WithLabel(corev1.LabelOSStable, os).
+			WithLabel(corev1.LabelArchStable, arch).
+			WithAnnotation(annotationNodeNotReadyTick, "5").
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+			Build()
+		return &node
+	}
+
+	testCases := []struct {
+		name              string
+		nodeOS            string
+		nodeArch          string
+		expectedBadNodes  []string
+		expectedTickCount map[string]string
+	}{
+		{
+			name:             "test 0 - all nodes",
+			expectedBadNodes: []string{"linux1", "linux2", "windows1"},
+			expectedTickCount: map[string]string{
+				"linux1":   "6",
+				"linux2":   "6",
+				"windows1": "6",
 			},
 		},
 		{
-			name: "test 2 - 1 worker node, 2 master nodes",
-			nodes: []corev1.Node{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master2",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
+			name:             "test 1 - linux nodes only",
+			nodeOS:           "linux",
+			expectedBadNodes: []string{"linux1", "linux2"},
+			expectedTickCount: map[string]string{
+				"linux1":   "6",
+				"linux2":   "6",
+				"windows1": "5",
 			},
-			expectedNodes: []corev1.Node{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
+		},
+		{
+			name:             "test 2 - windows nodes only",
+			nodeOS:           "windows",
+			expectedBadNodes: []string{"windows1"},
+			expectedTickCount: map[string]string{
+				"linux1":   "5",
+				"linux2":   "5",
+				"windows1": "6",
 			},
 		},
 		{
-			name: "test 3 - 1 worker node, 3 master nodes",
-			nodes: []corev1.Node{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master2",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master3",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
+			name:             "test 3 - linux arm64 nodes only",
+			nodeOS:           "linux",
+			nodeArch:         "arm64",
+			expectedBadNodes: []string{"linux2"},
+			expectedTickCount: map[string]string{
+				"linux1":   "5",
+				"linux2":   "6",
+				"windows1": "5",
 			},
-			expectedNodes: []corev1.Node{
-				{
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			k8sClient := fake.NewClientBuilder().WithObjects(
+				newNode("linux1", "linux", "amd64"),
+				newNode("linux2", "linux", "arm64"),
+				newNode("windows1", "windows", "amd64"),
+			).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+				NodeOS:                       tc.nodeOS,
+				NodeArch:                     tc.nodeArch,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, n := range badNodes {
+				names = append(names, n.Name)
+			}
+			// the fake client does not guarantee any order of listed nodes
+			sort.Strings(names)
+			if !cmp.Equal(names, tc.expectedBadNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedBadNodes, names))
+			}
+
+			var nodeList corev1.NodeList
+			err = k8sClient.List(context.Background(), &nodeList)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, n := range nodeList.Items {
+				if n.Annotations[annotationNodeNotReadyTick] != tc.expectedTickCount[n.Name] {
+					t.Fatalf("Expected tick count '%s' for node %s but got '%s'.\n", tc.expectedTickCount[n.Name], n.Name, n.Annotations[annotationNodeNotReadyTick])
+				}
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_disableRecovery(t *testing.T) {
+	testCases := []struct {
+		name              string
+		disableRecovery   bool
+		readySequence     []bool
+		expectedTickCount string
+	}{
+		{
+			name:              "test 0 - flaky node recovers with recovery enabled",
+			readySequence:     []bool{false, false, true, true, false},
+			expectedTickCount: "1",
+		},
+		{
+			name:              "test 1 - flaky node holds tick count with recovery disabled",
+			disableRecovery:   true,
+			readySequence:     []bool{false, false, true, true, false},
+			expectedTickCount: "3",
+		},
+		{
+			name:              "test 2 - healthy node with recovery disabled",
+			disableRecovery:   true,
+			readySequence:     []bool{true, true, true},
+			expectedTickCount: "",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			k8sClient := fake.NewClientBuilder().WithObjects(
+				&corev1.Node{
 					ObjectMeta: metav1.ObjectMeta{
 						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
 					},
 				},
+			).Build()
+
+			d, err := NewDetector(Config{
+				Clock:           &FakeClock{Time: testNow},
+				Logger:          logger,
+				K8sClient:       k8sClient,
+				DisableRecovery: tc.disableRecovery,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var node corev1.Node
+			for _, ready := range tc.readySequence {
+				err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &node)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				condition := corev1.NodeCondition{
+					Type:              corev1.NodeReady,
+					Status:            corev1.ConditionTrue,
+					LastHeartbeatTime: metav1.NewTime(testNow),
+				}
+				if !ready {
+					condition.Status = corev1.ConditionFalse
+					condition.LastHeartbeatTime = metav1.NewTime(testNow.Add(-time.Minute * 10))
+				}
+				node.Status.Conditions = []corev1.NodeCondition{condition}
+
+				err = k8sClient.Update(context.Background(), &node)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				_, err = d.DetectBadNodes(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &node)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if node.Annotations[annotationNodeNotReadyTick] != tc.expectedTickCount {
+				t.Fatalf("Expected tick count '%s' but got '%s'.\n", tc.expectedTickCount, node.Annotations[annotationNodeNotReadyTick])
+			}
+		})
+	}
+}
+
+// cancellingClient wraps a client and cancels the context after the given number of Update calls.
+type cancellingClient struct {
+	client.Client
+
+	cancel      context.CancelFunc
+	cancelAfter int
+	updateCalls int
+}
+
+func (c *cancellingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	err := c.Client.Update(ctx, obj, opts...)
+	c.updateCalls++
+	if c.updateCalls == c.cancelAfter {
+		c.cancel()
+	}
+	return err
+}
+
+func Test_DetectBadNodes_contextCancelled(t *testing.T) {
+	testCases := []struct {
+		name                string
+		cancelAfter         int
+		expectedBadNodes    int
+		expectedUpdateCalls int
+		expectCancelled     bool
+	}{
+		{
+			name:                "test 0 - context not cancelled",
+			cancelAfter:         0,
+			expectedBadNodes:    10,
+			expectedUpdateCalls: 10,
+			expectCancelled:     false,
+		},
+		{
+			name:                "test 1 - context cancelled after first node",
+			cancelAfter:         1,
+			expectedBadNodes:    1,
+			expectedUpdateCalls: 1,
+			expectCancelled:     true,
+		},
+		{
+			name:                "test 2 - context cancelled mid loop",
+			cancelAfter:         4,
+			expectedBadNodes:    4,
+			expectedUpdateCalls: 4,
+			expectCancelled:     true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			var objects []client.Object
+			for j := 0; j < 10; j++ {
+				node := testNode(fmt.Sprintf("worker%d", j)).
+					WithRole(labelNodeRoleWorker).
+					WithAnnotation(annotationNodeNotReadyTick, "5").
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build()
+				objects = append(objects, &node)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			k8sClient := &cancellingClient{
+				Client:      fake.NewClientBuilder().WithObjects(objects...).Build(),
+				cancel:      cancel,
+				cancelAfter: tc.cancelAfter,
+			}
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(ctx)
+			if tc.expectCancelled && !errors.Is(err, context.Canceled) {
+				t.Fatalf("error == %#v, want context.Canceled", err)
+			}
+			if !tc.expectCancelled && err != nil {
+				t.Fatal(err)
+			}
+
+			if len(badNodes) != tc.expectedBadNodes {
+				t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", tc.expectedBadNodes, len(badNodes))
+			}
+			if k8sClient.updateCalls != tc.expectedUpdateCalls {
+				t.Fatalf("Expected '%d' node updates but got '%d'.\n", tc.expectedUpdateCalls, k8sClient.updateCalls)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_shouldTerminate(t *testing.T) {
+	newNode := func(name string, pool string, tick string) client.Object {
+		node := testNode(name).
+			WithLabel("pool", pool).
+			WithAnnotation(annotationNodeNotReadyTick, tick).
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+			Build()
+		return &node
+	}
+
+	testCases := []struct {
+		name             string
+		shouldTerminate  func(node corev1.Node, tick int) bool
+		expectedBadNodes []string
+	}{
+		{
+			name:             "test 0 - default threshold comparison",
+			shouldTerminate:  nil,
+			expectedBadNodes: []string{"batch2", "default2"},
+		},
+		{
+			name: "test 1 - batch pool ignores the threshold",
+			shouldTerminate: func(node corev1.Node, tick int) bool {
+				if node.Labels["pool"] == "batch" {
+					return tick > 0
+				}
+				return tick >= defaultNotReadyTickThreshold
 			},
+			expectedBadNodes: []string{"batch1", "batch2", "default2"},
 		},
 		{
-			name: "test 4 - 4 worker nodes, 3 master nodes",
-			nodes: []corev1.Node{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker2",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master2",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker3",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master3",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker4",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
+			name: "test 2 - only default pool is terminated",
+			shouldTerminate: func(node corev1.Node, tick int) bool {
+				return node.Labels["pool"] == "default" && tick >= defaultNotReadyTickThreshold
 			},
-			expectedNodes: []corev1.Node{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "master1",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleMaster,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker2",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker3",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "worker4",
-						Labels: map[string]string{
-							labelNodeRole: labelNodeRoleWorker,
-						},
-					},
-				},
+			expectedBadNodes: []string{"default2"},
+		},
+		{
+			name: "test 3 - never terminate",
+			shouldTerminate: func(node corev1.Node, tick int) bool {
+				return false
 			},
+			expectedBadNodes: nil,
 		},
 	}
 
@@ -291,56 +1185,86 @@ func Test_removeMultipleMasterNodes(t *testing.T) {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			t.Log(tc.name)
 
-			filteredNodes := removeMultipleMasterNodes(tc.nodes)
+			logger, _ := micrologger.New(micrologger.Config{})
 
-			if len(filteredNodes) != len(tc.expectedNodes) {
-				t.Fatalf("Expected '%d' nodes but got '%d'.\n", len(tc.expectedNodes), len(filteredNodes))
+			k8sClient := fake.NewClientBuilder().WithObjects(
+				newNode("batch1", "batch", "0"),
+				newNode("batch2", "batch", "5"),
+				newNode("default1", "default", "0"),
+				newNode("default2", "default", "5"),
+			).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+				ShouldTerminate:              tc.shouldTerminate,
+			})
+			if err != nil {
+				t.Fatal(err)
 			}
 
-			if !cmp.Equal(filteredNodes, tc.expectedNodes) {
-				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodes, filteredNodes))
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, n := range badNodes {
+				names = append(names, n.Name)
+			}
+			sort.Strings(names)
 
+			if !cmp.Equal(names, tc.expectedBadNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedBadNodes, names))
 			}
 		})
 	}
 }
 
-func Test_maximumNodeTermination(t *testing.T) {
+func Test_DetectBadNodes_maxNodeTerminationsPerRun(t *testing.T) {
 	testCases := []struct {
 		name                         string
-		nodeCount                    int
+		badNodes                     int
 		maxNodeTerminationPercentage float64
-		expectedNodeCount            int
+		maxNodeTerminationsPerRun    int
+		expectedBadNodes             int
 	}{
 		{
-			name:                         "test 0 - basic test",
-			nodeCount:                    10,
-			maxNodeTerminationPercentage: 0.5,
-			expectedNodeCount:            5,
+			name:                         "test 0 - disabled",
+			badNodes:                     5,
+			maxNodeTerminationPercentage: 1,
+			maxNodeTerminationsPerRun:    0,
+			expectedBadNodes:             5,
 		},
 		{
-			name:                         "test 1 - test minimal limit",
-			nodeCount:                    10,
-			maxNodeTerminationPercentage: 0.01,
-			expectedNodeCount:            1,
+			name:                         "test 1 - one node per run",
+			badNodes:                     5,
+			maxNodeTerminationPercentage: 1,
+			maxNodeTerminationsPerRun:    1,
+			expectedBadNodes:             1,
 		},
 		{
-			name:                         "test 2 - test minimal limit",
-			nodeCount:                    3,
-			maxNodeTerminationPercentage: 0.10,
-			expectedNodeCount:            1,
+			name:                         "test 2 - three nodes per run",
+			badNodes:                     5,
+			maxNodeTerminationPercentage: 1,
+			maxNodeTerminationsPerRun:    3,
+			expectedBadNodes:             3,
 		},
 		{
-			name:                         "test 3 - test rounding",
-			nodeCount:                    10,
-			maxNodeTerminationPercentage: 0.23,
-			expectedNodeCount:            2,
+			name:                         "test 3 - percentage limit is lower",
+			badNodes:                     10,
+			maxNodeTerminationPercentage: 0.2,
+			maxNodeTerminationsPerRun:    3,
+			expectedBadNodes:             2,
 		},
 		{
-			name:                         "test 4 - lot of nodes",
-			nodeCount:                    1000,
-			maxNodeTerminationPercentage: 0.25,
-			expectedNodeCount:            250,
+			name:                         "test 4 - fewer bad nodes than the limit",
+			badNodes:                     2,
+			maxNodeTerminationPercentage: 1,
+			maxNodeTerminationsPerRun:    3,
+			expectedBadNodes:             2,
 		},
 	}
 
@@ -348,107 +1272,107 @@ func Test_maximumNodeTermination(t *testing.T) {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			t.Log(tc.name)
 
-			maxNodeCount := maximumNodeTermination(tc.nodeCount, tc.maxNodeTerminationPercentage)
+			logger, _ := micrologger.New(micrologger.Config{})
 
-			if maxNodeCount != tc.expectedNodeCount {
-				t.Fatalf("Expected '%d' nodes but got '%d'.\n", tc.expectedNodeCount, maxNodeCount)
+			var objects []client.Object
+			for j := 0; j < tc.badNodes; j++ {
+				node := testNode(fmt.Sprintf("worker%d", j)).
+					WithAnnotation(annotationNodeNotReadyTick, "5").
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build()
+				objects = append(objects, &node)
+			}
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    fake.NewClientBuilder().WithObjects(objects...).Build(),
+				MaxNodeTerminationPercentage: tc.maxNodeTerminationPercentage,
+				MaxNodeTerminationsPerRun:    tc.maxNodeTerminationsPerRun,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(badNodes) != tc.expectedBadNodes {
+				t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", tc.expectedBadNodes, len(badNodes))
 			}
 		})
 	}
 }
 
-func Test_isNodeUnhealthy(t *testing.T) {
-	const diskFullCondition corev1.NodeConditionType = "DiskFullKubelet"
+func Test_DefaultConfig(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+	k8sClient := fake.NewClientBuilder().Build()
+
+	config := DefaultConfig()
+	config.Logger = logger
+	config.K8sClient = k8sClient
+
+	d, err := NewDetector(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the defaults must be the same as applied by NewDetector to an empty config
+	expected, err := NewDetector(Config{Logger: logger, K8sClient: k8sClient})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.maxNodeTerminationPercentage != expected.maxNodeTerminationPercentage {
+		t.Fatalf("Expected max node termination percentage '%f' but got '%f'.\n", expected.maxNodeTerminationPercentage, d.maxNodeTerminationPercentage)
+	}
+	if d.notReadyTickThreshold != expected.notReadyTickThreshold {
+		t.Fatalf("Expected tick threshold '%d' but got '%d'.\n", expected.notReadyTickThreshold, d.notReadyTickThreshold)
+	}
+	if d.pauseBetweenTermination != expected.pauseBetweenTermination {
+		t.Fatalf("Expected pause between termination '%s' but got '%s'.\n", expected.pauseBetweenTermination, d.pauseBetweenTermination)
+	}
+	if d.tickAnnotationKey != expected.tickAnnotationKey {
+		t.Fatalf("Expected tick annotation key '%s' but got '%s'.\n", expected.tickAnnotationKey, d.tickAnnotationKey)
+	}
+	if d.newNodeGracePeriod != expected.newNodeGracePeriod || d.establishedNodeAge != expected.establishedNodeAge {
+		t.Fatalf("Expected lifecycle durations '%s' and '%s' but got '%s' and '%s'.\n", expected.newNodeGracePeriod, expected.establishedNodeAge, d.newNodeGracePeriod, d.establishedNodeAge)
+	}
+	if d.terminationHistoryWindow != expected.terminationHistoryWindow {
+		t.Fatalf("Expected termination history window '%s' but got '%s'.\n", expected.terminationHistoryWindow, d.terminationHistoryWindow)
+	}
+}
 
+func Test_NewDetector_pauseBetweenTermination(t *testing.T) {
 	testCases := []struct {
-		name                 string
-		node                 corev1.Node
-		expectedNodeNotReady bool
+		name                            string
+		pauseBetweenTermination         time.Duration
+		expectedPauseBetweenTermination time.Duration
+		expectWarning                   bool
+		errorMatcher                    func(error) bool
 	}{
 		{
-			name: "test 0 - node ready",
-			node: corev1.Node{
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionTrue,
-							LastHeartbeatTime: metav1.Now(),
-						},
-					},
-				},
-			},
-			expectedNodeNotReady: false,
-		},
-		{
-			name: "test 1 - node not ready",
-			node: corev1.Node{
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionFalse,
-							LastHeartbeatTime: metav1.Time{Time: time.Now().Add(-time.Minute * 10)},
-						},
-					},
-				},
-			},
-			expectedNodeNotReady: true,
+			name:                            "test 0 - zero is defaulted",
+			pauseBetweenTermination:         0,
+			expectedPauseBetweenTermination: defaultPauseBetweenTermination,
 		},
 		{
-			name: "test 2 - not ready but only for short time",
-			node: corev1.Node{
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionFalse,
-							LastHeartbeatTime: metav1.Time{Time: time.Now().Add(-time.Second * 10)},
-						},
-					},
-				},
-			},
-			expectedNodeNotReady: false,
+			name:                            "test 1 - reasonable duration",
+			pauseBetweenTermination:         time.Minute * 30,
+			expectedPauseBetweenTermination: time.Minute * 30,
 		},
 		{
-			name: "test 3 - ready but disk full",
-			node: corev1.Node{
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionTrue,
-							LastHeartbeatTime: metav1.Time{Time: time.Now().Add(-time.Minute * 10)},
-						},
-						{
-							Type:              diskFullCondition,
-							Status:            corev1.ConditionTrue,
-							LastHeartbeatTime: metav1.Time{Time: time.Now().Add(-time.Minute * 10)},
-						},
-					},
-				},
-			},
-			expectedNodeNotReady: true,
+			name:                    "test 2 - negative duration",
+			pauseBetweenTermination: -time.Minute,
+			errorMatcher:            IsInvalidConfig,
 		},
 		{
-			name: "test 4 - ready but disk full for a short time",
-			node: corev1.Node{
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionTrue,
-							LastHeartbeatTime: metav1.Time{Time: time.Now().Add(-time.Minute * 10)},
-						},
-						{
-							Type:              diskFullCondition,
-							Status:            corev1.ConditionTrue,
-							LastHeartbeatTime: metav1.Time{Time: time.Now().Add(-time.Second * 10)},
-						},
-					},
-				},
-			},
-			expectedNodeNotReady: false,
+			name:                            "test 3 - huge duration",
+			pauseBetweenTermination:         time.Hour * 24 * 365,
+			expectedPauseBetweenTermination: time.Hour * 24 * 365,
+			expectWarning:                   true,
 		},
 	}
 
@@ -456,163 +1380,189 @@ func Test_isNodeUnhealthy(t *testing.T) {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			t.Log(tc.name)
 
-			logger, _ := micrologger.New(micrologger.Config{})
+			var buf bytes.Buffer
+			logger, err := micrologger.New(micrologger.Config{IOWriter: &buf})
+			if err != nil {
+				t.Fatal(err)
+			}
 
-			result := isNodeUnhealthy(context.Background(), logger, tc.node)
-			if result != tc.expectedNodeNotReady {
-				t.Fatalf("Expected '%t' but got '%t'.\n", tc.expectedNodeNotReady, result)
+			d, err := NewDetector(Config{
+				Logger:                  logger,
+				K8sClient:               fake.NewClientBuilder().Build(),
+				PauseBetweenTermination: tc.pauseBetweenTermination,
+			})
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if d.pauseBetweenTermination != tc.expectedPauseBetweenTermination {
+				t.Fatalf("Expected pause between termination '%s' but got '%s'.\n", tc.expectedPauseBetweenTermination, d.pauseBetweenTermination)
+			}
+
+			warned := strings.Contains(buf.String(), "PauseBetweenTermination")
+			if warned != tc.expectWarning {
+				t.Fatalf("Expected warning '%t' but got '%t'.\n", tc.expectWarning, warned)
 			}
 		})
 	}
 }
 
-func Test_nodeNotReadyTickCount(t *testing.T) {
+func Test_NewDetector_notReadyThresholdDuration(t *testing.T) {
 	testCases := []struct {
-		name              string
-		node              corev1.Node
-		expectedTickCount int
-		shouldUpdate      bool
+		name                      string
+		notReadyTickThreshold     int
+		notReadyThresholdDuration time.Duration
+		runInterval               time.Duration
+		expectedThreshold         int
+		errorMatcher              func(error) bool
 	}{
 		{
-			name: "test 0 - tick counter not changed - empty annotation",
-			node: corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{},
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionTrue,
-							LastHeartbeatTime: metav1.Now(),
-						},
-					},
-				},
-			},
-			expectedTickCount: 0,
-			shouldUpdate:      false,
+			name:              "test 0 - tick threshold without duration",
+			expectedThreshold: defaultNotReadyTickThreshold,
 		},
 		{
-			name: "test 1 - tick counter not changed",
-			node: corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{
-						annotationNodeNotReadyTick: "0",
-					},
-				},
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionTrue,
-							LastHeartbeatTime: metav1.Now(),
-						},
-					},
-				},
-			},
-			expectedTickCount: 0,
-			shouldUpdate:      false,
+			name:                      "test 1 - duration matching the interval",
+			notReadyThresholdDuration: time.Minute * 5,
+			runInterval:               time.Minute,
+			expectedThreshold:         5,
 		},
 		{
-			name: "test 2 - tick counter increase - no annotation",
-			node: corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{},
-				},
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionFalse,
-							LastHeartbeatTime: metav1.Time{Time: time.Now().Add(-time.Minute * 10)},
-						},
-					},
-				},
-			},
-			expectedTickCount: 1,
-			shouldUpdate:      true,
+			name:                      "test 2 - duration is rounded up",
+			notReadyThresholdDuration: time.Minute * 5,
+			runInterval:               time.Minute * 2,
+			expectedThreshold:         3,
 		},
 		{
-			name: "test 3 - tick counter increase",
-			node: corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{
-						annotationNodeNotReadyTick: "5",
-					},
-				},
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionFalse,
-							LastHeartbeatTime: metav1.Time{Time: time.Now().Add(-time.Minute * 10)},
-						},
-					},
-				},
-			},
-			expectedTickCount: 6,
-			shouldUpdate:      true,
+			name:                      "test 3 - duration shorter than the interval",
+			notReadyThresholdDuration: time.Minute,
+			runInterval:               time.Minute * 10,
+			expectedThreshold:         1,
 		},
 		{
-			name: "test 4 - tick counter decrease",
-			node: corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{
-						annotationNodeNotReadyTick: "5",
-					},
-				},
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionTrue,
-							LastHeartbeatTime: metav1.Now(),
-						},
-					},
-				},
-			},
-			expectedTickCount: 4,
-			shouldUpdate:      true,
+			name:                      "test 4 - duration takes precedence over the tick threshold",
+			notReadyTickThreshold:     10,
+			notReadyThresholdDuration: time.Minute * 10,
+			runInterval:               time.Minute * 5,
+			expectedThreshold:         2,
 		},
 		{
-			name: "test 5 - invalid tick counter - increase",
-			node: corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{
-						annotationNodeNotReadyTick: "asdefg",
-					},
-				},
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionFalse,
-							LastHeartbeatTime: metav1.Time{Time: time.Now().Add(-time.Minute * 10)},
-						},
-					},
-				},
+			name:                      "test 5 - duration without interval",
+			notReadyThresholdDuration: time.Minute * 10,
+			errorMatcher:              IsInvalidConfig,
+		},
+		{
+			name:                      "test 6 - negative duration",
+			notReadyThresholdDuration: -time.Minute,
+			runInterval:               time.Minute,
+			errorMatcher:              IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Logger:                    logger,
+				K8sClient:                 fake.NewClientBuilder().Build(),
+				NotReadyTickThreshold:     tc.notReadyTickThreshold,
+				NotReadyThresholdDuration: tc.notReadyThresholdDuration,
+				RunInterval:               tc.runInterval,
+			})
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if d.notReadyTickThreshold != tc.expectedThreshold {
+				t.Fatalf("Expected tick threshold '%d' but got '%d'.\n", tc.expectedThreshold, d.notReadyTickThreshold)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_notReadyThresholdDuration(t *testing.T) {
+	node := testNode("worker1").
+		WithRole(labelNodeRoleWorker).
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    fake.NewClientBuilder().WithObjects(&node).Build(),
+		MaxNodeTerminationPercentage: 1,
+		NotReadyThresholdDuration:    time.Minute * 3,
+		RunInterval:                  time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the node must be NotReady for 3 minutes, which are 3 runs at an interval of 1 minute
+	var badNodeCounts []int
+	for i := 0; i < 3; i++ {
+		badNodes, err := d.DetectBadNodes(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		badNodeCounts = append(badNodeCounts, len(badNodes))
+	}
+
+	expected := []int{0, 0, 1}
+	if !cmp.Equal(badNodeCounts, expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, badNodeCounts))
+	}
+}
+
+func Test_DetectBadNodes_lastDetectionRun(t *testing.T) {
+	testCases := []struct {
+		name           string
+		record         bool
+		expectedRecord map[string]bool
+	}{
+		{
+			name:   "test 0 - recording disabled",
+			record: false,
+			expectedRecord: map[string]bool{
+				"changed":   false,
+				"unchanged": false,
 			},
-			expectedTickCount: 1,
-			shouldUpdate:      true,
 		},
 		{
-			name: "test 6 - invalid tick counter - reset to zero",
-			node: corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{
-						annotationNodeNotReadyTick: "asdefg",
-					},
-				},
-				Status: corev1.NodeStatus{
-					Conditions: []corev1.NodeCondition{
-						{
-							Type:              corev1.NodeReady,
-							Status:            corev1.ConditionTrue,
-							LastHeartbeatTime: metav1.Now(),
-						},
-					},
-				},
+			name:   "test 1 - recording enabled",
+			record: true,
+			expectedRecord: map[string]bool{
+				"changed":   true,
+				"unchanged": false,
 			},
-			expectedTickCount: 0,
-			shouldUpdate:      true,
 		},
 	}
 
@@ -622,15 +1572,49 @@ func Test_nodeNotReadyTickCount(t *testing.T) {
 
 			logger, _ := micrologger.New(micrologger.Config{})
 
-			tickCounter, updated := nodeNotReadyTickCount(context.Background(), logger, tc.node)
-			if tickCounter != tc.expectedTickCount {
-				t.Fatalf("Expected tick counter '%d' but got '%d'.\n", tc.expectedTickCount, tickCounter)
+			changedNode := testNode("changed").
+				WithAnnotation(annotationNodeNotReadyTick, "2").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build()
+			unchangedNode := testNode("unchanged").
+				WithAnnotation(annotationNodeNotReadyTick, "0").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build()
+			k8sClient := fake.NewClientBuilder().WithObjects(&changedNode, &unchangedNode).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                  &FakeClock{Time: testNow},
+				Logger:                 logger,
+				K8sClient:              k8sClient,
+				RecordLastDetectionRun: tc.record,
+			})
+			if err != nil {
+				t.Fatal(err)
 			}
 
-			if updated != tc.shouldUpdate {
-				t.Fatalf("Expected tick counter updated value to be '%t' but got '%t'.\n", tc.shouldUpdate, updated)
+			_, err = d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
 			}
 
+			for name, expected := range tc.expectedRecord {
+				var node corev1.Node
+				err = k8sClient.Get(context.Background(), client.ObjectKey{Name: name}, &node)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				value, ok := node.Annotations[LastDetectionRunAnnotation]
+				if ok != expected {
+					t.Fatalf("Expected annotation '%t' on node %s but got '%t'.\n", expected, name, ok)
+				}
+				if ok && !strings.HasSuffix(value, " "+testNow.Format(time.RFC3339)) {
+					t.Fatalf("Expected annotation with run time '%s' but got '%s'.\n", testNow.Format(time.RFC3339), value)
+				}
+				if ok && len(strings.Fields(value)[0]) != runIDLength {
+					t.Fatalf("Expected run id of length '%d' but got '%s'.\n", runIDLength, value)
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,72 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TerminationDiagnostics is the diagnostic context of a node at the moment it was returned for termination.
+// It is stored as json in the NodeTerminationDiagnosticsAnnotation.
+type TerminationDiagnostics struct {
+	// DetectedAt is the time of the run which returned the node for termination.
+	DetectedAt time.Time `json:"detectedAt"`
+	// TickCount is the not ready tick count of the node.
+	TickCount int `json:"tickCount"`
+	// Conditions are all conditions of the node.
+	Conditions []corev1.NodeCondition `json:"conditions"`
+}
+
+// GetTerminationDiagnostics returns the diagnostics recorded on the node when it was returned for termination.
+// The second return value is false when the node has no diagnostics annotation.
+func GetTerminationDiagnostics(node corev1.Node) (TerminationDiagnostics, bool, error) {
+	value, ok := node.Annotations[NodeTerminationDiagnosticsAnnotation]
+	if !ok {
+		return TerminationDiagnostics{}, false, nil
+	}
+
+	var diagnostics TerminationDiagnostics
+	err := json.Unmarshal([]byte(value), &diagnostics)
+	if err != nil {
+		return TerminationDiagnostics{}, false, microerror.Mask(err)
+	}
+
+	return diagnostics, true, nil
+}
+
+// annotateTerminationDiagnostics writes the diagnostics annotation to all nodes returned for termination.
+// Failures are logged only, as missing diagnostics must not prevent the termination of bad nodes.
+func (d *Detector) annotateTerminationDiagnostics(ctx context.Context, r *detectionRun, badNodes []corev1.Node) {
+	for i := range badNodes {
+		n := &badNodes[i]
+
+		diagnostics := TerminationDiagnostics{
+			DetectedAt: r.now,
+			TickCount:  nodeTickCount(*n, d.tickAnnotationKey),
+			Conditions: n.Status.Conditions,
+		}
+		value, err := json.Marshal(diagnostics)
+		if err != nil {
+			r.logger.Errorf(ctx, err, "failed to encode termination diagnostics of node %s", n.Name)
+			continue
+		}
+
+		err = d.waitForUpdate(ctx)
+		if err != nil {
+			r.logger.Errorf(ctx, err, "failed to write termination diagnostics of node %s", n.Name)
+			return
+		}
+
+		updated := n.DeepCopy()
+		setAnnotation(updated, NodeTerminationDiagnosticsAnnotation, string(value))
+		err = d.k8sClient.Update(ctx, updated)
+		if err != nil {
+			r.logger.Errorf(ctx, err, "failed to write termination diagnostics of node %s", n.Name)
+			continue
+		}
+		*n = *updated
+	}
+}
@@ -0,0 +1,127 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodes_terminationDiagnostics(t *testing.T) {
+	testCases := []struct {
+		name              string
+		annotate          bool
+		expectDiagnostics bool
+	}{
+		{
+			name:              "test 0 - diagnostics disabled",
+			annotate:          false,
+			expectDiagnostics: false,
+		},
+		{
+			name:              "test 1 - diagnostics enabled",
+			annotate:          true,
+			expectDiagnostics: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			badNode := testNode("worker1").
+				WithRole(labelNodeRoleWorker).
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				WithCondition("DiskFullKubelet", corev1.ConditionTrue, time.Minute*10).
+				Build()
+			healthyNode := testNode("worker2").
+				WithRole(labelNodeRoleWorker).
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				Build()
+
+			k8sClient := fake.NewClientBuilder().WithObjects(&badNode, &healthyNode).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                          &FakeClock{Time: testNow},
+				Logger:                         logger,
+				K8sClient:                      k8sClient,
+				MaxNodeTerminationPercentage:   1,
+				AnnotateTerminationDiagnostics: tc.annotate,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(badNodes) != 1 {
+				t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", 1, len(badNodes))
+			}
+
+			var stored corev1.Node
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, n := range []corev1.Node{badNodes[0], stored} {
+				diagnostics, ok, err := GetTerminationDiagnostics(n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != tc.expectDiagnostics {
+					t.Fatalf("Expected diagnostics '%t' but got '%t'.\n", tc.expectDiagnostics, ok)
+				}
+				if !ok {
+					continue
+				}
+
+				if !diagnostics.DetectedAt.Equal(testNow) {
+					t.Fatalf("Expected detection time '%s' but got '%s'.\n", testNow, diagnostics.DetectedAt)
+				}
+				if diagnostics.TickCount != 6 {
+					t.Fatalf("Expected '%d' tick count but got '%d'.\n", 6, diagnostics.TickCount)
+				}
+				if len(diagnostics.Conditions) != 2 {
+					t.Fatalf("Expected '%d' conditions but got '%d'.\n", 2, len(diagnostics.Conditions))
+				}
+				if diagnostics.Conditions[0].Type != corev1.NodeReady || diagnostics.Conditions[0].Status != corev1.ConditionFalse {
+					t.Fatalf("Expected condition '%s=%s' but got '%s=%s'.\n", corev1.NodeReady, corev1.ConditionFalse, diagnostics.Conditions[0].Type, diagnostics.Conditions[0].Status)
+				}
+			}
+
+			var healthy corev1.Node
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker2"}, &healthy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := healthy.Annotations[NodeTerminationDiagnosticsAnnotation]; ok {
+				t.Fatalf("Expected no diagnostics on healthy node.\n")
+			}
+		})
+	}
+}
+
+func Test_GetTerminationDiagnostics_invalid(t *testing.T) {
+	node := testNode("worker1").
+		WithAnnotation(NodeTerminationDiagnosticsAnnotation, "garbage").
+		Build()
+
+	_, ok, err := GetTerminationDiagnostics(node)
+	if err == nil {
+		t.Fatalf("error == nil, want non-nil")
+	}
+	if ok {
+		t.Fatalf("Expected diagnostics '%t' but got '%t'.\n", false, ok)
+	}
+}
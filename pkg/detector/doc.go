@@ -0,0 +1,39 @@
+// Package detector implements bad node detection for Kubernetes clusters.
+//
+// Every call of Detector.DetectBadNodes evaluates the conditions of all nodes. A node is unhealthy when
+// its Ready condition is not true or one of the disk conditions (DiskPressure and the conditions of
+// node-problem-detector) is true for at least 30 seconds. Each run increases the not ready tick count
+// of unhealthy nodes by one and decreases it for healthy nodes. The tick count is stored in the
+// `giantswarm.io/node-not-ready-tick` node annotation, so it survives restarts of the caller.
+//
+// Nodes whose tick count reached the NotReadyTickThreshold are returned as 'marked for termination'.
+// The result is limited to protect the cluster: at most MaxMasterTerminations master nodes are returned,
+// node pools keep MinReadyNodesPerPool Ready nodes and no more than MaxNodeTerminationPercentage of all
+// nodes are returned.
+//
+// The detector does not hold a time lock between terminations. PauseBetweenTermination is kept for
+// compatibility, callers have to pause between terminating nodes themselves, ie: by calling
+// DetectBadNodes only after the replaced nodes joined the cluster. After terminating nodes,
+// ResetTickCounters starts the tick accounting from scratch.
+//
+// A typical usage from a reconciliation loop looks like this:
+//
+//	config := detector.DefaultConfig()
+//	config.Logger = logger
+//	config.K8sClient = k8sClient
+//	config.MaxNodeTerminationPercentage = 0.2
+//
+//	d, err := detector.NewDetector(config)
+//	if err != nil {
+//		return microerror.Mask(err)
+//	}
+//
+//	// called every minute
+//	badNodes, err := d.DetectBadNodes(ctx)
+//	if err != nil {
+//		return microerror.Mask(err)
+//	}
+//	for _, n := range badNodes {
+//		// terminate the instance of node n
+//	}
+package detector
@@ -4,6 +4,24 @@ import (
 	"github.com/giantswarm/microerror"
 )
 
+var ambiguousRoleError = &microerror.Error{
+	Kind: "ambiguousRoleError",
+}
+
+// IsAmbiguousRole asserts ambiguousRoleError.
+func IsAmbiguousRole(err error) bool {
+	return microerror.Cause(err) == ambiguousRoleError
+}
+
+var invalidArgumentError = &microerror.Error{
+	Kind: "invalidArgumentError",
+}
+
+// IsInvalidArgument asserts invalidArgumentError.
+func IsInvalidArgument(err error) bool {
+	return microerror.Cause(err) == invalidArgumentError
+}
+
 var invalidConfigError = &microerror.Error{
 	Kind: "invalidConfigError",
 }
@@ -12,3 +30,75 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var invalidNodeError = &microerror.Error{
+	Kind: "invalidNodeError",
+}
+
+// IsInvalidNode asserts invalidNodeError.
+func IsInvalidNode(err error) bool {
+	return microerror.Cause(err) == invalidNodeError
+}
+
+var invalidTickAnnotationError = &microerror.Error{
+	Kind: "invalidTickAnnotationError",
+}
+
+// IsInvalidTickAnnotation asserts invalidTickAnnotationError.
+func IsInvalidTickAnnotation(err error) bool {
+	return microerror.Cause(err) == invalidTickAnnotationError
+}
+
+var listNodesError = &microerror.Error{
+	Kind: "listNodesError",
+}
+
+// IsListNodes asserts listNodesError.
+func IsListNodes(err error) bool {
+	return microerror.Cause(err) == listNodesError
+}
+
+var nodeNotFoundError = &microerror.Error{
+	Kind: "nodeNotFoundError",
+}
+
+// IsNodeNotFound asserts nodeNotFoundError.
+func IsNodeNotFound(err error) bool {
+	return microerror.Cause(err) == nodeNotFoundError
+}
+
+var nodeUpdateError = &microerror.Error{
+	Kind: "nodeUpdateError",
+}
+
+// IsNodeUpdate asserts nodeUpdateError.
+func IsNodeUpdate(err error) bool {
+	return microerror.Cause(err) == nodeUpdateError
+}
+
+var recentTerminationsError = &microerror.Error{
+	Kind: "recentTerminationsError",
+}
+
+// IsRecentTerminations asserts recentTerminationsError.
+func IsRecentTerminations(err error) bool {
+	return microerror.Cause(err) == recentTerminationsError
+}
+
+var staleNodeDataError = &microerror.Error{
+	Kind: "staleNodeDataError",
+}
+
+// IsStaleNodeData asserts staleNodeDataError.
+func IsStaleNodeData(err error) bool {
+	return microerror.Cause(err) == staleNodeDataError
+}
+
+var terminationHistoryError = &microerror.Error{
+	Kind: "terminationHistoryError",
+}
+
+// IsTerminationHistory asserts terminationHistoryError.
+func IsTerminationHistory(err error) bool {
+	return microerror.Cause(err) == terminationHistoryError
+}
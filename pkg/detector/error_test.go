@@ -0,0 +1,147 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// errorClient wraps a client and returns the configured errors for List and Update calls.
+type errorClient struct {
+	client.Client
+
+	listError   error
+	updateError error
+}
+
+func (c *errorClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if c.listError != nil {
+		return c.listError
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c *errorClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if c.updateError != nil {
+		return c.updateError
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func Test_errorMatchers(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		errorMatcher func(error) bool
+		expected     bool
+	}{
+		{
+			name:         "test 0 - invalid config error",
+			err:          microerror.Maskf(invalidConfigError, "test"),
+			errorMatcher: IsInvalidConfig,
+			expected:     true,
+		},
+		{
+			name:         "test 1 - list nodes error",
+			err:          microerror.Maskf(listNodesError, "test"),
+			errorMatcher: IsListNodes,
+			expected:     true,
+		},
+		{
+			name:         "test 2 - node update error",
+			err:          microerror.Maskf(nodeUpdateError, "test"),
+			errorMatcher: IsNodeUpdate,
+			expected:     true,
+		},
+		{
+			name:         "test 3 - list nodes error does not match node update error",
+			err:          microerror.Maskf(listNodesError, "test"),
+			errorMatcher: IsNodeUpdate,
+			expected:     false,
+		},
+		{
+			name:         "test 4 - unrelated error",
+			err:          errors.New("test"),
+			errorMatcher: IsListNodes,
+			expected:     false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result := tc.errorMatcher(tc.err)
+			if result != tc.expected {
+				t.Fatalf("Expected '%t' but got '%t'.\n", tc.expected, result)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_errors(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "worker1",
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{
+					Type:   corev1.NodeReady,
+					Status: corev1.ConditionFalse,
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		listError    error
+		updateError  error
+		errorMatcher func(error) bool
+	}{
+		{
+			name:         "test 0 - list nodes fails",
+			listError:    errors.New("connection refused"),
+			errorMatcher: IsListNodes,
+		},
+		{
+			name:         "test 1 - node update fails",
+			updateError:  errors.New("connection refused"),
+			errorMatcher: IsNodeUpdate,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Logger: logger,
+				K8sClient: &errorClient{
+					Client:      fake.NewClientBuilder().WithObjects(node.DeepCopy()).Build(),
+					listError:   tc.listError,
+					updateError: tc.updateError,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = d.DetectBadNodes(context.Background())
+			if !tc.errorMatcher(err) {
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
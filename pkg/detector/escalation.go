@@ -0,0 +1,107 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// terminationHistoryRecoveredSuffix marks the termination history entries recording the number of terminations
+	// of a pool at the time its nodes recovered. ConfigMap keys must only contain alphanumeric characters, '-', '_' or '.'.
+	terminationHistoryRecoveredSuffix = ".recovered"
+)
+
+// escalatedPools returns the pools with at least escalationTerminations nodes 'marked for termination'
+// in the current termination history window since the nodes of the pool recovered the last time.
+// Terminating more nodes of these pools is unlikely to help, as the repeated terminations did not resolve the issue.
+func (d *Detector) escalatedPools(ctx context.Context, now time.Time) (map[string]bool, error) {
+	windowStart := now.UTC().Truncate(d.terminationHistoryWindow)
+
+	counts, err := d.terminationHistory.Counts(ctx)
+	if err != nil {
+		return nil, microerror.Maskf(terminationHistoryError, "%s", err.Error())
+	}
+
+	pools := map[string]bool{}
+	for key, count := range counts {
+		t, ok := terminationHistoryKeyTime(key)
+		if !ok || !t.Equal(windowStart) {
+			continue
+		}
+		pool := terminationHistoryKeyPool(key)
+		if strings.HasSuffix(pool, terminationHistoryRecoveredSuffix) {
+			continue
+		}
+		if count-counts[terminationHistoryRecoveredKey(pool, windowStart)] < d.escalationTerminations {
+			continue
+		}
+		pools[pool] = true
+	}
+
+	return pools, nil
+}
+
+// resetRecoveredPools resets the escalation of the escalated pools without unhealthy nodes,
+// so only the terminations after the recovery count towards escalationTerminations again.
+func (d *Detector) resetRecoveredPools(ctx context.Context, r *detectionRun) error {
+	var recovered []string
+	for pool := range r.escalatedPools {
+		if !r.unhealthyPools[pool] {
+			recovered = append(recovered, pool)
+		}
+	}
+	if len(recovered) == 0 {
+		return nil
+	}
+	sort.Strings(recovered)
+
+	windowStart := r.now.UTC().Truncate(d.terminationHistoryWindow)
+	err := d.terminationHistory.Update(ctx, func(counts map[string]int) {
+		for _, pool := range recovered {
+			counts[terminationHistoryRecoveredKey(pool, windowStart)] = counts[terminationHistoryKey(pool, windowStart)]
+		}
+	})
+	if err != nil {
+		return microerror.Maskf(terminationHistoryError, "%s", err.Error())
+	}
+
+	for _, pool := range recovered {
+		r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("reset escalated tick threshold of node pool %#q as its nodes recovered", pool))
+	}
+
+	return nil
+}
+
+// terminationHistoryRecoveredKey returns the key `<pool>.recovered-<window start>` of the termination history.
+func terminationHistoryRecoveredKey(pool string, windowStart time.Time) string {
+	return terminationHistoryKey(pool+terminationHistoryRecoveredSuffix, windowStart)
+}
+
+// logEscalatedPools warns about every pool with an escalated tick threshold.
+func (d *Detector) logEscalatedPools(ctx context.Context, r *detectionRun) {
+	var pools []string
+	for pool := range r.escalatedPools {
+		pools = append(pools, pool)
+	}
+	sort.Strings(pools)
+
+	for _, pool := range pools {
+		r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("escalated tick threshold of node pool %#q to %d after %d terminations within %s, the issue is likely systemic", pool, d.escalatedTickThreshold, d.escalationTerminations, d.terminationHistoryWindow))
+	}
+}
+
+// nodeThreshold returns the tick threshold of the node, which is the threshold of its node pool
+// raised to the escalated tick threshold when the pool is escalated.
+func (d *Detector) nodeThreshold(r *detectionRun, n corev1.Node) int {
+	threshold := d.poolThreshold(r.threshold, n)
+	if r.escalatedPools[n.Labels[d.nodePoolLabel]] && d.escalatedTickThreshold > threshold {
+		threshold = d.escalatedTickThreshold
+	}
+	return threshold
+}
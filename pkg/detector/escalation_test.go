@@ -0,0 +1,177 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodes_escalation(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		escalationTerminations int
+		escalatedTickThreshold int
+		expectedBadNodes       []int
+		expectedEscalatedPools [][]string
+	}{
+		{
+			name:                   "test 0 - escalation disabled",
+			escalationTerminations: 0,
+			expectedBadNodes:       []int{1, 1, 1, 1},
+			expectedEscalatedPools: [][]string{nil, nil, nil, nil},
+		},
+		{
+			name:                   "test 1 - escalation after 2 terminations",
+			escalationTerminations: 2,
+			escalatedTickThreshold: 9,
+			expectedBadNodes:       []int{1, 1, 0, 1},
+			expectedEscalatedPools: [][]string{nil, nil, {"a"}, {"a"}},
+		},
+		{
+			name:                   "test 2 - escalation with default escalated threshold",
+			escalationTerminations: 2,
+			expectedBadNodes:       []int{1, 1, 0, 0},
+			expectedEscalatedPools: [][]string{nil, nil, {"a"}, {"a"}},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			node := testNode("a1").
+				WithLabel(testPoolLabel, "a").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    fake.NewClientBuilder().WithObjects(&node).Build(),
+				MaxNodeTerminationPercentage: 1,
+				NodePoolLabel:                testPoolLabel,
+				TerminationHistoryStore:      &fakeTerminationHistoryStore{counts: map[string]int{}},
+				EscalationTerminations:       tc.escalationTerminations,
+				EscalatedTickThreshold:       tc.escalatedTickThreshold,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the node is not replaced, so every run marks it for termination again until the pool is escalated
+			for run := range tc.expectedBadNodes {
+				ctx := ContextWithRunSummary(context.Background())
+
+				badNodes, err := d.DetectBadNodes(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(badNodes) != tc.expectedBadNodes[run] {
+					t.Fatalf("Expected '%d' bad nodes in run %d but got '%d'.\n", tc.expectedBadNodes[run], run, len(badNodes))
+				}
+
+				summary, _ := RunSummaryFromContext(ctx)
+				if !cmp.Equal(summary.EscalatedPools, tc.expectedEscalatedPools[run]) {
+					t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedEscalatedPools[run], summary.EscalatedPools))
+				}
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_escalationRecovery(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	unhealthy := testNode("a1").
+		WithLabel(testPoolLabel, "a").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+	k8sClient := fake.NewClientBuilder().WithObjects(&unhealthy).Build()
+	store := &fakeTerminationHistoryStore{counts: map[string]int{}}
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    k8sClient,
+		MaxNodeTerminationPercentage: 1,
+		NodePoolLabel:                testPoolLabel,
+		TerminationHistoryStore:      store,
+		EscalationTerminations:       2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(expectedBadNodes int, expectedEscalatedPools []string) {
+		ctx := ContextWithRunSummary(context.Background())
+
+		badNodes, err := d.DetectBadNodes(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(badNodes) != expectedBadNodes {
+			t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", expectedBadNodes, len(badNodes))
+		}
+
+		summary, _ := RunSummaryFromContext(ctx)
+		if !cmp.Equal(summary.EscalatedPools, expectedEscalatedPools) {
+			t.Fatalf("\n\n%s\n", cmp.Diff(expectedEscalatedPools, summary.EscalatedPools))
+		}
+	}
+
+	// the unhealthy node is marked for termination twice, which escalates the pool
+	run(1, nil)
+	run(1, nil)
+
+	// the node got replaced by a healthy node, the pool is still escalated during the run but recovers
+	err = k8sClient.Delete(context.Background(), &unhealthy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement := testNode("a2").
+		WithLabel(testPoolLabel, "a").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+		Build()
+	err = k8sClient.Create(context.Background(), &replacement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(0, []string{"a"})
+
+	windowStart := testNow.UTC().Truncate(defaultTerminationHistoryWindow)
+	if store.counts[terminationHistoryRecoveredKey("a", windowStart)] != 2 {
+		t.Fatalf("Expected '%d' terminations at the recovery but got '%d'.\n", 2, store.counts[terminationHistoryRecoveredKey("a", windowStart)])
+	}
+
+	// the ConfigMap store rejects invalid keys, which the fake store does not
+	for key := range store.counts {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			t.Fatalf("Expected valid ConfigMap key '%s' but got %v.\n", key, errs)
+		}
+	}
+
+	// only the terminations after the recovery count towards the escalation
+	err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "a2"}, &replacement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement.Annotations = map[string]string{annotationNodeNotReadyTick: "5"}
+	replacement.Status.Conditions = unhealthy.Status.Conditions
+	err = k8sClient.Update(context.Background(), &replacement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(1, nil)
+}
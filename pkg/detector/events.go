@@ -0,0 +1,119 @@
+package detector
+
+import (
+	"context"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// defaultNodeStateEventsBuffer is the capacity of the NodeStateEvents channel before the first run
+// counted the nodes of the cluster.
+const defaultNodeStateEventsBuffer = 500
+
+const (
+	// NodeStatePhaseHealthy is the phase of nodes with a zero tick count after the run.
+	NodeStatePhaseHealthy = "Healthy"
+	// NodeStatePhaseNotReady is the phase of nodes with a non zero tick count which are not 'marked for termination'.
+	NodeStatePhaseNotReady = "NotReady"
+	// NodeStatePhaseCandidate is the phase of nodes 'marked for termination' before the termination limits are applied.
+	NodeStatePhaseCandidate = "Candidate"
+)
+
+// NodeStateEvent describes the state of a single node before and after it was processed by a detection run.
+type NodeStateEvent struct {
+	NodeName string
+	// Phase is one of NodeStatePhaseHealthy, NodeStatePhaseNotReady or NodeStatePhaseCandidate.
+	Phase string
+	// OldTick and NewTick are the tick counts of the node before and after the run.
+	OldTick int
+	NewTick int
+	// IsCandidate is true if the node was 'marked for termination' before the termination limits are applied.
+	IsCandidate bool
+	// WasUpdated is true if the run updated the node in the k8s api.
+	WasUpdated bool
+	// Conditions contains the types of the unhealthy conditions of the node.
+	Conditions []string
+}
+
+// nodeStateEvents hands the channel returned by NodeStateEvents to the next detection run.
+type nodeStateEvents struct {
+	mutex sync.Mutex
+	next  chan NodeStateEvent
+}
+
+// NodeStateEvents returns a channel on which the next DetectBadNodes run publishes one event per processed node.
+// The capacity of the channel is the node count of the previous run, events are dropped when it is full.
+// The channel is closed when the run returns, so it must be requested again for every run.
+func (d *Detector) NodeStateEvents() <-chan NodeStateEvent {
+	capacity := d.previousNodeCount()
+	if capacity == 0 {
+		capacity = defaultNodeStateEventsBuffer
+	}
+
+	d.stateEvents.mutex.Lock()
+	defer d.stateEvents.mutex.Unlock()
+
+	// a channel which was not used by a run yet is replaced, its reader must not wait forever
+	if d.stateEvents.next != nil {
+		close(d.stateEvents.next)
+	}
+
+	d.stateEvents.next = make(chan NodeStateEvent, capacity)
+
+	return d.stateEvents.next
+}
+
+// takeNodeStateEvents returns the channel requested for the run or nil if no channel was requested.
+func (d *Detector) takeNodeStateEvents() chan NodeStateEvent {
+	d.stateEvents.mutex.Lock()
+	defer d.stateEvents.mutex.Unlock()
+
+	events := d.stateEvents.next
+	d.stateEvents.next = nil
+	return events
+}
+
+// processNodeWithEvent processes the node and publishes its state change, if a channel was requested for the run.
+func (d *Detector) processNodeWithEvent(ctx context.Context, r *detectionRun, n *corev1.Node) (bool, error) {
+	if r.events == nil {
+		return d.processNode(ctx, r, n)
+	}
+
+	oldTick := nodeTickCount(*n, d.tickAnnotationKey)
+	// the k8s api changes the resource version of the node on every update
+	resourceVersion := n.ResourceVersion
+
+	bad, err := d.processNode(ctx, r, n)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	event := NodeStateEvent{
+		NodeName:    n.Name,
+		OldTick:     oldTick,
+		NewTick:     nodeTickCount(*n, d.tickAnnotationKey),
+		IsCandidate: bad,
+		WasUpdated:  n.ResourceVersion != resourceVersion,
+	}
+	switch {
+	case bad:
+		event.Phase = NodeStatePhaseCandidate
+	case event.NewTick > 0:
+		event.Phase = NodeStatePhaseNotReady
+	default:
+		event.Phase = NodeStatePhaseHealthy
+	}
+	for _, c := range d.healthCheck.unhealthyConditions(*n) {
+		event.Conditions = append(event.Conditions, string(c.Type))
+	}
+
+	select {
+	case r.events <- event:
+	default:
+		// the reader is too slow, events are dropped instead of blocking the run
+	}
+
+	return bad, nil
+}
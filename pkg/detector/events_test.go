@@ -0,0 +1,98 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodes_nodeStateEvents(t *testing.T) {
+	newNode := func(name string, tick string, status corev1.ConditionStatus) *corev1.Node {
+		node := testNode(name).
+			WithRole(labelNodeRoleWorker).
+			WithAnnotation(annotationNodeNotReadyTick, tick).
+			WithCondition(corev1.NodeReady, status, time.Minute*10).
+			Build()
+		return &node
+	}
+
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	d, err := NewDetector(Config{
+		Clock:  &FakeClock{Time: testNow},
+		Logger: logger,
+		K8sClient: fake.NewClientBuilder().WithObjects(
+			newNode("worker1", "0", corev1.ConditionTrue),
+			newNode("worker2", "2", corev1.ConditionFalse),
+			newNode("worker3", "5", corev1.ConditionFalse),
+			newNode("worker4", "5", corev1.ConditionFalse),
+		).Build(),
+		MaxNodeTerminationPercentage: 0.25,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := d.NodeStateEvents()
+
+	badNodes, err := d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(badNodes) != 1 {
+		t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", 1, len(badNodes))
+	}
+
+	// the channel is closed after the run, so reading it ends
+	received := map[string]NodeStateEvent{}
+	phases := map[string]int{}
+	for e := range events {
+		received[e.NodeName] = e
+		phases[e.Phase]++
+	}
+
+	if len(received) != 4 {
+		t.Fatalf("Expected '%d' events but got '%d'.\n", 4, len(received))
+	}
+	expectedPhases := map[string]int{
+		NodeStatePhaseHealthy:   1,
+		NodeStatePhaseNotReady:  1,
+		NodeStatePhaseCandidate: 2,
+	}
+	if !cmp.Equal(phases, expectedPhases) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedPhases, phases))
+	}
+
+	expected := NodeStateEvent{
+		NodeName:    "worker3",
+		Phase:       NodeStatePhaseCandidate,
+		OldTick:     5,
+		NewTick:     6,
+		IsCandidate: true,
+		WasUpdated:  true,
+		Conditions:  []string{string(corev1.NodeReady)},
+	}
+	if !cmp.Equal(received["worker3"], expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, received["worker3"]))
+	}
+	if received["worker1"].WasUpdated {
+		t.Fatalf("Expected healthy node %s not to be updated.\n", "worker1")
+	}
+
+	// a run without requested channel does not publish events
+	_, err = d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the capacity of the next channel is the node count of the previous run
+	events = d.NodeStateEvents()
+	if cap(events) != 4 {
+		t.Fatalf("Expected capacity '%d' but got '%d'.\n", 4, cap(events))
+	}
+}
@@ -0,0 +1,148 @@
+package detector
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodeFilter decides which nodes are handled by the detector.
+// Nodes which are not included are skipped before tick accounting the same way as nodes not matching NodeOS or NodeArch.
+type NodeFilter interface {
+	// Include returns false for nodes which must be skipped by the detector.
+	Include(node corev1.Node) bool
+}
+
+// NodeFilterFunc allows to use an ordinary function as NodeFilter.
+type NodeFilterFunc func(node corev1.Node) bool
+
+// Include calls f(node).
+func (f NodeFilterFunc) Include(node corev1.Node) bool {
+	return f(node)
+}
+
+// ExcludeLabelFilter skips nodes with the given label. An empty value matches any value of the label.
+func ExcludeLabelFilter(key, value string) NodeFilter {
+	return NodeFilterFunc(func(node corev1.Node) bool {
+		v, ok := node.Labels[key]
+		return !ok || (value != "" && v != value)
+	})
+}
+
+// ExcludeAnnotationFilter skips nodes with the given annotation. An empty value matches any value of the annotation.
+func ExcludeAnnotationFilter(key, value string) NodeFilter {
+	return NodeFilterFunc(func(node corev1.Node) bool {
+		v, ok := node.Annotations[key]
+		return !ok || (value != "" && v != value)
+	})
+}
+
+// ExcludeTaintFilter skips nodes with a taint of the given key. An empty effect matches any effect of the taint.
+func ExcludeTaintFilter(key string, effect corev1.TaintEffect) NodeFilter {
+	return NodeFilterFunc(func(node corev1.Node) bool {
+		for _, t := range node.Spec.Taints {
+			if t.Key == key && (effect == "" || t.Effect == effect) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// ExcludeCordonedFilter skips nodes which are cordoned.
+func ExcludeCordonedFilter() NodeFilter {
+	return NodeFilterFunc(func(node corev1.Node) bool {
+		return !node.Spec.Unschedulable
+	})
+}
+
+// MinAgeFilter skips nodes which were created less than minAge ago, ie: nodes which are still joining the cluster.
+func MinAgeFilter(clock Clock, minAge time.Duration) NodeFilter {
+	return NodeFilterFunc(func(node corev1.Node) bool {
+		return clock.Now().Sub(node.CreationTimestamp.Time) >= minAge
+	})
+}
+
+// filterNodes returns the nodes included by all node filters, the filters are applied in order.
+func filterNodes(nodes []corev1.Node, filters []NodeFilter) []corev1.Node {
+	if len(filters) == 0 {
+		return nodes
+	}
+
+	var filtered []corev1.Node
+	for _, n := range nodes {
+		if includeNode(n, filters) {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+func includeNode(n corev1.Node, filters []NodeFilter) bool {
+	for _, f := range filters {
+		if !f.Include(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// NodeListFilter filters the nodes 'marked for termination' as a whole, ie: to limit them.
+// Unlike NodeFilter it is applied after the detection and does not affect the tick accounting.
+type NodeListFilter func(nodes []corev1.Node) []corev1.Node
+
+// FilterChain is an ordered list of NodeListFilter.
+type FilterChain []NodeListFilter
+
+// Apply applies the filters in order, each filter gets the nodes returned by the previous one.
+func (c FilterChain) Apply(nodes []corev1.Node) []corev1.Node {
+	for _, f := range c {
+		nodes = f(nodes)
+	}
+	return nodes
+}
+
+// MaxPercentageFilter keeps at most the given percentage of totalCount nodes, but at least 1 node.
+func MaxPercentageFilter(pct float64, totalCount int) NodeListFilter {
+	return func(nodes []corev1.Node) []corev1.Node {
+		limit := maximumNodeTermination(totalCount, pct)
+		if len(nodes) > limit {
+			return nodes[:limit]
+		}
+		return nodes
+	}
+}
+
+// MasterDedupFilter keeps at most maxMasters master nodes, worker nodes are unaffected.
+func MasterDedupFilter(maxMasters int) NodeListFilter {
+	return func(nodes []corev1.Node) []corev1.Node {
+		return limitMasterNodes(nodes, maxMasters)
+	}
+}
+
+// ZoneLimitFilter keeps at most max nodes per value of the zone label, ie: `topology.kubernetes.io/zone`.
+// Nodes without the zone label are unaffected.
+func ZoneLimitFilter(zoneLabel string, max int) NodeListFilter {
+	return func(nodes []corev1.Node) []corev1.Node {
+		var filtered []corev1.Node
+		nodesPerZone := map[string]int{}
+		for _, n := range nodes {
+			zone, ok := n.Labels[zoneLabel]
+			if ok {
+				if nodesPerZone[zone] >= max {
+					continue
+				}
+				nodesPerZone[zone]++
+			}
+			filtered = append(filtered, n)
+		}
+		return filtered
+	}
+}
+
+// AnnotationSkipFilter removes the nodes with the given annotation regardless of its value.
+func AnnotationSkipFilter(key string) NodeListFilter {
+	return func(nodes []corev1.Node) []corev1.Node {
+		return filterNodes(nodes, []NodeFilter{ExcludeAnnotationFilter(key, "")})
+	}
+}
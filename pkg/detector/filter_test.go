@@ -0,0 +1,463 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/detector/testutil"
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_filterNodes(t *testing.T) {
+	nodes := []corev1.Node{
+		testNode("worker1").
+			WithCreationTime(testNow.Add(-time.Hour)).
+			Build(),
+		testNode("worker2").
+			WithLabel("example.com/ignore", "true").
+			WithCreationTime(testNow.Add(-time.Hour)).
+			Build(),
+		testNode("worker3").
+			WithAnnotation("example.com/maintenance", "planned").
+			WithCreationTime(testNow.Add(-time.Hour)).
+			Build(),
+		testNode("worker4").
+			WithTaint("example.com/dedicated", "gpu", corev1.TaintEffectNoSchedule).
+			WithCreationTime(testNow.Add(-time.Hour)).
+			Build(),
+		testNode("worker5").
+			WithCreationTime(testNow.Add(-time.Minute)).
+			Build(),
+	}
+	cordoned := testNode("worker6").
+		WithCreationTime(testNow.Add(-time.Hour)).
+		Build()
+	cordoned.Spec.Unschedulable = true
+	nodes = append(nodes, cordoned)
+
+	testCases := []struct {
+		name          string
+		filters       []NodeFilter
+		expectedNodes []string
+	}{
+		{
+			name:          "test 0 - no filters",
+			filters:       nil,
+			expectedNodes: []string{"worker1", "worker2", "worker3", "worker4", "worker5", "worker6"},
+		},
+		{
+			name:          "test 1 - exclude label with any value",
+			filters:       []NodeFilter{ExcludeLabelFilter("example.com/ignore", "")},
+			expectedNodes: []string{"worker1", "worker3", "worker4", "worker5", "worker6"},
+		},
+		{
+			name:          "test 2 - exclude label with different value",
+			filters:       []NodeFilter{ExcludeLabelFilter("example.com/ignore", "false")},
+			expectedNodes: []string{"worker1", "worker2", "worker3", "worker4", "worker5", "worker6"},
+		},
+		{
+			name:          "test 3 - exclude annotation",
+			filters:       []NodeFilter{ExcludeAnnotationFilter("example.com/maintenance", "planned")},
+			expectedNodes: []string{"worker1", "worker2", "worker4", "worker5", "worker6"},
+		},
+		{
+			name:          "test 4 - exclude taint with any effect",
+			filters:       []NodeFilter{ExcludeTaintFilter("example.com/dedicated", "")},
+			expectedNodes: []string{"worker1", "worker2", "worker3", "worker5", "worker6"},
+		},
+		{
+			name:          "test 5 - exclude taint with different effect",
+			filters:       []NodeFilter{ExcludeTaintFilter("example.com/dedicated", corev1.TaintEffectNoExecute)},
+			expectedNodes: []string{"worker1", "worker2", "worker3", "worker4", "worker5", "worker6"},
+		},
+		{
+			name:          "test 6 - exclude cordoned nodes",
+			filters:       []NodeFilter{ExcludeCordonedFilter()},
+			expectedNodes: []string{"worker1", "worker2", "worker3", "worker4", "worker5"},
+		},
+		{
+			name:          "test 7 - exclude young nodes",
+			filters:       []NodeFilter{MinAgeFilter(&FakeClock{Time: testNow}, time.Minute*10)},
+			expectedNodes: []string{"worker1", "worker2", "worker3", "worker4", "worker6"},
+		},
+		{
+			name: "test 8 - combined filters",
+			filters: []NodeFilter{
+				ExcludeLabelFilter("example.com/ignore", ""),
+				ExcludeAnnotationFilter("example.com/maintenance", ""),
+				ExcludeTaintFilter("example.com/dedicated", corev1.TaintEffectNoSchedule),
+				ExcludeCordonedFilter(),
+				MinAgeFilter(&FakeClock{Time: testNow}, time.Minute*10),
+			},
+			expectedNodes: []string{"worker1"},
+		},
+		{
+			name: "test 9 - custom filter function",
+			filters: []NodeFilter{
+				ExcludeCordonedFilter(),
+				NodeFilterFunc(func(node corev1.Node) bool {
+					return node.Name != "worker1"
+				}),
+			},
+			expectedNodes: []string{"worker2", "worker3", "worker4", "worker5"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var names []string
+			for _, n := range filterNodes(nodes, tc.filters) {
+				names = append(names, n.Name)
+			}
+
+			if !cmp.Equal(names, tc.expectedNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodes, names))
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_nodeFilters(t *testing.T) {
+	newNode := func(name string, labels map[string]string) *corev1.Node {
+		b := testNode(name).
+			WithAnnotation(annotationNodeNotReadyTick, "5").
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10)
+		for k, v := range labels {
+			b.WithLabel(k, v)
+		}
+		node := b.Build()
+		return &node
+	}
+
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		newNode("worker1", nil),
+		newNode("worker2", map[string]string{"example.com/ignore": "true"}),
+		newNode("worker3", map[string]string{"example.com/team": "storage"}),
+	).Build()
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    k8sClient,
+		MaxNodeTerminationPercentage: 1,
+		NodeFilters: []NodeFilter{
+			ExcludeLabelFilter("example.com/ignore", ""),
+			ExcludeLabelFilter("example.com/team", "storage"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badNodes, err := d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, n := range badNodes {
+		names = append(names, n.Name)
+	}
+	sort.Strings(names)
+	if !cmp.Equal(names, []string{"worker1"}) {
+		t.Fatalf("\n\n%s\n", cmp.Diff([]string{"worker1"}, names))
+	}
+
+	// excluded nodes must not be touched by the tick accounting
+	expectedTickCount := map[string]string{
+		"worker1": "6",
+		"worker2": "5",
+		"worker3": "5",
+	}
+	for name, expected := range expectedTickCount {
+		var node corev1.Node
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: name}, &node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Annotations[annotationNodeNotReadyTick] != expected {
+			t.Fatalf("Expected tick count '%s' for node %s but got '%s'.\n", expected, name, node.Annotations[annotationNodeNotReadyTick])
+		}
+	}
+}
+
+func Test_DetectBadNodes_nodeFiltersTerminationLimit(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	var objects []client.Object
+	for i := 0; i < 10; i++ {
+		b := testNode(fmt.Sprintf("worker%d", i)).
+			WithAnnotation(annotationNodeNotReadyTick, "5").
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10)
+		if i >= 4 {
+			b.WithLabel("example.com/ignore", "true")
+		}
+		node := b.Build()
+		objects = append(objects, &node)
+	}
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    fake.NewClientBuilder().WithObjects(objects...).Build(),
+		MaxNodeTerminationPercentage: 0.4,
+		NodeFilters: []NodeFilter{
+			ExcludeLabelFilter("example.com/ignore", ""),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badNodes, err := d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the termination limit is based on all 10 nodes, not only on the 4 nodes handled by the detector
+	if len(badNodes) != 4 {
+		t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", 4, len(badNodes))
+	}
+}
+
+func Test_ResetTickCounters_nodeFilters(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	handled := testNode("worker1").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		Build()
+	excluded := testNode("worker2").
+		WithLabel("example.com/ignore", "true").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		Build()
+	k8sClient := fake.NewClientBuilder().WithObjects(&handled, &excluded).Build()
+
+	d, err := NewDetector(Config{
+		Clock:     &FakeClock{Time: testNow},
+		Logger:    logger,
+		K8sClient: k8sClient,
+		NodeFilters: []NodeFilter{
+			ExcludeLabelFilter("example.com/ignore", ""),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.ResetTickCounters(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nodes excluded by the node filters must be reset as well
+	for _, name := range []string{"worker1", "worker2"} {
+		var node corev1.Node
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: name}, &node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Annotations[annotationNodeNotReadyTick] != "0" {
+			t.Fatalf("Expected tick count '0' for node %s but got '%s'.\n", name, node.Annotations[annotationNodeNotReadyTick])
+		}
+	}
+}
+
+func Test_DetectBadNodes_operationalExclusion(t *testing.T) {
+	newNode := func(name string) *testutil.NodeBuilder {
+		return testNode(name).
+			WithAnnotation(annotationNodeNotReadyTick, "5").
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10)
+	}
+	buildNode := func(b *testutil.NodeBuilder) *corev1.Node {
+		node := b.Build()
+		return &node
+	}
+
+	testCases := []struct {
+		name             string
+		exclusionLabels  []string
+		exclusionTaints  []string
+		expectedBadNodes []string
+	}{
+		{
+			name:             "test 0 - no operational exclusion by default",
+			expectedBadNodes: []string{"worker1", "worker2", "worker3"},
+		},
+		{
+			name:             "test 1 - node excluded from external load balancers is skipped",
+			exclusionLabels:  []string{ExcludeFromExternalLoadBalancersLabel},
+			expectedBadNodes: []string{"worker1", "worker3"},
+		},
+		{
+			name:             "test 2 - node with exclusion taint is skipped",
+			exclusionLabels:  []string{ExcludeFromExternalLoadBalancersLabel},
+			exclusionTaints:  []string{"example.com/maintenance"},
+			expectedBadNodes: []string{"worker1"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			k8sClient := fake.NewClientBuilder().WithObjects(
+				buildNode(newNode("worker1")),
+				buildNode(newNode("worker2").WithLabel(ExcludeFromExternalLoadBalancersLabel, "")),
+				buildNode(newNode("worker3").WithTaint("example.com/maintenance", "true", corev1.TaintEffectNoSchedule)),
+			).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+				OperationalExclusionLabels:   tc.exclusionLabels,
+				OperationalExclusionTaints:   tc.exclusionTaints,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, n := range badNodes {
+				names = append(names, n.Name)
+			}
+			sort.Strings(names)
+			if !cmp.Equal(names, tc.expectedBadNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedBadNodes, names))
+			}
+		})
+	}
+}
+
+func Test_FilterChain(t *testing.T) {
+	const zoneLabel = "topology.kubernetes.io/zone"
+
+	nodes := []corev1.Node{
+		testNode("master1").WithRole(labelNodeRoleMaster).WithLabel(zoneLabel, "a").Build(),
+		testNode("master2").WithRole(labelNodeRoleMaster).WithLabel(zoneLabel, "b").Build(),
+		testNode("worker1").WithRole(labelNodeRoleWorker).WithLabel(zoneLabel, "a").Build(),
+		testNode("worker2").WithRole(labelNodeRoleWorker).WithLabel(zoneLabel, "a").Build(),
+		testNode("worker3").WithRole(labelNodeRoleWorker).WithLabel(zoneLabel, "b").WithAnnotation("example.com/keep", "").Build(),
+		testNode("worker4").WithRole(labelNodeRoleWorker).Build(),
+	}
+
+	testCases := []struct {
+		name          string
+		chain         FilterChain
+		expectedNodes []string
+	}{
+		{
+			name:          "test 0 - empty chain",
+			chain:         nil,
+			expectedNodes: []string{"master1", "master2", "worker1", "worker2", "worker3", "worker4"},
+		},
+		{
+			name:          "test 1 - master dedup",
+			chain:         FilterChain{MasterDedupFilter(1)},
+			expectedNodes: []string{"master1", "worker1", "worker2", "worker3", "worker4"},
+		},
+		{
+			name:          "test 2 - zone limit keeps nodes without zone",
+			chain:         FilterChain{ZoneLimitFilter(zoneLabel, 1)},
+			expectedNodes: []string{"master1", "master2", "worker4"},
+		},
+		{
+			name:          "test 3 - annotation skip",
+			chain:         FilterChain{AnnotationSkipFilter("example.com/keep")},
+			expectedNodes: []string{"master1", "master2", "worker1", "worker2", "worker4"},
+		},
+		{
+			name:          "test 4 - max percentage",
+			chain:         FilterChain{MaxPercentageFilter(0.1, 20)},
+			expectedNodes: []string{"master1", "master2"},
+		},
+		{
+			name: "test 5 - filters are applied in order",
+			chain: FilterChain{
+				MasterDedupFilter(0),
+				AnnotationSkipFilter("example.com/keep"),
+				ZoneLimitFilter(zoneLabel, 1),
+				MaxPercentageFilter(0.5, 4),
+			},
+			expectedNodes: []string{"worker1", "worker4"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var names []string
+			for _, n := range tc.chain.Apply(nodes) {
+				names = append(names, n.Name)
+			}
+
+			if !cmp.Equal(names, tc.expectedNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodes, names))
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_extraFilters(t *testing.T) {
+	newNode := func(name string, zone string) *corev1.Node {
+		node := testNode(name).
+			WithRole(labelNodeRoleWorker).
+			WithLabel("topology.kubernetes.io/zone", zone).
+			WithAnnotation(annotationNodeNotReadyTick, "5").
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+			Build()
+		return &node
+	}
+
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	d, err := NewDetector(Config{
+		Clock:  &FakeClock{Time: testNow},
+		Logger: logger,
+		K8sClient: fake.NewClientBuilder().WithObjects(
+			newNode("worker1", "a"),
+			newNode("worker2", "a"),
+			newNode("worker3", "b"),
+		).Build(),
+		MaxNodeTerminationPercentage: 1,
+		ExtraFilters:                 []NodeListFilter{ZoneLimitFilter("topology.kubernetes.io/zone", 1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badNodes, err := d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zones := map[string]int{}
+	for _, n := range badNodes {
+		zones[n.Labels["topology.kubernetes.io/zone"]]++
+	}
+	expectedZones := map[string]int{"a": 1, "b": 1}
+	if !cmp.Equal(zones, expectedZones) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedZones, zones))
+	}
+}
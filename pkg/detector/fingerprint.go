@@ -0,0 +1,51 @@
+package detector
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// GetFingerprintAnnotation returns the unhealthy conditions recorded on the node when it reached the tick threshold,
+// ie: `Ready=False,DiskFullKubelet=True`. The value is empty when the node never reached the threshold or recovered since.
+func GetFingerprintAnnotation(node corev1.Node) string {
+	return node.Annotations[NodeNotReadyFingerprintAnnotation]
+}
+
+// updateNodeFingerprint sets the fingerprint annotation when the node reaches the tick threshold
+// and removes it once the node fully recovered and the tick count is back at zero.
+// Once set, the fingerprint is not overwritten so it reflects the conditions which triggered the detection.
+// It returns true if the annotations of the node changed.
+func updateNodeFingerprint(n *corev1.Node, healthCheck nodeHealthCheck, notReadyTickCount int, threshold int) bool {
+	_, ok := n.Annotations[NodeNotReadyFingerprintAnnotation]
+
+	if notReadyTickCount == 0 {
+		if ok {
+			delete(n.Annotations, NodeNotReadyFingerprintAnnotation)
+			return true
+		}
+		return false
+	}
+
+	if ok || notReadyTickCount < threshold {
+		return false
+	}
+
+	fingerprint := nodeFingerprint(healthCheck, *n)
+	if fingerprint == "" {
+		return false
+	}
+
+	setAnnotation(n, NodeNotReadyFingerprintAnnotation, fingerprint)
+	return true
+}
+
+// nodeFingerprint returns a comma separated list of the unhealthy conditions of the node.
+func nodeFingerprint(healthCheck nodeHealthCheck, n corev1.Node) string {
+	var conditions []string
+	for _, c := range healthCheck.unhealthyConditions(n) {
+		conditions = append(conditions, fmt.Sprintf("%s=%s", c.Type, c.Status))
+	}
+	return strings.Join(conditions, ",")
+}
@@ -0,0 +1,123 @@
+package detector
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_updateNodeFingerprint(t *testing.T) {
+	const diskFullCondition corev1.NodeConditionType = "DiskFullKubelet"
+
+	notReadyConditions := []corev1.NodeCondition{
+		{
+			Type:              corev1.NodeReady,
+			Status:            corev1.ConditionFalse,
+			LastHeartbeatTime: metav1.NewTime(testNow.Add(-time.Minute * 10)),
+		},
+		{
+			Type:              diskFullCondition,
+			Status:            corev1.ConditionTrue,
+			LastHeartbeatTime: metav1.NewTime(testNow.Add(-time.Minute * 10)),
+		},
+	}
+
+	testCases := []struct {
+		name                string
+		node                corev1.Node
+		notReadyTickCount   int
+		expectedFingerprint string
+		expectedUpdated     bool
+	}{
+		{
+			name: "test 0 - below threshold",
+			node: corev1.Node{
+				Status: corev1.NodeStatus{
+					Conditions: notReadyConditions,
+				},
+			},
+			notReadyTickCount:   5,
+			expectedFingerprint: "",
+			expectedUpdated:     false,
+		},
+		{
+			name: "test 1 - threshold breached",
+			node: corev1.Node{
+				Status: corev1.NodeStatus{
+					Conditions: notReadyConditions,
+				},
+			},
+			notReadyTickCount:   6,
+			expectedFingerprint: "Ready=False,DiskFullKubelet=True",
+			expectedUpdated:     true,
+		},
+		{
+			name: "test 2 - fingerprint is not overwritten",
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						NodeNotReadyFingerprintAnnotation: "Ready=False",
+					},
+				},
+				Status: corev1.NodeStatus{
+					Conditions: notReadyConditions,
+				},
+			},
+			notReadyTickCount:   7,
+			expectedFingerprint: "Ready=False",
+			expectedUpdated:     false,
+		},
+		{
+			name: "test 3 - fingerprint is kept while recovering",
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						NodeNotReadyFingerprintAnnotation: "Ready=False",
+					},
+				},
+			},
+			notReadyTickCount:   3,
+			expectedFingerprint: "Ready=False",
+			expectedUpdated:     false,
+		},
+		{
+			name: "test 4 - fingerprint is cleared on recovery",
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						NodeNotReadyFingerprintAnnotation: "Ready=False",
+					},
+				},
+			},
+			notReadyTickCount:   0,
+			expectedFingerprint: "",
+			expectedUpdated:     true,
+		},
+		{
+			name:                "test 5 - threshold reached but node currently healthy",
+			node:                corev1.Node{},
+			notReadyTickCount:   6,
+			expectedFingerprint: "",
+			expectedUpdated:     false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			updated := updateNodeFingerprint(&tc.node, testHealthCheck(), tc.notReadyTickCount, 6)
+			if updated != tc.expectedUpdated {
+				t.Fatalf("Expected updated '%t' but got '%t'.\n", tc.expectedUpdated, updated)
+			}
+
+			fingerprint := GetFingerprintAnnotation(tc.node)
+			if fingerprint != tc.expectedFingerprint {
+				t.Fatalf("Expected fingerprint '%s' but got '%s'.\n", tc.expectedFingerprint, fingerprint)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package detector
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/nodehealth"
+)
+
+const (
+	// nodeOutOfDisk was replaced by NodeDiskPressure in Kubernetes 1.9 and is not part of the api anymore.
+	nodeOutOfDisk corev1.NodeConditionType = "OutOfDisk"
+)
+
+var trueConditions = []string{
+	string(corev1.NodeReady),
+}
+var falseConditions = []string{
+	string(corev1.NodeDiskPressure),
+	// Custom conditionx generated by https://github.com/giantswarm/node-problem-detector-app
+	"DiskFullKubelet",
+	"DiskFullContainerd",
+	"DiskFullVarLog",
+}
+var legacyFalseConditions = []string{
+	string(nodeOutOfDisk),
+}
+
+// nodeHealthCheck evaluates the node conditions to decide if a node is unhealthy.
+type nodeHealthCheck struct {
+	clock Clock
+
+	// trueConditions have to be true, otherwise node has to be considered unhealthy.
+	trueConditions []string
+	// falseConditions have to be false, otherwise node has to be considered unhealthy.
+	falseConditions []string
+	// staleHeartbeatDuration defines how old the latest heartbeat of a node can be before the node is considered unhealthy.
+	// Disabled when zero.
+	staleHeartbeatDuration time.Duration
+	// readyUnknownDuration defines how long the status of the true conditions must be unknown before the node
+	// is considered unhealthy. The status is unknown when the node controller did not get a heartbeat
+	// within the node monitor grace period, ie: when the kubelet can not reach the api server.
+	readyUnknownDuration time.Duration
+	// diskFullDuration defines how long the false conditions, which all report a full disk, must be true
+	// before the node is considered unhealthy.
+	diskFullDuration time.Duration
+	// minAllocatableFraction defines per resource the fraction of the capacity the allocatable of a node must not
+	// drop below. Disabled when empty.
+	minAllocatableFraction map[corev1.ResourceName]float64
+	// allocatablePressureDuration defines how long the allocatable must be below the fraction
+	// before the node is considered unhealthy.
+	allocatablePressureDuration time.Duration
+	// predicate replaces the evaluation of trueConditions and falseConditions when set.
+	predicate Predicate
+	// externalHealth reports the health of a node from a source outside of the node conditions.
+	// Not consulted when nil.
+	externalHealth func(ctx context.Context, n corev1.Node) (bool, error)
+}
+
+func newNodeHealthCheck(clock Clock, legacyConditionSupport bool) nodeHealthCheck {
+	h := nodeHealthCheck{
+		clock: clock,
+
+		trueConditions:  trueConditions,
+		falseConditions: falseConditions,
+
+		readyUnknownDuration: nodeNotReadyDuration,
+		diskFullDuration:     nodeNotReadyDuration,
+	}
+
+	if legacyConditionSupport {
+		h.falseConditions = append(append([]string{}, falseConditions...), legacyFalseConditions...)
+	}
+
+	return h
+}
+
+// isNodeUnhealthy returns true of the node is not ready for certain period of time
+// this is used to detect bad nodes
+func (h nodeHealthCheck) isNodeUnhealthy(ctx context.Context, logger micrologger.Logger, n corev1.Node) bool {
+	if h.predicate != nil {
+		if h.predicate.Match(n, h.clock.Now()) {
+			logger.Debugf(ctx, "node %s is unhealthy because it matches the unhealthy predicate", n.Name)
+			return true
+		}
+		return h.isNodeHeartbeatStale(ctx, logger, n) || h.isNodeAllocatableCollapsed(ctx, logger, n)
+	}
+
+	conditions := h.unhealthyConditions(n)
+	for _, c := range conditions {
+		if c.Status == corev1.ConditionTrue {
+			logger.Debugf(ctx, "node %s is unhealthy because we expected condition %s to be false, but was true", n.Name, c.Type)
+		} else {
+			logger.Debugf(ctx, "node %s is unhealthy because we expected condition %s to be true, but was %s", n.Name, c.Type, c.Status)
+		}
+	}
+
+	if len(conditions) > 0 {
+		return true
+	}
+
+	return h.isNodeHeartbeatStale(ctx, logger, n) || h.isNodeAllocatableCollapsed(ctx, logger, n)
+}
+
+// isNodeHeartbeatStale returns true if the latest heartbeat of the node is older than the stale heartbeat duration.
+func (h nodeHealthCheck) isNodeHeartbeatStale(ctx context.Context, logger micrologger.Logger, n corev1.Node) bool {
+	if heartbeat, ok := h.staleHeartbeat(n); ok {
+		logger.Debugf(ctx, "node %s is unhealthy because the latest heartbeat at %s is older than %s", n.Name, heartbeat.Format(time.RFC3339), h.staleHeartbeatDuration)
+		return true
+	}
+
+	return false
+}
+
+// isNodeExternallyUnhealthy consults the external health hook and returns true if the node is unhealthy.
+// The second return value is false when the health is unknown, ie: when the hook failed.
+func (h nodeHealthCheck) isNodeExternallyUnhealthy(ctx context.Context, logger micrologger.Logger, n corev1.Node) (bool, bool) {
+	if h.externalHealth == nil {
+		return false, true
+	}
+
+	healthy, err := h.externalHealth(ctx, n)
+	if err != nil {
+		logger.Errorf(ctx, err, "failed to check the external health of node %s", n.Name)
+		return false, false
+	}
+
+	if !healthy {
+		logger.Debugf(ctx, "node %s is unhealthy because the external health check failed", n.Name)
+		return true, true
+	}
+
+	return false, true
+}
+
+// staleHeartbeat returns the latest heartbeat of all node conditions and true if it is older than the stale heartbeat duration.
+// Nodes without conditions are not considered stale as the kubelet did not report yet.
+func (h nodeHealthCheck) staleHeartbeat(n corev1.Node) (time.Time, bool) {
+	if h.staleHeartbeatDuration == 0 || len(n.Status.Conditions) == 0 {
+		return time.Time{}, false
+	}
+
+	var latest time.Time
+	for _, c := range n.Status.Conditions {
+		if c.LastHeartbeatTime.Time.After(latest) {
+			latest = c.LastHeartbeatTime.Time
+		}
+	}
+
+	return latest, h.clock.Now().Sub(latest) >= h.staleHeartbeatDuration
+}
+
+// hasHealthyConditionStatus returns true if all evaluated conditions of the node have the healthy status.
+// Conditions with an unhealthy status can make the node unhealthy later on without any change of the status,
+// once they are older than their duration.
+func (h nodeHealthCheck) hasHealthyConditionStatus(n corev1.Node) bool {
+	for _, trueCondition := range h.trueConditions {
+		c, ok := nodehealth.GetCondition(n, corev1.NodeConditionType(trueCondition))
+		if ok && c.Status != corev1.ConditionTrue {
+			return false
+		}
+	}
+
+	for _, falseCondition := range h.falseConditions {
+		c, ok := nodehealth.GetCondition(n, corev1.NodeConditionType(falseCondition))
+		if ok && c.Status == corev1.ConditionTrue {
+			return false
+		}
+	}
+	return true
+}
+
+// unhealthyConditions returns all conditions of the node which are in an unhealthy state for certain period of time.
+func (h nodeHealthCheck) unhealthyConditions(n corev1.Node) []corev1.NodeCondition {
+	var conditions []corev1.NodeCondition
+
+	for _, trueCondition := range h.trueConditions {
+		c, ok := nodehealth.GetCondition(n, corev1.NodeConditionType(trueCondition))
+		if ok && c.Status != corev1.ConditionTrue {
+			// We want condition to be true, but it's not.
+			duration := nodeNotReadyDuration
+			if c.Status == corev1.ConditionUnknown {
+				duration = h.readyUnknownDuration
+			}
+			if h.clock.Now().Sub(c.LastHeartbeatTime.Time) >= duration {
+				conditions = append(conditions, c)
+			}
+		}
+	}
+
+	for _, falseCondition := range h.falseConditions {
+		c, ok := nodehealth.GetCondition(n, corev1.NodeConditionType(falseCondition))
+		if ok && c.Status == corev1.ConditionTrue {
+			// we want condition to be false, but it's not.
+			if h.clock.Now().Sub(c.LastHeartbeatTime.Time) >= h.diskFullDuration {
+				conditions = append(conditions, c)
+			}
+		}
+	}
+	return conditions
+}
@@ -0,0 +1,394 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_nodeHealthCheck_diskConditions(t *testing.T) {
+	newNode := func(condition corev1.NodeConditionType, heartbeatAge time.Duration) corev1.Node {
+		return corev1.Node{
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{
+					{
+						Type:              corev1.NodeReady,
+						Status:            corev1.ConditionTrue,
+						LastHeartbeatTime: metav1.NewTime(testNow),
+					},
+					{
+						Type:              condition,
+						Status:            corev1.ConditionTrue,
+						LastHeartbeatTime: metav1.NewTime(testNow.Add(-heartbeatAge)),
+					},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                   string
+		node                   corev1.Node
+		legacyConditionSupport bool
+		expectedUnhealthy      bool
+	}{
+		{
+			name:                   "test 0 - disk pressure",
+			node:                   newNode(corev1.NodeDiskPressure, time.Minute*10),
+			legacyConditionSupport: true,
+			expectedUnhealthy:      true,
+		},
+		{
+			name:                   "test 1 - disk pressure without legacy condition support",
+			node:                   newNode(corev1.NodeDiskPressure, time.Minute*10),
+			legacyConditionSupport: false,
+			expectedUnhealthy:      true,
+		},
+		{
+			name:                   "test 2 - disk pressure for a short time",
+			node:                   newNode(corev1.NodeDiskPressure, time.Second*10),
+			legacyConditionSupport: true,
+			expectedUnhealthy:      false,
+		},
+		{
+			name:                   "test 3 - legacy out of disk",
+			node:                   newNode(nodeOutOfDisk, time.Minute*10),
+			legacyConditionSupport: true,
+			expectedUnhealthy:      true,
+		},
+		{
+			name:                   "test 4 - legacy out of disk without legacy condition support",
+			node:                   newNode(nodeOutOfDisk, time.Minute*10),
+			legacyConditionSupport: false,
+			expectedUnhealthy:      false,
+		},
+		{
+			name:                   "test 5 - legacy out of disk for a short time",
+			node:                   newNode(nodeOutOfDisk, time.Second*10),
+			legacyConditionSupport: true,
+			expectedUnhealthy:      false,
+		},
+		{
+			name:                   "test 6 - custom disk full condition",
+			node:                   newNode("DiskFullContainerd", time.Minute*10),
+			legacyConditionSupport: false,
+			expectedUnhealthy:      true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			h := newNodeHealthCheck(&FakeClock{Time: testNow}, tc.legacyConditionSupport)
+
+			result := h.isNodeUnhealthy(context.Background(), logger, tc.node)
+			if result != tc.expectedUnhealthy {
+				t.Fatalf("Expected '%t' but got '%t'.\n", tc.expectedUnhealthy, result)
+			}
+		})
+	}
+}
+
+func Test_nodeHealthCheck_staleHeartbeat(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		node                   corev1.Node
+		staleHeartbeatDuration time.Duration
+		expectedUnhealthy      bool
+	}{
+		{
+			name: "test 0 - ready with old heartbeat - stale heartbeat check disabled",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Hour).
+				Build(),
+			staleHeartbeatDuration: 0,
+			expectedUnhealthy:      false,
+		},
+		{
+			name: "test 1 - ready with old heartbeat",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Hour).
+				Build(),
+			staleHeartbeatDuration: time.Minute * 5,
+			expectedUnhealthy:      true,
+		},
+		{
+			name: "test 2 - ready with recent heartbeat",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute).
+				Build(),
+			staleHeartbeatDuration: time.Minute * 5,
+			expectedUnhealthy:      false,
+		},
+		{
+			name: "test 3 - latest heartbeat of all conditions is recent",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Hour).
+				WithCondition(corev1.NodeMemoryPressure, corev1.ConditionFalse, time.Minute).
+				Build(),
+			staleHeartbeatDuration: time.Minute * 5,
+			expectedUnhealthy:      false,
+		},
+		{
+			name: "test 4 - all conditions with old heartbeat",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Hour).
+				WithCondition(corev1.NodeMemoryPressure, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			staleHeartbeatDuration: time.Minute * 5,
+			expectedUnhealthy:      true,
+		},
+		{
+			name:                   "test 5 - node without conditions",
+			node:                   testNode("worker1").Build(),
+			staleHeartbeatDuration: time.Minute * 5,
+			expectedUnhealthy:      false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			h := testHealthCheck()
+			h.staleHeartbeatDuration = tc.staleHeartbeatDuration
+
+			result := h.isNodeUnhealthy(context.Background(), logger, tc.node)
+			if result != tc.expectedUnhealthy {
+				t.Fatalf("Expected '%t' but got '%t'.\n", tc.expectedUnhealthy, result)
+			}
+		})
+	}
+}
+
+func Test_nodeHealthCheck_readyUnknown(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		node                 corev1.Node
+		readyUnknownDuration time.Duration
+		expectedUnhealthy    bool
+	}{
+		{
+			name: "test 0 - ready unknown for a long time",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionUnknown, time.Minute*10).
+				Build(),
+			readyUnknownDuration: nodeNotReadyDuration,
+			expectedUnhealthy:    true,
+		},
+		{
+			name: "test 1 - ready unknown for a short time",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionUnknown, time.Second*10).
+				Build(),
+			readyUnknownDuration: nodeNotReadyDuration,
+			expectedUnhealthy:    false,
+		},
+		{
+			name: "test 2 - ready unknown within custom threshold",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionUnknown, time.Minute).
+				Build(),
+			readyUnknownDuration: time.Minute * 5,
+			expectedUnhealthy:    false,
+		},
+		{
+			name: "test 3 - ready unknown exceeding custom threshold",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionUnknown, time.Minute*5).
+				Build(),
+			readyUnknownDuration: time.Minute * 5,
+			expectedUnhealthy:    true,
+		},
+		{
+			name: "test 4 - ready false is not affected by custom threshold",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute).
+				Build(),
+			readyUnknownDuration: time.Minute * 5,
+			expectedUnhealthy:    true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			h := testHealthCheck()
+			h.readyUnknownDuration = tc.readyUnknownDuration
+
+			result := h.isNodeUnhealthy(context.Background(), logger, tc.node)
+			if result != tc.expectedUnhealthy {
+				t.Fatalf("Expected '%t' but got '%t'.\n", tc.expectedUnhealthy, result)
+			}
+		})
+	}
+}
+
+func Test_nodeHealthCheck_diskFullDuration(t *testing.T) {
+	const diskFullCondition corev1.NodeConditionType = "DiskFullKubelet"
+
+	testCases := []struct {
+		name              string
+		node              corev1.Node
+		diskFullDuration  time.Duration
+		expectedUnhealthy bool
+	}{
+		{
+			name: "test 0 - disk full for a long time",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition(diskFullCondition, corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			diskFullDuration:  nodeNotReadyDuration,
+			expectedUnhealthy: true,
+		},
+		{
+			name: "test 1 - disk full within custom duration",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition(diskFullCondition, corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			diskFullDuration:  time.Minute * 15,
+			expectedUnhealthy: false,
+		},
+		{
+			name: "test 2 - disk full exceeding custom duration",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition(diskFullCondition, corev1.ConditionTrue, time.Minute*15).
+				Build(),
+			diskFullDuration:  time.Minute * 15,
+			expectedUnhealthy: true,
+		},
+		{
+			name: "test 3 - disk pressure exceeding short custom duration",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionTrue, time.Second*10).
+				Build(),
+			diskFullDuration:  time.Second * 5,
+			expectedUnhealthy: true,
+		},
+		{
+			name: "test 4 - ready false is not affected by custom duration",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute).
+				Build(),
+			diskFullDuration:  time.Minute * 15,
+			expectedUnhealthy: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			h := testHealthCheck()
+			h.diskFullDuration = tc.diskFullDuration
+
+			result := h.isNodeUnhealthy(context.Background(), logger, tc.node)
+			if result != tc.expectedUnhealthy {
+				t.Fatalf("Expected '%t' but got '%t'.\n", tc.expectedUnhealthy, result)
+			}
+		})
+	}
+}
+
+func Test_nodeNotReadyTickCount_externalHealth(t *testing.T) {
+	testCases := []struct {
+		name            string
+		node            corev1.Node
+		externalHealthy bool
+		externalErr     error
+		expectedTick    int
+		expectedUpdated bool
+	}{
+		{
+			name: "test 0 - externally unhealthy node increases the tick count",
+			node: testNode("worker1").
+				WithAnnotation(annotationNodeNotReadyTick, "2").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			externalHealthy: false,
+			expectedTick:    3,
+			expectedUpdated: true,
+		},
+		{
+			name: "test 1 - externally healthy node decreases the tick count",
+			node: testNode("worker1").
+				WithAnnotation(annotationNodeNotReadyTick, "2").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			externalHealthy: true,
+			expectedTick:    1,
+			expectedUpdated: true,
+		},
+		{
+			name: "test 2 - external error keeps the tick count",
+			node: testNode("worker1").
+				WithAnnotation(annotationNodeNotReadyTick, "2").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			externalErr:     errors.New("monitoring api unavailable"),
+			expectedTick:    2,
+			expectedUpdated: false,
+		},
+		{
+			name: "test 3 - external error does not hide unhealthy conditions",
+			node: testNode("worker1").
+				WithAnnotation(annotationNodeNotReadyTick, "2").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			externalErr:     errors.New("monitoring api unavailable"),
+			expectedTick:    3,
+			expectedUpdated: true,
+		},
+		{
+			name: "test 4 - externally healthy node with unhealthy conditions increases the tick count",
+			node: testNode("worker1").
+				WithAnnotation(annotationNodeNotReadyTick, "2").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			externalHealthy: true,
+			expectedTick:    3,
+			expectedUpdated: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			h := testHealthCheck()
+			h.externalHealth = func(ctx context.Context, n corev1.Node) (bool, error) {
+				return tc.externalHealthy, tc.externalErr
+			}
+
+			tick, updated := nodeNotReadyTickCount(context.Background(), logger, h, tc.node, annotationNodeNotReadyTick, false)
+			if tick != tc.expectedTick {
+				t.Fatalf("Expected '%d' tick count but got '%d'.\n", tc.expectedTick, tick)
+			}
+			if updated != tc.expectedUpdated {
+				t.Fatalf("Expected updated '%t' but got '%t'.\n", tc.expectedUpdated, updated)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package detector
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ClusterHealthScore returns the health of the whole cluster between 0 and 1, computed as
+// `1 - average tick count / effective tick threshold`. A score of 1 means all nodes are healthy,
+// 0 means all nodes are at or above the tick threshold. An empty cluster has a score of 1.
+// DetectBadNodes publishes the score of every run via Metrics.SetClusterHealthScore.
+func (d *Detector) ClusterHealthScore(ctx context.Context) (float64, error) {
+	h, err := d.healthScores(ctx)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	return h.clusterScore(d.effectiveThreshold(h.nodeCount)), nil
+}
+
+// WorstNodeHealthScore returns the health of the worst node between 0 and 1, computed as
+// `1 - highest tick count / effective tick threshold`. An empty cluster has a score of 1.
+func (d *Detector) WorstNodeHealthScore(ctx context.Context) (float64, error) {
+	h, err := d.healthScores(ctx)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	return h.worstNodeScore(d.effectiveThreshold(h.nodeCount)), nil
+}
+
+// healthScores accumulates the tick counts of all nodes, including the nodes excluded by the node filters.
+func (d *Detector) healthScores(ctx context.Context) (healthScoreAccumulator, error) {
+	var h healthScoreAccumulator
+	err := d.forEachNodePage(ctx, func(nodes []corev1.Node) error {
+		for _, n := range nodes {
+			h.add(nodeTickCount(n, d.tickAnnotationKey))
+		}
+		return nil
+	})
+	if err != nil {
+		return healthScoreAccumulator{}, microerror.Mask(err)
+	}
+
+	return h, nil
+}
+
+// healthScoreAccumulator sums up the tick counts of the nodes to compute the health scores.
+type healthScoreAccumulator struct {
+	nodeCount int
+	tickSum   int
+	maxTick   int
+}
+
+func (h *healthScoreAccumulator) add(tick int) {
+	h.nodeCount++
+	h.tickSum += tick
+	if tick > h.maxTick {
+		h.maxTick = tick
+	}
+}
+
+// clusterScore returns the cluster health score for the given tick threshold.
+func (h healthScoreAccumulator) clusterScore(threshold int) float64 {
+	if h.nodeCount == 0 {
+		return 1
+	}
+	return healthScore(float64(h.tickSum) / float64(h.nodeCount) / float64(threshold))
+}
+
+// worstNodeScore returns the health score of the worst node for the given tick threshold.
+func (h healthScoreAccumulator) worstNodeScore(threshold int) float64 {
+	if h.nodeCount == 0 {
+		return 1
+	}
+	return healthScore(float64(h.maxTick) / float64(threshold))
+}
+
+// healthScore returns 1 - ratio clamped to [0, 1].
+func healthScore(ratio float64) float64 {
+	score := 1 - ratio
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
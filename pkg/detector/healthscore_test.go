@@ -0,0 +1,139 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ClusterHealthScore(t *testing.T) {
+	testCases := []struct {
+		name                         string
+		tickCounts                   []int
+		expectedClusterHealthScore   float64
+		expectedWorstNodeHealthScore float64
+	}{
+		{
+			name:                         "test 0 - empty cluster",
+			tickCounts:                   nil,
+			expectedClusterHealthScore:   1,
+			expectedWorstNodeHealthScore: 1,
+		},
+		{
+			name:                         "test 1 - all nodes healthy",
+			tickCounts:                   []int{0, 0, 0},
+			expectedClusterHealthScore:   1,
+			expectedWorstNodeHealthScore: 1,
+		},
+		{
+			name:                         "test 2 - all nodes at threshold",
+			tickCounts:                   []int{6, 6, 6},
+			expectedClusterHealthScore:   0,
+			expectedWorstNodeHealthScore: 0,
+		},
+		{
+			name:                         "test 3 - nodes above threshold are clamped",
+			tickCounts:                   []int{12, 9},
+			expectedClusterHealthScore:   0,
+			expectedWorstNodeHealthScore: 0,
+		},
+		{
+			name:                         "test 4 - some nodes not ready",
+			tickCounts:                   []int{0, 0, 3, 3},
+			expectedClusterHealthScore:   0.75,
+			expectedWorstNodeHealthScore: 0.5,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var objects []client.Object
+			for j, tick := range tc.tickCounts {
+				node := testNode(fmt.Sprintf("worker%d", j)).
+					WithAnnotation(annotationNodeNotReadyTick, strconv.Itoa(tick)).
+					WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Duration(0)).
+					Build()
+				objects = append(objects, &node)
+			}
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:     &FakeClock{Time: testNow},
+				Logger:    logger,
+				K8sClient: fake.NewClientBuilder().WithObjects(objects...).Build(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			score, err := d.ClusterHealthScore(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if score != tc.expectedClusterHealthScore {
+				t.Fatalf("Expected cluster health score '%f' but got '%f'.\n", tc.expectedClusterHealthScore, score)
+			}
+
+			score, err = d.WorstNodeHealthScore(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if score != tc.expectedWorstNodeHealthScore {
+				t.Fatalf("Expected worst node health score '%f' but got '%f'.\n", tc.expectedWorstNodeHealthScore, score)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_clusterHealthScore(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	unhealthy := testNode("worker1").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+	healthy := testNode("worker2").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Duration(0)).
+		Build()
+	excluded := testNode("worker3").
+		WithLabel("example.com/ignore", "true").
+		WithAnnotation(annotationNodeNotReadyTick, "3").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+
+	metrics := &testMetrics{apiCalls: map[string]int{}}
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    fake.NewClientBuilder().WithObjects(&unhealthy, &healthy, &excluded).Build(),
+		MaxNodeTerminationPercentage: 1,
+		NodeFilters: []NodeFilter{
+			ExcludeLabelFilter("example.com/ignore", ""),
+		},
+		Metrics: metrics,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the updated tick count 6 of worker1 and the tick count 3 of the excluded worker3 average to half the threshold
+	if len(metrics.healthScores) != 1 || metrics.healthScores[0] != 0.5 {
+		t.Fatalf("Expected cluster health score '%f' but got '%v'.\n", 0.5, metrics.healthScores)
+	}
+}
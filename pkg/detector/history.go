@@ -0,0 +1,167 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// terminationHistoryTimeFormat is the ISO 8601 basic format of RFC3339 timestamps
+	// as ConfigMap keys must not contain colons.
+	terminationHistoryTimeFormat = "20060102T150405Z"
+)
+
+// TerminationHistoryStore persists the number of nodes 'marked for termination' per termination history key,
+// so the termination limits are enforced across restarts and multiple replicas.
+type TerminationHistoryStore interface {
+	// Counts returns the current counts without modifying them.
+	Counts(ctx context.Context) (map[string]int, error)
+	// Update calls fn with the current counts and persists the counts modified by fn.
+	Update(ctx context.Context, fn func(counts map[string]int)) error
+}
+
+// ConfigMapTerminationHistoryStore is a TerminationHistoryStore persisting the counts in the data of a ConfigMap.
+type ConfigMapTerminationHistoryStore struct {
+	k8sClient client.Client
+	namespace string
+	name      string
+
+	mutex sync.Mutex
+}
+
+// NewConfigMapTerminationHistoryStore returns a store using the ConfigMap with the given name,
+// the ConfigMap is created if it does not exist.
+func NewConfigMapTerminationHistoryStore(k8sClient client.Client, namespace string, name string) *ConfigMapTerminationHistoryStore {
+	return &ConfigMapTerminationHistoryStore{
+		k8sClient: k8sClient,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Counts reads the counts from the ConfigMap. A missing ConfigMap returns no counts and invalid counts are dropped.
+func (s *ConfigMapTerminationHistoryStore) Counts(ctx context.Context) (map[string]int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var configMap corev1.ConfigMap
+	err := s.k8sClient.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, &configMap)
+	if apierrors.IsNotFound(err) {
+		return map[string]int{}, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return parseTerminationHistoryCounts(configMap.Data), nil
+}
+
+// Update reads the counts from the ConfigMap, calls fn and writes the modified counts back.
+// Invalid counts in the ConfigMap are dropped. fn is called again when another replica changed the counts in the meantime.
+func (s *ConfigMapTerminationHistoryStore) Update(ctx context.Context, fn func(counts map[string]int)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := updateConfigMap(ctx, s.k8sClient, s.namespace, s.name, func(data map[string]string) (map[string]string, error) {
+		counts := parseTerminationHistoryCounts(data)
+
+		fn(counts)
+
+		data = map[string]string{}
+		for k, v := range counts {
+			data[k] = strconv.Itoa(v)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// parseTerminationHistoryCounts returns the counts of the ConfigMap data, invalid counts are dropped.
+func parseTerminationHistoryCounts(data map[string]string) map[string]int {
+	counts := map[string]int{}
+	for k, v := range data {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		counts[k] = count
+	}
+	return counts
+}
+
+// limitTerminationHistory removes the bad nodes of pools which reached the maximum number of terminations
+// in the current termination history window and records the remaining nodes in the termination history.
+// Entries of previous windows are expired with the next recorded termination.
+func (d *Detector) limitTerminationHistory(ctx context.Context, badNodes []corev1.Node, now time.Time) ([]corev1.Node, error) {
+	// nothing to record, the termination history is not read nor written
+	if len(badNodes) == 0 {
+		return badNodes, nil
+	}
+
+	windowStart := now.UTC().Truncate(d.terminationHistoryWindow)
+
+	var allowedNodes []corev1.Node
+	err := d.terminationHistory.Update(ctx, func(counts map[string]int) {
+		allowedNodes = nil
+
+		for key := range counts {
+			t, ok := terminationHistoryKeyTime(key)
+			if !ok || t.Before(windowStart) {
+				delete(counts, key)
+			}
+		}
+
+		for _, n := range badNodes {
+			key := terminationHistoryKey(n.Labels[d.nodePoolLabel], windowStart)
+			if d.maxTerminationsPerPool > 0 && counts[key] >= d.maxTerminationsPerPool {
+				continue
+			}
+			counts[key]++
+			allowedNodes = append(allowedNodes, n)
+		}
+	})
+	if err != nil {
+		return nil, microerror.Maskf(terminationHistoryError, "%s", err.Error())
+	}
+
+	return allowedNodes, nil
+}
+
+// terminationHistoryKey returns the key `<pool>-<window start>` of the termination history.
+func terminationHistoryKey(pool string, windowStart time.Time) string {
+	return pool + "-" + windowStart.Format(terminationHistoryTimeFormat)
+}
+
+// terminationHistoryKeyTime returns the window start of the termination history key.
+func terminationHistoryKeyTime(key string) (time.Time, bool) {
+	i := strings.LastIndex(key, "-")
+	if i < 0 {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(terminationHistoryTimeFormat, key[i+1:])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// terminationHistoryKeyPool returns the pool of the termination history key.
+func terminationHistoryKeyPool(key string) string {
+	i := strings.LastIndex(key, "-")
+	if i < 0 {
+		return key
+	}
+	return key[:i]
+}
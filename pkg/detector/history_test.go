@@ -0,0 +1,253 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeTerminationHistoryStore keeps the termination history in memory.
+type fakeTerminationHistoryStore struct {
+	counts      map[string]int
+	updateError error
+	updateCalls int
+}
+
+func (s *fakeTerminationHistoryStore) Counts(ctx context.Context) (map[string]int, error) {
+	if s.updateError != nil {
+		return nil, s.updateError
+	}
+	counts := map[string]int{}
+	for k, v := range s.counts {
+		counts[k] = v
+	}
+	return counts, nil
+}
+
+func (s *fakeTerminationHistoryStore) Update(ctx context.Context, fn func(counts map[string]int)) error {
+	s.updateCalls++
+	if s.updateError != nil {
+		return s.updateError
+	}
+	fn(s.counts)
+	return nil
+}
+
+func Test_terminationHistoryKey(t *testing.T) {
+	windowStart := testNow.Truncate(time.Hour)
+
+	key := terminationHistoryKey("pool-a", windowStart)
+	if key != "pool-a-20231109T120000Z" {
+		t.Fatalf("Expected key 'pool-a-20231109T120000Z' but got '%s'.\n", key)
+	}
+
+	keyTime, ok := terminationHistoryKeyTime(key)
+	if !ok || !keyTime.Equal(windowStart) {
+		t.Fatalf("Expected window start '%s' but got '%s'.\n", windowStart, keyTime)
+	}
+
+	_, ok = terminationHistoryKeyTime("pool-a-garbage")
+	if ok {
+		t.Fatalf("Expected invalid key to not be parsed.\n")
+	}
+}
+
+func Test_DetectBadNodes_terminationHistory(t *testing.T) {
+	currentWindow := testNow.Truncate(time.Hour)
+	previousWindow := currentWindow.Add(-time.Hour)
+
+	newNode := func(name string, pool string) client.Object {
+		node := testNode(name).
+			WithLabel(testPoolLabel, pool).
+			WithAnnotation(annotationNodeNotReadyTick, "5").
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+			Build()
+		return &node
+	}
+
+	testCases := []struct {
+		name             string
+		history          map[string]string
+		expectedBadNodes []string
+		expectedHistory  map[string]string
+	}{
+		{
+			name:             "test 0 - empty history",
+			history:          nil,
+			expectedBadNodes: []string{"a1", "b1"},
+			expectedHistory: map[string]string{
+				terminationHistoryKey("a", currentWindow): "1",
+				terminationHistoryKey("b", currentWindow): "1",
+			},
+		},
+		{
+			name: "test 1 - pool a reached the limit in the current window",
+			history: map[string]string{
+				terminationHistoryKey("a", currentWindow): "1",
+			},
+			expectedBadNodes: []string{"b1"},
+			expectedHistory: map[string]string{
+				terminationHistoryKey("a", currentWindow): "1",
+				terminationHistoryKey("b", currentWindow): "1",
+			},
+		},
+		{
+			name: "test 2 - previous window is expired",
+			history: map[string]string{
+				terminationHistoryKey("a", previousWindow): "1",
+				terminationHistoryKey("b", previousWindow): "1",
+				"garbage": "1",
+			},
+			expectedBadNodes: []string{"a1", "b1"},
+			expectedHistory: map[string]string{
+				terminationHistoryKey("a", currentWindow): "1",
+				terminationHistoryKey("b", currentWindow): "1",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			objects := []client.Object{
+				newNode("a1", "a"),
+				newNode("a2", "a"),
+				newNode("b1", "b"),
+			}
+			if tc.history != nil {
+				objects = append(objects, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: defaultTerminationHistoryNamespace,
+						Name:      "termination-history",
+					},
+					Data: tc.history,
+				})
+			}
+			k8sClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+				NodePoolLabel:                testPoolLabel,
+				TerminationHistoryConfigMap:  "termination-history",
+				MaxTerminationsPerPool:       1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, n := range badNodes {
+				names = append(names, n.Name)
+			}
+			sort.Strings(names)
+			// pool a has two bad nodes, which of them is kept depends on the list order
+			if len(names) > 0 && names[0] == "a2" {
+				names[0] = "a1"
+			}
+
+			if !cmp.Equal(names, tc.expectedBadNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedBadNodes, names))
+			}
+
+			var configMap corev1.ConfigMap
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Namespace: defaultTerminationHistoryNamespace, Name: "termination-history"}, &configMap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(configMap.Data, tc.expectedHistory) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedHistory, configMap.Data))
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_terminationHistoryStore(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	node := testNode("worker1").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+
+	store := &fakeTerminationHistoryStore{counts: map[string]int{}}
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    fake.NewClientBuilder().WithObjects(&node).Build(),
+		MaxNodeTerminationPercentage: 1,
+		TerminationHistoryStore:      store,
+		MaxTerminationsPerPool:       1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the limit is reached by the first run and enforced by the second run
+	for i, expectedBadNodes := range []int{1, 0} {
+		badNodes, err := d.DetectBadNodes(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(badNodes) != expectedBadNodes {
+			t.Fatalf("Expected '%d' bad nodes in run %d but got '%d'.\n", expectedBadNodes, i, len(badNodes))
+		}
+	}
+
+	store.updateError = errors.New("connection refused")
+	_, err = d.DetectBadNodes(context.Background())
+	if !IsTerminationHistory(err) {
+		t.Fatalf("error == %#v, want matching", err)
+	}
+}
+
+func Test_DetectBadNodes_terminationHistoryWithoutBadNodes(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	node := testNode("worker1").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+		Build()
+
+	store := &fakeTerminationHistoryStore{counts: map[string]int{}}
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    fake.NewClientBuilder().WithObjects(&node).Build(),
+		MaxNodeTerminationPercentage: 1,
+		TerminationHistoryStore:      store,
+		MaxTerminationsPerPool:       1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.updateCalls != 0 {
+		t.Fatalf("Expected no termination history update but got '%d'.\n", store.updateCalls)
+	}
+}
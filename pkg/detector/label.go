@@ -0,0 +1,27 @@
+package detector
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+const badNodeLabelValue = "true"
+
+// setBadNodeLabel sets the label on nodes 'marked for termination' and removes it from all other nodes.
+// The returned value indicates if the labels of the node changed and need to be updated.
+func setBadNodeLabel(n *corev1.Node, label string, bad bool) bool {
+	_, labeled := n.Labels[label]
+	if bad == labeled {
+		return false
+	}
+
+	if bad {
+		if n.Labels == nil {
+			n.Labels = map[string]string{}
+		}
+		n.Labels[label] = badNodeLabelValue
+	} else {
+		delete(n.Labels, label)
+	}
+
+	return true
+}
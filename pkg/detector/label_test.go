@@ -0,0 +1,97 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodes_badNodeLabel(t *testing.T) {
+	const badNodeLabel = "example.com/bad-node"
+
+	node := testNode("worker1").
+		WithRole(labelNodeRoleWorker).
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+
+	logger, _ := micrologger.New(micrologger.Config{})
+	k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+	metrics := &testMetrics{apiCalls: map[string]int{}}
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    k8sClient,
+		MaxNodeTerminationPercentage: 1,
+		BadNodeLabel:                 badNodeLabel,
+		Metrics:                      metrics,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getNode := func() corev1.Node {
+		var n corev1.Node
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// the node reaches the threshold and is labeled
+	badNodes, err := d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(badNodes) != 1 {
+		t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", 1, len(badNodes))
+	}
+	if v := getNode().Labels[badNodeLabel]; v != "true" {
+		t.Fatalf("Expected label value '%s' but got '%s'.\n", "true", v)
+	}
+	// the label is written with the same update as the tick count
+	if metrics.apiCalls[apiCallUpdate] != 1 {
+		t.Fatalf("Expected '%d' updates but got '%d'.\n", 1, metrics.apiCalls[apiCallUpdate])
+	}
+
+	// the node recovers and the label is removed
+	recovered := getNode()
+	recovered.Status.Conditions = testNode("worker1").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+		Build().Status.Conditions
+	err = k8sClient.Update(context.Background(), &recovered)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badNodes, err = d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(badNodes) != 0 {
+		t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", 0, len(badNodes))
+	}
+	if v, ok := getNode().Labels[badNodeLabel]; ok {
+		t.Fatalf("Expected label to be removed but got '%s'.\n", v)
+	}
+}
+
+func Test_NewDetector_badNodeLabel(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	_, err := NewDetector(Config{
+		Logger:       logger,
+		K8sClient:    fake.NewClientBuilder().Build(),
+		BadNodeLabel: "invalid label",
+	})
+	if !IsInvalidConfig(err) {
+		t.Fatalf("Expected invalid config error but got '%v'.\n", err)
+	}
+}
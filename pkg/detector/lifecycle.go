@@ -0,0 +1,75 @@
+package detector
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodeLifecycleState describes in which phase of its lifecycle a node is.
+// New nodes have a different expected health trajectory than nodes running for a long time.
+type NodeLifecycleState string
+
+const (
+	// NodeLifecycleStateProvisioning is the state of nodes younger than NewNodeGracePeriod.
+	NodeLifecycleStateProvisioning NodeLifecycleState = "Provisioning"
+	// NodeLifecycleStateEstablished is the state of nodes older than EstablishedNodeAge.
+	NodeLifecycleStateEstablished NodeLifecycleState = "Established"
+	// NodeLifecycleStateUnknown is the state of nodes in between and of nodes without creation timestamp.
+	NodeLifecycleStateUnknown NodeLifecycleState = "Unknown"
+)
+
+// nodeLifecycleState returns the lifecycle state of the node based on its age.
+func nodeLifecycleState(n corev1.Node, now time.Time, newNodeGracePeriod time.Duration, establishedNodeAge time.Duration) NodeLifecycleState {
+	if n.CreationTimestamp.IsZero() {
+		return NodeLifecycleStateUnknown
+	}
+
+	age := now.Sub(n.CreationTimestamp.Time)
+	switch {
+	case age < newNodeGracePeriod:
+		return NodeLifecycleStateProvisioning
+	case age >= establishedNodeAge:
+		return NodeLifecycleStateEstablished
+	default:
+		return NodeLifecycleStateUnknown
+	}
+}
+
+// isNodeNeverReady returns true if the node did not become Ready within the timeout after its creation.
+// The Ready condition transitions once the node becomes Ready, so a not Ready condition which did not
+// transition since the node was created means the node has never been Ready.
+func isNodeNeverReady(n corev1.Node, now time.Time, timeout time.Duration) bool {
+	if n.CreationTimestamp.IsZero() {
+		return false
+	}
+	readyBy := n.CreationTimestamp.Add(timeout)
+	if now.Before(readyBy) {
+		return false
+	}
+
+	for _, c := range n.Status.Conditions {
+		if c.Type != corev1.NodeReady {
+			continue
+		}
+		if c.Status == corev1.ConditionTrue {
+			return false
+		}
+		return c.LastTransitionTime.Time.Before(readyBy)
+	}
+
+	// nodes without Ready condition never reported their status
+	return true
+}
+
+// lifecycleStateTerminationOrder defines which nodes are preferred for termination, lower values first.
+func lifecycleStateTerminationOrder(state NodeLifecycleState) int {
+	switch state {
+	case NodeLifecycleStateEstablished:
+		return 0
+	case NodeLifecycleStateProvisioning:
+		return 2
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,126 @@
+package detector
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_nodeLifecycleState(t *testing.T) {
+	testCases := []struct {
+		name          string
+		node          corev1.Node
+		expectedState NodeLifecycleState
+	}{
+		{
+			name:          "test 0 - new node is provisioning",
+			node:          testNode("worker1").WithCreationTime(testNow.Add(-time.Minute * 5)).Build(),
+			expectedState: NodeLifecycleStateProvisioning,
+		},
+		{
+			name:          "test 1 - node older than the grace period",
+			node:          testNode("worker1").WithCreationTime(testNow.Add(-defaultNewNodeGracePeriod)).Build(),
+			expectedState: NodeLifecycleStateUnknown,
+		},
+		{
+			name:          "test 2 - node in between",
+			node:          testNode("worker1").WithCreationTime(testNow.Add(-time.Hour * 6)).Build(),
+			expectedState: NodeLifecycleStateUnknown,
+		},
+		{
+			name:          "test 3 - node exactly at the established age",
+			node:          testNode("worker1").WithCreationTime(testNow.Add(-defaultEstablishedNodeAge)).Build(),
+			expectedState: NodeLifecycleStateEstablished,
+		},
+		{
+			name:          "test 4 - old node is established",
+			node:          testNode("worker1").WithCreationTime(testNow.Add(-time.Hour * 24 * 30)).Build(),
+			expectedState: NodeLifecycleStateEstablished,
+		},
+		{
+			name:          "test 5 - node without creation timestamp",
+			node:          testNode("worker1").Build(),
+			expectedState: NodeLifecycleStateUnknown,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			state := nodeLifecycleState(tc.node, testNow, defaultNewNodeGracePeriod, defaultEstablishedNodeAge)
+			if state != tc.expectedState {
+				t.Fatalf("Expected lifecycle state '%s' but got '%s'.\n", tc.expectedState, state)
+			}
+		})
+	}
+}
+
+func Test_isNodeNeverReady(t *testing.T) {
+	timeout := time.Minute * 15
+
+	newNode := func(age time.Duration, status corev1.ConditionStatus, transitionAge time.Duration) corev1.Node {
+		node := testNode("worker1").
+			WithCreationTime(testNow.Add(-age)).
+			WithCondition(corev1.NodeReady, status, time.Minute).
+			Build()
+		node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(testNow.Add(-transitionAge))
+		return node
+	}
+
+	testCases := []struct {
+		name          string
+		node          corev1.Node
+		expectedValue bool
+	}{
+		{
+			name:          "test 0 - node not ready since creation past the timeout",
+			node:          newNode(time.Minute*20, corev1.ConditionFalse, time.Minute*20),
+			expectedValue: true,
+		},
+		{
+			name:          "test 1 - node not ready since creation before the timeout",
+			node:          newNode(time.Minute*10, corev1.ConditionFalse, time.Minute*10),
+			expectedValue: false,
+		},
+		{
+			name:          "test 2 - node with unknown readiness since creation past the timeout",
+			node:          newNode(time.Minute*20, corev1.ConditionUnknown, time.Minute*20),
+			expectedValue: true,
+		},
+		{
+			name:          "test 3 - node became not ready after the timeout",
+			node:          newNode(time.Hour, corev1.ConditionFalse, time.Minute*10),
+			expectedValue: false,
+		},
+		{
+			name:          "test 4 - ready node",
+			node:          newNode(time.Minute*20, corev1.ConditionTrue, time.Minute*20),
+			expectedValue: false,
+		},
+		{
+			name:          "test 5 - node without ready condition past the timeout",
+			node:          testNode("worker1").WithCreationTime(testNow.Add(-time.Minute * 20)).Build(),
+			expectedValue: true,
+		},
+		{
+			name:          "test 6 - node without creation timestamp",
+			node:          testNode("worker1").WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute).Build(),
+			expectedValue: false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			value := isNodeNeverReady(tc.node, testNow, timeout)
+			if value != tc.expectedValue {
+				t.Fatalf("Expected never ready '%t' but got '%t'.\n", tc.expectedValue, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package detector
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// nodeLifetimeTickCount returns the cumulative tick count of the node, missing or invalid values count as 0.
+func nodeLifetimeTickCount(n corev1.Node) int {
+	return nodeTickCount(n, NodeLifetimeTickAnnotation)
+}
+
+// increaseLifetimeTickCount increases the cumulative tick count of the node by one.
+func increaseLifetimeTickCount(n *corev1.Node) {
+	setAnnotation(n, NodeLifetimeTickAnnotation, fmt.Sprintf("%d", nodeLifetimeTickCount(*n)+1))
+}
@@ -0,0 +1,130 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodes_lifetimeTickThreshold(t *testing.T) {
+	testCases := []struct {
+		name                      string
+		tick                      string
+		lifetimeTick              string
+		status                    corev1.ConditionStatus
+		lifetimeTickThreshold     int
+		expectedBadNode           bool
+		expectedTickCount         string
+		expectedLifetimeTickCount string
+	}{
+		{
+			name:                      "test 0 - low tick count but high lifetime tick count",
+			tick:                      "1",
+			lifetimeTick:              "29",
+			status:                    corev1.ConditionFalse,
+			lifetimeTickThreshold:     30,
+			expectedBadNode:           true,
+			expectedTickCount:         "2",
+			expectedLifetimeTickCount: "30",
+		},
+		{
+			name:                      "test 1 - lifetime tick count below the threshold",
+			tick:                      "1",
+			lifetimeTick:              "10",
+			status:                    corev1.ConditionFalse,
+			lifetimeTickThreshold:     30,
+			expectedBadNode:           false,
+			expectedTickCount:         "2",
+			expectedLifetimeTickCount: "11",
+		},
+		{
+			name:                      "test 2 - recovering node keeps its lifetime tick count",
+			tick:                      "3",
+			lifetimeTick:              "10",
+			status:                    corev1.ConditionTrue,
+			lifetimeTickThreshold:     30,
+			expectedBadNode:           false,
+			expectedTickCount:         "2",
+			expectedLifetimeTickCount: "10",
+		},
+		{
+			name:                      "test 3 - healthy node with lifetime tick count above the threshold",
+			tick:                      "0",
+			lifetimeTick:              "30",
+			status:                    corev1.ConditionTrue,
+			lifetimeTickThreshold:     30,
+			expectedBadNode:           true,
+			expectedTickCount:         "0",
+			expectedLifetimeTickCount: "30",
+		},
+		{
+			name:                      "test 4 - lifetime tick count is not tracked when disabled",
+			tick:                      "1",
+			lifetimeTick:              "",
+			status:                    corev1.ConditionFalse,
+			lifetimeTickThreshold:     0,
+			expectedBadNode:           false,
+			expectedTickCount:         "2",
+			expectedLifetimeTickCount: "",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			b := testNode("worker1").
+				WithRole(labelNodeRoleWorker).
+				WithAnnotation(annotationNodeNotReadyTick, tc.tick).
+				WithCondition(corev1.NodeReady, tc.status, time.Minute*10)
+			if tc.lifetimeTick != "" {
+				b.WithAnnotation(NodeLifetimeTickAnnotation, tc.lifetimeTick)
+			}
+			node := b.Build()
+
+			logger, _ := micrologger.New(micrologger.Config{})
+			k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+				LifetimeTickThreshold:        tc.lifetimeTickThreshold,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := d.DetectBadNodesWithResult(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (len(result.BadNodes) == 1) != tc.expectedBadNode {
+				t.Fatalf("Expected bad node '%t' but got '%d' bad nodes.\n", tc.expectedBadNode, len(result.BadNodes))
+			}
+			if tc.expectedBadNode && result.BadNodes[0].Reason != BadNodeReasonLifetimeTickThreshold {
+				t.Fatalf("Expected reason '%s' but got '%s'.\n", BadNodeReasonLifetimeTickThreshold, result.BadNodes[0].Reason)
+			}
+
+			var updated corev1.Node
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &updated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Annotations[annotationNodeNotReadyTick] != tc.expectedTickCount {
+				t.Fatalf("Expected tick count '%s' but got '%s'.\n", tc.expectedTickCount, updated.Annotations[annotationNodeNotReadyTick])
+			}
+			if updated.Annotations[NodeLifetimeTickAnnotation] != tc.expectedLifetimeTickCount {
+				t.Fatalf("Expected lifetime tick count '%s' but got '%s'.\n", tc.expectedLifetimeTickCount, updated.Annotations[NodeLifetimeTickAnnotation])
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package detector
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+)
+
+// limiter blocks until the next request is allowed, it is implemented by rate.Limiter.
+type limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// waitForUpdate blocks until the update limiter allows the next node update.
+func (d *Detector) waitForUpdate(ctx context.Context) error {
+	if d.updateLimiter == nil {
+		return nil
+	}
+
+	err := d.updateLimiter.Wait(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testLimiter records the Wait calls into the shared call log.
+type testLimiter struct {
+	calls *[]string
+	err   error
+}
+
+func (l *testLimiter) Wait(ctx context.Context) error {
+	*l.calls = append(*l.calls, "wait")
+	return l.err
+}
+
+// recordingClient records the Update calls into the shared call log.
+type recordingClient struct {
+	client.Client
+
+	calls *[]string
+}
+
+func (c *recordingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	*c.calls = append(*c.calls, "update")
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func Test_NewDetector_updateLimiter(t *testing.T) {
+	testCases := []struct {
+		name            string
+		updateRateLimit float64
+		updateBurst     int
+		expectLimiter   bool
+		errorMatcher    func(error) bool
+	}{
+		{
+			name:            "test 0 - rate limiting disabled by default",
+			updateRateLimit: 0,
+			expectLimiter:   false,
+		},
+		{
+			name:            "test 1 - rate limiting enabled",
+			updateRateLimit: 5,
+			updateBurst:     2,
+			expectLimiter:   true,
+		},
+		{
+			name:            "test 2 - rate limiting enabled with default burst",
+			updateRateLimit: 5,
+			expectLimiter:   true,
+		},
+		{
+			name:            "test 3 - negative rate limit",
+			updateRateLimit: -1,
+			errorMatcher:    IsInvalidConfig,
+		},
+		{
+			name:            "test 4 - negative burst",
+			updateRateLimit: 5,
+			updateBurst:     -1,
+			errorMatcher:    IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Logger:          logger,
+				K8sClient:       fake.NewClientBuilder().Build(),
+				UpdateRateLimit: rate.Limit(tc.updateRateLimit),
+				UpdateBurst:     tc.updateBurst,
+			})
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if (d.updateLimiter != nil) != tc.expectLimiter {
+				t.Fatalf("Expected limiter '%t' but got '%t'.\n", tc.expectLimiter, d.updateLimiter != nil)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_updateLimiter(t *testing.T) {
+	testCases := []struct {
+		name          string
+		limiterErr    error
+		expectedCalls []string
+		expectErr     bool
+	}{
+		{
+			name:          "test 0 - wait before every node update",
+			expectedCalls: []string{"wait", "update", "wait", "update", "wait", "update"},
+		},
+		{
+			name:          "test 1 - no update when wait fails",
+			limiterErr:    context.DeadlineExceeded,
+			expectedCalls: []string{"wait"},
+			expectErr:     true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			var objects []client.Object
+			for j := 0; j < 3; j++ {
+				node := testNode(fmt.Sprintf("worker%d", j)).
+					WithRole(labelNodeRoleWorker).
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build()
+				objects = append(objects, &node)
+			}
+
+			var calls []string
+			d, err := NewDetector(Config{
+				Clock:     &FakeClock{Time: testNow},
+				Logger:    logger,
+				K8sClient: &recordingClient{Client: fake.NewClientBuilder().WithObjects(objects...).Build(), calls: &calls},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.updateLimiter = &testLimiter{calls: &calls, err: tc.limiterErr}
+
+			_, err = d.DetectBadNodes(context.Background())
+			if tc.expectErr && !errors.Is(err, tc.limiterErr) {
+				t.Fatalf("error == %#v, want %#v", err, tc.limiterErr)
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(tc.expectedCalls, calls) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedCalls, calls))
+			}
+		})
+	}
+}
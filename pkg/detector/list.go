@@ -0,0 +1,138 @@
+package detector
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// listNodesRetryBackoff defines the retries of failed node list requests in degraded mode.
+var listNodesRetryBackoff = wait.Backoff{
+	Steps:    3,
+	Duration: time.Millisecond * 100,
+	Factor:   2,
+}
+
+// forEachNodePage lists the nodes page by page and calls fn for each page,
+// so only a single page of nodes is kept in memory at once.
+// Without a configured page size all nodes are passed in a single page.
+// The pages are not filtered, use handledNodes to drop the nodes the detector does not handle.
+func (d *Detector) forEachNodePage(ctx context.Context, fn func(nodes []corev1.Node) error) error {
+	return d.forEachNodeList(ctx, func(nodeList corev1.NodeList) error {
+		return fn(nodeList.Items)
+	})
+}
+
+// forEachNodeList works like forEachNodePage but passes the list metadata of each page as well.
+func (d *Detector) forEachNodeList(ctx context.Context, fn func(nodeList corev1.NodeList) error) error {
+	continueToken := ""
+	for {
+		opts := []client.ListOption{d.nodeSelector}
+		if d.listPageSize > 0 {
+			opts = append(opts, client.Limit(d.listPageSize), client.Continue(continueToken))
+		}
+
+		var nodeList corev1.NodeList
+		err := d.listNodes(ctx, &nodeList, opts)
+		if err != nil {
+			return microerror.Maskf(listNodesError, "%s", err.Error())
+		}
+
+		err = fn(nodeList)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		continueToken = nodeList.Continue
+		if continueToken == "" {
+			return nil
+		}
+	}
+}
+
+// listNodes lists a single page of nodes. In degraded mode failed requests are retried a few times
+// before the result of the last successful run is returned.
+func (d *Detector) listNodes(ctx context.Context, nodeList *corev1.NodeList, opts []client.ListOption) error {
+	if d.degradedMode == nil {
+		return d.k8sClient.List(ctx, nodeList, opts...)
+	}
+
+	retriable := func(error) bool {
+		return ctx.Err() == nil
+	}
+	return retry.OnError(listNodesRetryBackoff, retriable, func() error {
+		return d.k8sClient.List(ctx, nodeList, opts...)
+	})
+}
+
+// estimateNodeCount returns the number of nodes of the whole list from its first page, so the node count
+// is known before the remaining pages are listed. The api server does not report the remaining items
+// for lists filtered by labels, the node count of the previous run is used in this case.
+// It returns false if the node count can not be known from the first page.
+func (d *Detector) estimateNodeCount(firstPage corev1.NodeList) (int, bool) {
+	count := len(firstPage.Items)
+	if firstPage.Continue == "" {
+		return count, true
+	}
+	if firstPage.RemainingItemCount != nil {
+		return count + int(*firstPage.RemainingItemCount), true
+	}
+	if previous := d.previousNodeCount(); previous > count {
+		return previous, true
+	}
+	return 0, false
+}
+
+// countNodes returns the number of nodes selected by the node selector, including the nodes excluded by the node filters.
+func (d *Detector) countNodes(ctx context.Context) (int, error) {
+	count := 0
+	err := d.forEachNodePage(ctx, func(nodes []corev1.Node) error {
+		count += len(nodes)
+		return nil
+	})
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	return count, nil
+}
+
+// handledNodes returns the nodes handled by the detector,
+// nodes excluded by the node filters and malformed nodes are removed.
+func (d *Detector) handledNodes(ctx context.Context, nodes []corev1.Node) []corev1.Node {
+	return d.nodeFilters.Filter(d.skipInvalidNodes(ctx, nodes))
+}
+
+// skipInvalidNodes removes malformed nodes from the list and logs them,
+// so a single node which was not fully decoded does not fail the whole run.
+func (d *Detector) skipInvalidNodes(ctx context.Context, nodes []corev1.Node) []corev1.Node {
+	var validNodes []corev1.Node
+	for _, n := range nodes {
+		err := validateNode(n)
+		if err != nil {
+			d.logger.Errorf(ctx, err, "skipping malformed node")
+			continue
+		}
+		validNodes = append(validNodes, n)
+	}
+	return validNodes
+}
+
+// validateNode returns an error if the node is missing the fields required to process it.
+func validateNode(n corev1.Node) error {
+	if n.Name == "" {
+		return microerror.Maskf(invalidNodeError, "node must have a name")
+	}
+	for i, c := range n.Status.Conditions {
+		if c.Type == "" {
+			return microerror.Maskf(invalidNodeError, "condition %d of node %s must have a type", i, n.Name)
+		}
+	}
+
+	return nil
+}
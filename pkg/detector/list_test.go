@@ -0,0 +1,404 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// pagingClient wraps a client and returns node lists in pages as the api server does when a limit is set.
+type pagingClient struct {
+	client.Client
+
+	listCalls int
+}
+
+func (c *pagingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	nodeList, ok := list.(*corev1.NodeList)
+	if !ok {
+		return c.Client.List(ctx, list, opts...)
+	}
+	c.listCalls++
+
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	err := c.Client.List(ctx, nodeList, &client.ListOptions{LabelSelector: listOpts.LabelSelector})
+	if err != nil {
+		return err
+	}
+	if listOpts.Limit == 0 {
+		return nil
+	}
+
+	sort.Slice(nodeList.Items, func(i, j int) bool {
+		return nodeList.Items[i].Name < nodeList.Items[j].Name
+	})
+
+	offset := 0
+	if listOpts.Continue != "" {
+		offset, err = strconv.Atoi(listOpts.Continue)
+		if err != nil {
+			return err
+		}
+	}
+	end := offset + int(listOpts.Limit)
+	if end >= len(nodeList.Items) {
+		end = len(nodeList.Items)
+		nodeList.Continue = ""
+	} else {
+		nodeList.Continue = strconv.Itoa(end)
+	}
+	nodeList.Items = nodeList.Items[offset:end]
+
+	return nil
+}
+
+func Test_DetectBadNodes_listPages(t *testing.T) {
+	testCases := []struct {
+		name              string
+		nodeCount         int
+		badNodeCount      int
+		listPageSize      int64
+		dynamicThreshold  bool
+		expectedListCalls int
+		expectedBadNodes  int
+	}{
+		{
+			name:              "test 0 - no pagination",
+			nodeCount:         10,
+			badNodeCount:      3,
+			listPageSize:      0,
+			expectedListCalls: 1,
+			expectedBadNodes:  3,
+		},
+		{
+			name:              "test 1 - multiple pages",
+			nodeCount:         10,
+			badNodeCount:      3,
+			listPageSize:      3,
+			expectedListCalls: 4,
+			expectedBadNodes:  3,
+		},
+		{
+			name:              "test 2 - exactly one page",
+			nodeCount:         10,
+			badNodeCount:      3,
+			listPageSize:      10,
+			expectedListCalls: 1,
+			expectedBadNodes:  3,
+		},
+		{
+			name:              "test 3 - termination limit applies to all pages",
+			nodeCount:         20,
+			badNodeCount:      15,
+			listPageSize:      7,
+			expectedListCalls: 3,
+			expectedBadNodes:  10,
+		},
+		{
+			name:              "test 4 - dynamic threshold does not list the nodes twice",
+			nodeCount:         10,
+			badNodeCount:      3,
+			listPageSize:      3,
+			dynamicThreshold:  true,
+			expectedListCalls: 4,
+			expectedBadNodes:  3,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var objects []client.Object
+			for j := 0; j < tc.nodeCount; j++ {
+				status := corev1.ConditionTrue
+				if j < tc.badNodeCount {
+					status = corev1.ConditionFalse
+				}
+
+				objects = append(objects, &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: fmt.Sprintf("worker%02d", j),
+						Annotations: map[string]string{
+							annotationNodeNotReadyTick: "5",
+						},
+					},
+					Status: corev1.NodeStatus{
+						Conditions: []corev1.NodeCondition{
+							{
+								Type:              corev1.NodeReady,
+								Status:            status,
+								LastHeartbeatTime: metav1.NewTime(testNow.Add(-time.Minute * 10)),
+							},
+						},
+					},
+				})
+			}
+
+			k8sClient := &pagingClient{
+				Client: fake.NewClientBuilder().WithObjects(objects...).Build(),
+			}
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 0.5,
+				ListPageSize:                 tc.listPageSize,
+				DynamicThreshold:             tc.dynamicThreshold,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if k8sClient.listCalls != tc.expectedListCalls {
+				t.Fatalf("Expected '%d' list calls but got '%d'.\n", tc.expectedListCalls, k8sClient.listCalls)
+			}
+			if len(badNodes) != tc.expectedBadNodes {
+				t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", tc.expectedBadNodes, len(badNodes))
+			}
+		})
+	}
+}
+
+func Test_estimateNodeCount(t *testing.T) {
+	remaining := int64(7)
+
+	testCases := []struct {
+		name              string
+		firstPage         corev1.NodeList
+		previousNodeCount int
+		expectedNodeCount int
+		expectedKnown     bool
+	}{
+		{
+			name: "test 0 - single page",
+			firstPage: corev1.NodeList{
+				Items: make([]corev1.Node, 3),
+			},
+			previousNodeCount: 10,
+			expectedNodeCount: 3,
+			expectedKnown:     true,
+		},
+		{
+			name: "test 1 - remaining item count reported",
+			firstPage: corev1.NodeList{
+				ListMeta: metav1.ListMeta{Continue: "3", RemainingItemCount: &remaining},
+				Items:    make([]corev1.Node, 3),
+			},
+			previousNodeCount: 20,
+			expectedNodeCount: 10,
+			expectedKnown:     true,
+		},
+		{
+			name: "test 2 - remaining item count not reported",
+			firstPage: corev1.NodeList{
+				ListMeta: metav1.ListMeta{Continue: "3"},
+				Items:    make([]corev1.Node, 3),
+			},
+			previousNodeCount: 20,
+			expectedNodeCount: 20,
+			expectedKnown:     true,
+		},
+		{
+			name: "test 3 - remaining item count not reported without previous run",
+			firstPage: corev1.NodeList{
+				ListMeta: metav1.ListMeta{Continue: "3"},
+				Items:    make([]corev1.Node, 3),
+			},
+			previousNodeCount: 0,
+			expectedNodeCount: 0,
+			expectedKnown:     false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:     &FakeClock{Time: testNow},
+				Logger:    logger,
+				K8sClient: fake.NewClientBuilder().Build(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.setNodeCount(tc.previousNodeCount)
+
+			nodeCount, known := d.estimateNodeCount(tc.firstPage)
+			if known != tc.expectedKnown {
+				t.Fatalf("Expected known '%t' but got '%t'.\n", tc.expectedKnown, known)
+			}
+			if nodeCount != tc.expectedNodeCount {
+				t.Fatalf("Expected node count '%d' but got '%d'.\n", tc.expectedNodeCount, nodeCount)
+			}
+		})
+	}
+}
+
+// malformedItemClient wraps a client and adds the given nodes to every node list,
+// as if they were returned by the api server but not fully decoded.
+type malformedItemClient struct {
+	client.Client
+
+	items []corev1.Node
+}
+
+func (c *malformedItemClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	err := c.Client.List(ctx, list, opts...)
+	if err != nil {
+		return err
+	}
+
+	nodeList, ok := list.(*corev1.NodeList)
+	if ok {
+		nodeList.Items = append(nodeList.Items, c.items...)
+	}
+	return nil
+}
+
+func Test_DetectBadNodes_malformedNodes(t *testing.T) {
+	testCases := []struct {
+		name             string
+		malformedNodes   []corev1.Node
+		expectedBadNodes int
+	}{
+		{
+			name:             "test 0 - no malformed nodes",
+			malformedNodes:   nil,
+			expectedBadNodes: 2,
+		},
+		{
+			name: "test 1 - node without name",
+			malformedNodes: []corev1.Node{
+				testNode("").
+					WithAnnotation(annotationNodeNotReadyTick, "5").
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build(),
+			},
+			expectedBadNodes: 2,
+		},
+		{
+			name: "test 2 - node with condition without type",
+			malformedNodes: []corev1.Node{
+				testNode("worker9").
+					WithAnnotation(annotationNodeNotReadyTick, "5").
+					WithCondition("", corev1.ConditionFalse, time.Minute*10).
+					Build(),
+			},
+			expectedBadNodes: 2,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var objects []client.Object
+			for j := 0; j < 4; j++ {
+				tick := "0"
+				if j < 2 {
+					tick = "5"
+				}
+				node := testNode(fmt.Sprintf("worker%d", j)).
+					WithRole(labelNodeRoleWorker).
+					WithAnnotation(annotationNodeNotReadyTick, tick).
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build()
+				objects = append(objects, &node)
+			}
+
+			k8sClient := &malformedItemClient{
+				Client: fake.NewClientBuilder().WithObjects(objects...).Build(),
+				items:  tc.malformedNodes,
+			}
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(badNodes) != tc.expectedBadNodes {
+				t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", tc.expectedBadNodes, len(badNodes))
+			}
+		})
+	}
+}
+
+func Test_validateNode(t *testing.T) {
+	testCases := []struct {
+		name         string
+		node         corev1.Node
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "test 0 - valid node",
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute).
+				Build(),
+		},
+		{
+			name:         "test 1 - node without name",
+			node:         testNode("").Build(),
+			errorMatcher: IsInvalidNode,
+		},
+		{
+			name: "test 2 - condition without type",
+			node: testNode("worker1").
+				WithCondition("", corev1.ConditionTrue, time.Minute).
+				Build(),
+			errorMatcher: IsInvalidNode,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			err := validateNode(tc.node)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
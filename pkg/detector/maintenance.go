@@ -0,0 +1,111 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxSkipReasonLength is the maximum length of the reason passed to DisableNode.
+	maxSkipReasonLength = 256
+
+	eventReasonNodeDetectionDisabled = "NodeDetectionDisabled"
+	eventReasonNodeDetectionEnabled  = "NodeDetectionEnabled"
+	eventSourceComponent             = "badnodedetector"
+)
+
+// DisableNode excludes the node from the detection until EnableNode is called, ie: during a manual maintenance.
+// It sets the NodeSkipAnnotation and NodeSkipReasonAnnotation and records a NodeDetectionDisabled event for the node.
+// The reason must not be longer than 256 characters.
+func (d *Detector) DisableNode(ctx context.Context, nodeName string, reason string) error {
+	if len(reason) > maxSkipReasonLength {
+		return microerror.Maskf(invalidArgumentError, "reason must not be longer than %d characters", maxSkipReasonLength)
+	}
+
+	err := d.patchNodeAnnotations(ctx, nodeName, func(n *corev1.Node) {
+		setAnnotation(n, NodeSkipAnnotation, "true")
+		setAnnotation(n, NodeSkipReasonAnnotation, reason)
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	d.logger.Debugf(ctx, "disabled detection for node %s: %s", nodeName, reason)
+	d.recordNodeEvent(ctx, nodeName, eventReasonNodeDetectionDisabled, fmt.Sprintf("Bad node detection disabled: %s", reason))
+
+	return nil
+}
+
+// EnableNode includes a node excluded by DisableNode in the detection again.
+// It removes the NodeSkipAnnotation and NodeSkipReasonAnnotation and records a NodeDetectionEnabled event for the node.
+func (d *Detector) EnableNode(ctx context.Context, nodeName string) error {
+	err := d.patchNodeAnnotations(ctx, nodeName, func(n *corev1.Node) {
+		delete(n.Annotations, NodeSkipAnnotation)
+		delete(n.Annotations, NodeSkipReasonAnnotation)
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	d.logger.Debugf(ctx, "enabled detection for node %s", nodeName)
+	d.recordNodeEvent(ctx, nodeName, eventReasonNodeDetectionEnabled, "Bad node detection enabled")
+
+	return nil
+}
+
+// patchNodeAnnotations fetches the node, applies fn to it and patches the changed annotations.
+func (d *Detector) patchNodeAnnotations(ctx context.Context, nodeName string, fn func(n *corev1.Node)) error {
+	var node corev1.Node
+	err := d.k8sClient.Get(ctx, client.ObjectKey{Name: nodeName}, &node)
+	if apierrors.IsNotFound(err) {
+		return microerror.Maskf(nodeNotFoundError, "node %s does not exist", nodeName)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	fn(&node)
+
+	err = d.k8sClient.Patch(ctx, &node, patch)
+	if err != nil {
+		return microerror.Maskf(nodeUpdateError, "failed to patch node %s: %s", nodeName, err.Error())
+	}
+
+	return nil
+}
+
+// recordNodeEvent creates a Kubernetes event for the node. Failures are logged only,
+// as the event is informational and the node was already changed.
+func (d *Detector) recordNodeEvent(ctx context.Context, nodeName string, reason string, message string) {
+	now := metav1.NewTime(d.clock.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: nodeName + ".",
+			// events of cluster scoped objects are stored in the default namespace
+			Namespace: metav1.NamespaceDefault,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:       "Node",
+			APIVersion: "v1",
+			Name:       nodeName,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: eventSourceComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	err := d.k8sClient.Create(ctx, event)
+	if err != nil {
+		d.logger.Errorf(ctx, err, "failed to record event %s for node %s", reason, nodeName)
+	}
+}
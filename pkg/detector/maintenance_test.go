@@ -0,0 +1,202 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DisableNode(t *testing.T) {
+	testCases := []struct {
+		name           string
+		nodeName       string
+		reason         string
+		expectedEvents int
+		errorMatcher   func(error) bool
+	}{
+		{
+			name:           "test 0 - disable node",
+			nodeName:       "worker1",
+			reason:         "replacing the disk",
+			expectedEvents: 1,
+		},
+		{
+			name:         "test 1 - node does not exist",
+			nodeName:     "worker2",
+			reason:       "replacing the disk",
+			errorMatcher: IsNodeNotFound,
+		},
+		{
+			name:         "test 2 - reason too long",
+			nodeName:     "worker1",
+			reason:       strings.Repeat("a", maxSkipReasonLength+1),
+			errorMatcher: IsInvalidArgument,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			node := testNode("worker1").Build()
+			k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+
+			d, err := NewDetector(Config{
+				Clock:     &FakeClock{Time: testNow},
+				Logger:    logger,
+				K8sClient: k8sClient,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = d.DisableNode(context.Background(), tc.nodeName, tc.reason)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			var events corev1.EventList
+			err = k8sClient.List(context.Background(), &events)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events.Items) != tc.expectedEvents {
+				t.Fatalf("Expected '%d' events but got '%d'.\n", tc.expectedEvents, len(events.Items))
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if events.Items[0].Reason != eventReasonNodeDetectionDisabled || events.Items[0].InvolvedObject.Name != tc.nodeName {
+				t.Fatalf("Expected event '%s' for node '%s' but got '%s' for node '%s'.\n", eventReasonNodeDetectionDisabled, tc.nodeName, events.Items[0].Reason, events.Items[0].InvolvedObject.Name)
+			}
+
+			var updated corev1.Node
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: tc.nodeName}, &updated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Annotations[NodeSkipAnnotation] != "true" {
+				t.Fatalf("Expected skip annotation 'true' but got '%s'.\n", updated.Annotations[NodeSkipAnnotation])
+			}
+			if updated.Annotations[NodeSkipReasonAnnotation] != tc.reason {
+				t.Fatalf("Expected skip reason '%s' but got '%s'.\n", tc.reason, updated.Annotations[NodeSkipReasonAnnotation])
+			}
+		})
+	}
+}
+
+func Test_EnableNode(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	node := testNode("worker1").
+		WithAnnotation(NodeSkipAnnotation, "true").
+		WithAnnotation(NodeSkipReasonAnnotation, "replacing the disk").
+		WithAnnotation(annotationNodeNotReadyTick, "2").
+		Build()
+	k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+
+	d, err := NewDetector(Config{
+		Clock:     &FakeClock{Time: testNow},
+		Logger:    logger,
+		K8sClient: k8sClient,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.EnableNode(context.Background(), "worker1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var updated corev1.Node
+	err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{NodeSkipAnnotation, NodeSkipReasonAnnotation} {
+		if _, ok := updated.Annotations[key]; ok {
+			t.Fatalf("Expected annotation '%s' to be removed.\n", key)
+		}
+	}
+	// other annotations are kept
+	if updated.Annotations[annotationNodeNotReadyTick] != "2" {
+		t.Fatalf("Expected tick count '2' but got '%s'.\n", updated.Annotations[annotationNodeNotReadyTick])
+	}
+
+	var events corev1.EventList
+	err = k8sClient.List(context.Background(), &events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Reason != eventReasonNodeDetectionEnabled {
+		t.Fatalf("Expected a single '%s' event but got %v.\n", eventReasonNodeDetectionEnabled, events.Items)
+	}
+
+	err = d.EnableNode(context.Background(), "worker2")
+	if !IsNodeNotFound(err) {
+		t.Fatalf("error == %#v, want matching", err)
+	}
+}
+
+func Test_DetectBadNodes_disabledNode(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	disabledNode := testNode("worker1").
+		WithAnnotation(NodeSkipAnnotation, "true").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+	badNode := testNode("worker2").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+	k8sClient := fake.NewClientBuilder().WithObjects(&disabledNode, &badNode).Build()
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    k8sClient,
+		MaxNodeTerminationPercentage: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badNodes, err := d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(badNodes) != 1 || badNodes[0].Name != "worker2" {
+		t.Fatalf("Expected only node 'worker2' but got %d nodes.\n", len(badNodes))
+	}
+
+	// the tick count of the disabled node is not touched
+	var updated corev1.Node
+	err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Annotations[annotationNodeNotReadyTick] != "5" {
+		t.Fatalf("Expected tick count '5' but got '%s'.\n", updated.Annotations[annotationNodeNotReadyTick])
+	}
+}
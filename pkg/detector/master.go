@@ -0,0 +1,52 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// effectiveMaxMasterTerminations returns how many master nodes can be 'marked for termination' at single run.
+// When the master count ConfigMap carries the ExpectedMasterCountAnnotation, the limit is derived from the
+// expected master count, otherwise the configured MaxMasterTerminations is used.
+func (d *Detector) effectiveMaxMasterTerminations(ctx context.Context, logger micrologger.Logger) (int, error) {
+	if d.masterCountConfigMap == "" {
+		return d.maxMasterTerminations, nil
+	}
+
+	var configMap corev1.ConfigMap
+	err := d.k8sClient.Get(ctx, client.ObjectKey{Namespace: d.masterCountNamespace, Name: d.masterCountConfigMap}, &configMap)
+	if apierrors.IsNotFound(err) {
+		return d.maxMasterTerminations, nil
+	} else if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	value, ok := configMap.Annotations[ExpectedMasterCountAnnotation]
+	if !ok {
+		return d.maxMasterTerminations, nil
+	}
+	expectedMasterCount, err := strconv.Atoi(value)
+	if err != nil || expectedMasterCount < 1 {
+		logger.Debugf(ctx, "ignoring invalid expected master count %#q of ConfigMap %s/%s", value, d.masterCountNamespace, d.masterCountConfigMap)
+		return d.maxMasterTerminations, nil
+	}
+
+	return safeMaxMasterTerminations(expectedMasterCount), nil
+}
+
+// safeMaxMasterTerminations returns how many master nodes can be terminated at once
+// without risking the etcd quorum of a cluster with the given number of master nodes.
+// At least one master node can always be terminated.
+func safeMaxMasterTerminations(expectedMasterCount int) int {
+	maxMasterTerminations := expectedMasterCount/2 - 1
+	if maxMasterTerminations < 1 {
+		maxMasterTerminations = 1
+	}
+	return maxMasterTerminations
+}
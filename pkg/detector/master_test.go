@@ -0,0 +1,150 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_safeMaxMasterTerminations(t *testing.T) {
+	testCases := []struct {
+		name                          string
+		expectedMasterCount           int
+		expectedMaxMasterTerminations int
+	}{
+		{
+			name:                          "test 0 - single master",
+			expectedMasterCount:           1,
+			expectedMaxMasterTerminations: 1,
+		},
+		{
+			name:                          "test 1 - 3 masters",
+			expectedMasterCount:           3,
+			expectedMaxMasterTerminations: 1,
+		},
+		{
+			name:                          "test 2 - 5 masters",
+			expectedMasterCount:           5,
+			expectedMaxMasterTerminations: 1,
+		},
+		{
+			name:                          "test 3 - 7 masters",
+			expectedMasterCount:           7,
+			expectedMaxMasterTerminations: 2,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result := safeMaxMasterTerminations(tc.expectedMasterCount)
+			if result != tc.expectedMaxMasterTerminations {
+				t.Fatalf("Expected '%d' master terminations but got '%d'.\n", tc.expectedMaxMasterTerminations, result)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_expectedMasterCount(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		masterCount           int
+		annotation            string
+		maxMasterTerminations int
+		expectedBadMasters    int
+	}{
+		{
+			name:               "test 0 - no ConfigMap",
+			masterCount:        7,
+			expectedBadMasters: 1,
+		},
+		{
+			name:                  "test 1 - no ConfigMap with configured limit",
+			masterCount:           7,
+			maxMasterTerminations: 3,
+			expectedBadMasters:    3,
+		},
+		{
+			name:               "test 2 - 3 expected masters",
+			masterCount:        3,
+			annotation:         "3",
+			expectedBadMasters: 1,
+		},
+		{
+			name:               "test 3 - 5 expected masters",
+			masterCount:        5,
+			annotation:         "5",
+			expectedBadMasters: 1,
+		},
+		{
+			name:                  "test 4 - 7 expected masters override the configured limit",
+			masterCount:           7,
+			annotation:            "7",
+			maxMasterTerminations: 3,
+			expectedBadMasters:    2,
+		},
+		{
+			name:                  "test 5 - invalid annotation",
+			masterCount:           7,
+			annotation:            "seven",
+			maxMasterTerminations: 3,
+			expectedBadMasters:    3,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			var objects []client.Object
+			for j := 0; j < tc.masterCount; j++ {
+				node := testNode(fmt.Sprintf("master%d", j)).
+					WithRole(labelNodeRoleMaster).
+					WithAnnotation(annotationNodeNotReadyTick, "5").
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build()
+				objects = append(objects, &node)
+			}
+			if tc.annotation != "" {
+				objects = append(objects, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:   defaultMasterCountNamespace,
+						Name:        "cluster-info",
+						Annotations: map[string]string{ExpectedMasterCountAnnotation: tc.annotation},
+					},
+				})
+			}
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    fake.NewClientBuilder().WithObjects(objects...).Build(),
+				MaxNodeTerminationPercentage: 1,
+				MaxMasterTerminations:        tc.maxMasterTerminations,
+				MasterCountConfigMap:         "cluster-info",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(badNodes) != tc.expectedBadMasters {
+				t.Fatalf("Expected '%d' bad master nodes but got '%d'.\n", tc.expectedBadMasters, len(badNodes))
+			}
+		})
+	}
+}
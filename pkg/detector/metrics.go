@@ -0,0 +1,87 @@
+package detector
+
+import (
+	"context"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// BadNodesDetectedMetricName is the name of the Prometheus counter exposing the number of nodes
+	// returned as 'marked for termination', see Metrics.AddBadNodesDetected.
+	BadNodesDetectedMetricName = "badnodedetector_bad_nodes_detected_total"
+	// NodeNotReadyTickCountMetricName is the name of the Prometheus gauge exposing the tick count per node
+	// with the label `node`, see Metrics.SetNodeNotReadyTickCounts.
+	NodeNotReadyTickCountMetricName = "badnodedetector_node_not_ready_tick_count"
+	// ClusterHealthScoreMetricName is the name of the Prometheus gauge exposing the ClusterHealthScore,
+	// see Metrics.SetClusterHealthScore.
+	ClusterHealthScoreMetricName = "badnodedetector_cluster_health_score"
+)
+
+const (
+	apiCallCreate = "create"
+	apiCallGet    = "get"
+	apiCallList   = "list"
+	apiCallUpdate = "update"
+	apiCallPatch  = "patch"
+)
+
+// Metrics receives measurements of the detector, ie: to expose them as Prometheus metrics.
+type Metrics interface {
+	// ObserveDetectionDuration is called with the duration of every DetectBadNodes run, ie: to observe a histogram.
+	ObserveDetectionDuration(duration time.Duration)
+	// IncAPICalls is called for every request the detector sends to the Kubernetes API,
+	// the operation is one of `create`, `get`, `list`, `update` or `patch`.
+	IncAPICalls(operation string)
+	// AddBadNodesDetected is called with the number of nodes returned as 'marked for termination' by every
+	// DetectBadNodes run, ie: to increase a counter named BadNodesDetectedMetricName.
+	AddBadNodesDetected(count int)
+	// SetNodeNotReadyTickCounts is called after every successful DetectBadNodes run with the tick count by node name
+	// of all nodes handled by the detector, ie: to replace the values of a gauge named NodeNotReadyTickCountMetricName.
+	// Nodes missing in tickCounts are gone or not handled anymore.
+	SetNodeNotReadyTickCounts(tickCounts map[string]int)
+	// SetClusterHealthScore is called after every successful DetectBadNodes run with the ClusterHealthScore
+	// of all nodes, ie: to set a gauge named ClusterHealthScoreMetricName.
+	SetClusterHealthScore(score float64)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveDetectionDuration(time.Duration)   {}
+func (noopMetrics) IncAPICalls(string)                       {}
+func (noopMetrics) AddBadNodesDetected(int)                  {}
+func (noopMetrics) SetNodeNotReadyTickCounts(map[string]int) {}
+func (noopMetrics) SetClusterHealthScore(float64)            {}
+
+// metricsClient counts the requests sent to the Kubernetes API.
+type metricsClient struct {
+	client.Client
+
+	metrics Metrics
+}
+
+func (c metricsClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.metrics.IncAPICalls(apiCallCreate)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c metricsClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	c.metrics.IncAPICalls(apiCallGet)
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c metricsClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	c.metrics.IncAPICalls(apiCallList)
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c metricsClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.metrics.IncAPICalls(apiCallUpdate)
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c metricsClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.metrics.IncAPICalls(apiCallPatch)
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
@@ -0,0 +1,196 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type testMetrics struct {
+	durations        []time.Duration
+	apiCalls         map[string]int
+	badNodesDetected int
+	tickCounts       map[string]int
+	healthScores     []float64
+}
+
+func (m *testMetrics) ObserveDetectionDuration(duration time.Duration) {
+	m.durations = append(m.durations, duration)
+}
+
+func (m *testMetrics) IncAPICalls(operation string) {
+	m.apiCalls[operation]++
+}
+
+func (m *testMetrics) AddBadNodesDetected(count int) {
+	m.badNodesDetected += count
+}
+
+func (m *testMetrics) SetNodeNotReadyTickCounts(tickCounts map[string]int) {
+	m.tickCounts = tickCounts
+}
+
+func (m *testMetrics) SetClusterHealthScore(score float64) {
+	m.healthScores = append(m.healthScores, score)
+}
+
+func Test_DetectBadNodes_metrics(t *testing.T) {
+	testCases := []struct {
+		name             string
+		readyNodes       int
+		notReadyNodes    int
+		listPageSize     int64
+		runs             int
+		expectedAPICalls map[string]int
+	}{
+		{
+			name:          "test 0 - ready nodes are not updated",
+			readyNodes:    3,
+			notReadyNodes: 0,
+			runs:          1,
+			expectedAPICalls: map[string]int{
+				apiCallList: 1,
+			},
+		},
+		{
+			name:          "test 1 - not ready nodes are updated",
+			readyNodes:    3,
+			notReadyNodes: 2,
+			runs:          1,
+			expectedAPICalls: map[string]int{
+				apiCallList:   1,
+				apiCallUpdate: 2,
+			},
+		},
+		{
+			name:          "test 2 - multiple runs",
+			readyNodes:    1,
+			notReadyNodes: 2,
+			runs:          3,
+			expectedAPICalls: map[string]int{
+				apiCallList:   3,
+				apiCallUpdate: 6,
+			},
+		},
+		{
+			name:          "test 3 - paged list",
+			readyNodes:    2,
+			notReadyNodes: 3,
+			listPageSize:  2,
+			runs:          1,
+			expectedAPICalls: map[string]int{
+				apiCallList:   3,
+				apiCallUpdate: 3,
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			var objects []client.Object
+			for j := 0; j < tc.readyNodes+tc.notReadyNodes; j++ {
+				status := corev1.ConditionTrue
+				heartbeatAge := time.Duration(0)
+				if j >= tc.readyNodes {
+					status = corev1.ConditionFalse
+					heartbeatAge = time.Minute * 10
+				}
+				node := testNode(fmt.Sprintf("worker%d", j)).
+					WithCondition(corev1.NodeReady, status, heartbeatAge).
+					Build()
+				objects = append(objects, &node)
+			}
+
+			metrics := &testMetrics{apiCalls: map[string]int{}}
+
+			d, err := NewDetector(Config{
+				Clock:        &FakeClock{Time: testNow},
+				Logger:       logger,
+				K8sClient:    &pagingClient{Client: fake.NewClientBuilder().WithObjects(objects...).Build()},
+				ListPageSize: tc.listPageSize,
+				Metrics:      metrics,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for j := 0; j < tc.runs; j++ {
+				_, err = d.DetectBadNodes(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(metrics.durations) != tc.runs {
+				t.Fatalf("Expected '%d' observed durations but got '%d'.\n", tc.runs, len(metrics.durations))
+			}
+			// the fake clock does not advance during a run
+			for _, duration := range metrics.durations {
+				if duration != 0 {
+					t.Fatalf("Expected observed duration '0s' but got '%s'.\n", duration)
+				}
+			}
+			if !cmp.Equal(metrics.apiCalls, tc.expectedAPICalls) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedAPICalls, metrics.apiCalls))
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_resultMetrics(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	worker0 := testNode("worker0").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+	worker1 := testNode("worker1").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+	worker2 := testNode("worker2").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+		Build()
+
+	metrics := &testMetrics{apiCalls: map[string]int{}}
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    fake.NewClientBuilder().WithObjects(&worker0, &worker1, &worker2).Build(),
+		MaxNodeTerminationPercentage: 1,
+		Metrics:                      metrics,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metrics.badNodesDetected != 1 {
+		t.Fatalf("Expected '%d' bad nodes detected but got '%d'.\n", 1, metrics.badNodesDetected)
+	}
+
+	expectedTickCounts := map[string]int{
+		"worker0": 6,
+		"worker1": 1,
+		"worker2": 0,
+	}
+	if !cmp.Equal(metrics.tickCounts, expectedTickCounts) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedTickCounts, metrics.tickCounts))
+	}
+}
@@ -0,0 +1,379 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testNodeGroup describes nodes of the same role in the same zone.
+// Bad nodes are named `<role>-<zone>-<i>` and have a higher tick count the earlier their group is listed,
+// so the order of the expected nodes follows the order of the groups.
+type testNodeGroup struct {
+	zone  string
+	role  string
+	ready int
+	bad   int
+}
+
+func Test_terminationLimits(t *testing.T) {
+	const zoneLabel = "topology.kubernetes.io/zone"
+
+	testCases := []struct {
+		name              string
+		maxPercentage     float64
+		maxAbsolute       int
+		minHealthy        int
+		maxPerZone        int
+		maxMasters        int
+		nodeGroups        []testNodeGroup
+		expectedNodeNames []string
+	}{
+		{
+			name:          "test 0 - no limits binding",
+			maxPercentage: 0.5,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 10, bad: 2},
+			},
+			expectedNodeNames: []string{"worker-a-0", "worker-a-1"},
+		},
+		{
+			name:          "test 1 - percentage binding",
+			maxPercentage: 0.25,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 8, bad: 4},
+			},
+			expectedNodeNames: []string{"worker-a-0", "worker-a-1", "worker-a-2"},
+		},
+		{
+			name:          "test 2 - percentage allows at least one node",
+			maxPercentage: 0.1,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 2, bad: 2},
+			},
+			expectedNodeNames: []string{"worker-a-0"},
+		},
+		{
+			name:          "test 3 - percentage is rounded",
+			maxPercentage: 0.25,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 5, bad: 5},
+			},
+			expectedNodeNames: []string{"worker-a-0", "worker-a-1", "worker-a-2"},
+		},
+		{
+			name:          "test 4 - absolute limit binding",
+			maxPercentage: 1,
+			maxAbsolute:   2,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 10, bad: 4},
+			},
+			expectedNodeNames: []string{"worker-a-0", "worker-a-1"},
+		},
+		{
+			name:          "test 5 - percentage lower than absolute limit",
+			maxPercentage: 0.1,
+			maxAbsolute:   3,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 10, bad: 4},
+			},
+			expectedNodeNames: []string{"worker-a-0"},
+		},
+		{
+			name:          "test 6 - master limit binding",
+			maxPercentage: 1,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleMaster, bad: 3},
+				{zone: "a", role: labelNodeRoleWorker, ready: 10},
+			},
+			expectedNodeNames: []string{"master-a-0"},
+		},
+		{
+			name:          "test 7 - master limit does not affect workers",
+			maxPercentage: 1,
+			maxMasters:    2,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleMaster, bad: 3},
+				{zone: "a", role: labelNodeRoleWorker, ready: 10, bad: 1},
+			},
+			expectedNodeNames: []string{"master-a-0", "master-a-1", "worker-a-0"},
+		},
+		{
+			name:          "test 8 - master limit across zones",
+			maxPercentage: 1,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleMaster, bad: 1},
+				{zone: "b", role: labelNodeRoleMaster, bad: 1},
+				{zone: "c", role: labelNodeRoleMaster, bad: 1},
+				{zone: "b", role: labelNodeRoleWorker, ready: 5, bad: 1},
+			},
+			expectedNodeNames: []string{"master-a-0", "worker-b-0"},
+		},
+		{
+			name:          "test 9 - zone cap binding",
+			maxPercentage: 1,
+			maxPerZone:    1,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 5, bad: 3},
+				{zone: "b", role: labelNodeRoleWorker, ready: 5, bad: 1},
+			},
+			expectedNodeNames: []string{"worker-a-0", "worker-b-0"},
+		},
+		{
+			name:          "test 10 - zone cap only limits crowded zones",
+			maxPercentage: 1,
+			maxPerZone:    2,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 4, bad: 3},
+				{zone: "b", role: labelNodeRoleWorker, ready: 4, bad: 3},
+				{zone: "c", role: labelNodeRoleWorker, ready: 4, bad: 1},
+			},
+			expectedNodeNames: []string{"worker-a-0", "worker-a-1", "worker-b-0", "worker-b-1", "worker-c-0"},
+		},
+		{
+			name:          "test 11 - zone cap counts master nodes",
+			maxPercentage: 1,
+			maxPerZone:    2,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleMaster, bad: 1},
+				{zone: "a", role: labelNodeRoleWorker, ready: 5, bad: 2},
+			},
+			expectedNodeNames: []string{"master-a-0", "worker-a-0"},
+		},
+		{
+			name:          "test 12 - min healthy holds back a zone",
+			maxPercentage: 1,
+			minHealthy:    3,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 2, bad: 2},
+				{zone: "b", role: labelNodeRoleWorker, ready: 5, bad: 1},
+			},
+			expectedNodeNames: []string{"worker-b-0"},
+		},
+		{
+			name:          "test 13 - min healthy overriding all",
+			maxPercentage: 1,
+			maxAbsolute:   10,
+			minHealthy:    3,
+			maxPerZone:    5,
+			maxMasters:    3,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 1, bad: 1},
+				{zone: "b", role: labelNodeRoleWorker, ready: 2, bad: 1},
+			},
+			expectedNodeNames: nil,
+		},
+		{
+			name:          "test 14 - min healthy exactly met",
+			maxPercentage: 1,
+			minHealthy:    3,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 3, bad: 2},
+			},
+			expectedNodeNames: []string{"worker-a-0", "worker-a-1"},
+		},
+		{
+			name:          "test 15 - min healthy counts Ready master nodes",
+			maxPercentage: 1,
+			minHealthy:    3,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleMaster, ready: 1},
+				{zone: "a", role: labelNodeRoleWorker, ready: 2, bad: 1},
+			},
+			expectedNodeNames: []string{"worker-a-0"},
+		},
+		{
+			name:          "test 16 - all limits binding with absolute limit",
+			maxPercentage: 0.5,
+			maxAbsolute:   3,
+			minHealthy:    2,
+			maxPerZone:    2,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleMaster, bad: 2},
+				{zone: "b", role: labelNodeRoleMaster, bad: 1},
+				{zone: "a", role: labelNodeRoleWorker, ready: 5, bad: 3},
+				{zone: "b", role: labelNodeRoleWorker, ready: 5, bad: 2},
+				{zone: "c", role: labelNodeRoleWorker, ready: 1, bad: 2},
+			},
+			expectedNodeNames: []string{"master-a-0", "worker-a-0", "worker-b-0"},
+		},
+		{
+			name:          "test 17 - all limits binding with percentage",
+			maxPercentage: 0.1,
+			maxAbsolute:   3,
+			minHealthy:    2,
+			maxPerZone:    2,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleMaster, bad: 2},
+				{zone: "b", role: labelNodeRoleMaster, bad: 1},
+				{zone: "a", role: labelNodeRoleWorker, ready: 5, bad: 3},
+				{zone: "b", role: labelNodeRoleWorker, ready: 5, bad: 2},
+				{zone: "c", role: labelNodeRoleWorker, ready: 1, bad: 2},
+			},
+			expectedNodeNames: []string{"master-a-0", "worker-a-0"},
+		},
+		{
+			name:          "test 18 - zone cap being the binding constraint",
+			maxPercentage: 0.5,
+			maxAbsolute:   10,
+			minHealthy:    1,
+			maxPerZone:    1,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 20, bad: 5},
+				{zone: "b", role: labelNodeRoleWorker, ready: 20, bad: 1},
+			},
+			expectedNodeNames: []string{"worker-a-0", "worker-b-0"},
+		},
+		{
+			name:          "test 19 - nodes without zone are not limited by zone cap and min healthy",
+			maxPercentage: 1,
+			minHealthy:    5,
+			maxPerZone:    1,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{role: labelNodeRoleWorker, ready: 2, bad: 3},
+			},
+			expectedNodeNames: []string{"worker-0", "worker-1", "worker-2"},
+		},
+		{
+			name:          "test 20 - no bad nodes",
+			maxPercentage: 1,
+			maxAbsolute:   1,
+			minHealthy:    1,
+			maxPerZone:    1,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleMaster, ready: 3},
+				{zone: "a", role: labelNodeRoleWorker, ready: 5},
+			},
+			expectedNodeNames: nil,
+		},
+		{
+			name:          "test 21 - master nodes of every zone within zone cap",
+			maxPercentage: 1,
+			maxPerZone:    1,
+			maxMasters:    3,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleMaster, bad: 1},
+				{zone: "b", role: labelNodeRoleMaster, bad: 1},
+				{zone: "c", role: labelNodeRoleMaster, bad: 1},
+				{zone: "a", role: labelNodeRoleWorker, ready: 3, bad: 1},
+				{zone: "b", role: labelNodeRoleWorker, ready: 3},
+				{zone: "c", role: labelNodeRoleWorker, ready: 3},
+			},
+			expectedNodeNames: []string{"master-a-0", "master-b-0", "master-c-0"},
+		},
+		{
+			name:          "test 22 - percentage applies to all nodes of the cluster",
+			maxPercentage: 0.3,
+			minHealthy:    2,
+			maxMasters:    1,
+			nodeGroups: []testNodeGroup{
+				{zone: "a", role: labelNodeRoleWorker, ready: 5, bad: 5},
+				{zone: "b", role: labelNodeRoleWorker, ready: 1, bad: 5},
+			},
+			expectedNodeNames: []string{"worker-a-0", "worker-a-1", "worker-a-2", "worker-a-3", "worker-a-4"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var objects []client.Object
+			badNodeCount := 0
+			for _, g := range tc.nodeGroups {
+				prefix := g.role
+				if g.zone != "" {
+					prefix = fmt.Sprintf("%s-%s", g.role, g.zone)
+				}
+
+				for j := 0; j < g.ready+g.bad; j++ {
+					name := fmt.Sprintf("%s-ready-%d", prefix, j-g.bad)
+					status := corev1.ConditionTrue
+					heartbeatAge := time.Duration(0)
+					if j < g.bad {
+						name = fmt.Sprintf("%s-%d", prefix, j)
+						status = corev1.ConditionFalse
+						heartbeatAge = time.Minute * 10
+					}
+
+					b := testNode(name).
+						WithRole(g.role).
+						WithCondition(corev1.NodeReady, status, heartbeatAge)
+					if g.zone != "" {
+						b = b.WithLabel(zoneLabel, g.zone)
+					}
+					if j < g.bad {
+						// the tick count decreases with every bad node, so the sorted result follows the node groups
+						b = b.WithAnnotation(annotationNodeNotReadyTick, strconv.Itoa(100-badNodeCount))
+						badNodeCount++
+					}
+					node := b.Build()
+					objects = append(objects, &node)
+				}
+			}
+
+			var extraFilters []NodeListFilter
+			if tc.maxPerZone > 0 {
+				extraFilters = append(extraFilters, ZoneLimitFilter(zoneLabel, tc.maxPerZone))
+			}
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    fake.NewClientBuilder().WithObjects(objects...).Build(),
+				MaxNodeTerminationPercentage: tc.maxPercentage,
+				MaxNodeTerminationsPerRun:    tc.maxAbsolute,
+				MinReadyNodesPerPool:         tc.minHealthy,
+				NodePoolLabel:                zoneLabel,
+				ExtraFilters:                 extraFilters,
+				MaxMasterTerminations:        tc.maxMasters,
+				SortBadNodesByTickCount:      true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var nodeNames []string
+			for _, n := range badNodes {
+				nodeNames = append(nodeNames, n.Name)
+			}
+			if !cmp.Equal(nodeNames, tc.expectedNodeNames) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodeNames, nodeNames))
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package detector
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TerminationStrategy selects the nodes which are 'marked for termination' when there are more bad nodes
+// than the termination limits allow, ie: MaxNodeTerminationPercentage and MaxNodeTerminationsPerRun.
+type TerminationStrategy interface {
+	// Select returns at most limit nodes of the candidates.
+	Select(candidates []corev1.Node, limit int) []corev1.Node
+}
+
+// HighestTickFirstStrategy selects the nodes with the highest tick count first, as they are bad for the longest time.
+// Nodes with the same tick count keep their order.
+type HighestTickFirstStrategy struct {
+	// TickAnnotationKey defines the node annotation storing the tick count. Defaults to the annotation used by the detector.
+	TickAnnotationKey string
+}
+
+// Select implements TerminationStrategy.
+func (s HighestTickFirstStrategy) Select(candidates []corev1.Node, limit int) []corev1.Node {
+	key := s.TickAnnotationKey
+	if key == "" {
+		key = annotationNodeNotReadyTick
+	}
+
+	nodes := copyNodes(candidates)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodeTickCount(nodes[i], key) > nodeTickCount(nodes[j], key)
+	})
+	return truncateNodes(nodes, limit)
+}
+
+// OldestNodeFirstStrategy selects the nodes with the oldest creation timestamp first.
+// Nodes created at the same time keep their order.
+type OldestNodeFirstStrategy struct{}
+
+// Select implements TerminationStrategy.
+func (s OldestNodeFirstStrategy) Select(candidates []corev1.Node, limit int) []corev1.Node {
+	nodes := copyNodes(candidates)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].CreationTimestamp.Before(&nodes[j].CreationTimestamp)
+	})
+	return truncateNodes(nodes, limit)
+}
+
+// RandomStrategy selects random nodes. Strategies created with the same seed select the same nodes
+// for the same sequence of candidates, which makes the selection reproducible.
+type RandomStrategy struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+// NewRandomStrategy returns a RandomStrategy using the given seed.
+func NewRandomStrategy(seed int64) *RandomStrategy {
+	return &RandomStrategy{
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Select implements TerminationStrategy.
+func (s *RandomStrategy) Select(candidates []corev1.Node, limit int) []corev1.Node {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	nodes := copyNodes(candidates)
+	s.rand.Shuffle(len(nodes), func(i, j int) {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	})
+	return truncateNodes(nodes, limit)
+}
+
+// RoundRobinStrategy selects one node per zone at a time, cycling through the zones in lexicographical order,
+// to spread the terminations over the zones. Nodes of the same zone keep their order.
+// Nodes without the zone label are handled as a zone of their own.
+type RoundRobinStrategy struct {
+	// ZoneLabel defines the node label identifying the zone of a node. Defaults to `topology.kubernetes.io/zone`.
+	ZoneLabel string
+}
+
+// Select implements TerminationStrategy.
+func (s RoundRobinStrategy) Select(candidates []corev1.Node, limit int) []corev1.Node {
+	zoneLabel := s.ZoneLabel
+	if zoneLabel == "" {
+		zoneLabel = corev1.LabelTopologyZone
+	}
+
+	var zones []string
+	nodesPerZone := map[string][]corev1.Node{}
+	for _, n := range candidates {
+		zone := n.Labels[zoneLabel]
+		if _, ok := nodesPerZone[zone]; !ok {
+			zones = append(zones, zone)
+		}
+		nodesPerZone[zone] = append(nodesPerZone[zone], n)
+	}
+	sort.Strings(zones)
+
+	var selected []corev1.Node
+	for i := 0; len(selected) < limit && len(selected) < len(candidates); i++ {
+		for _, zone := range zones {
+			if len(selected) >= limit {
+				break
+			}
+			if i < len(nodesPerZone[zone]) {
+				selected = append(selected, nodesPerZone[zone][i])
+			}
+		}
+	}
+	return selected
+}
+
+func copyNodes(nodes []corev1.Node) []corev1.Node {
+	return append([]corev1.Node{}, nodes...)
+}
+
+func truncateNodes(nodes []corev1.Node, limit int) []corev1.Node {
+	if len(nodes) > limit {
+		return nodes[:limit]
+	}
+	return nodes
+}
@@ -0,0 +1,132 @@
+package detector
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_TerminationStrategy(t *testing.T) {
+	newNode := func(name string, tick int, zone string, age time.Duration) corev1.Node {
+		b := testNode(name).
+			WithAnnotation(annotationNodeNotReadyTick, strconv.Itoa(tick)).
+			WithCreationTime(testNow.Add(-age))
+		if zone != "" {
+			b = b.WithLabel(corev1.LabelTopologyZone, zone)
+		}
+		return b.Build()
+	}
+
+	candidates := []corev1.Node{
+		newNode("worker1", 6, "a", time.Hour),
+		newNode("worker2", 9, "a", time.Hour*3),
+		newNode("worker3", 7, "b", time.Hour*2),
+		newNode("worker4", 9, "a", time.Hour*5),
+		newNode("worker5", 8, "c", time.Hour*4),
+		newNode("worker6", 6, "", time.Hour*6),
+	}
+
+	testCases := []struct {
+		name              string
+		strategy          TerminationStrategy
+		limit             int
+		expectedNodeNames []string
+	}{
+		{
+			name:              "test 0 - highest tick first keeps the order of nodes with the same tick count",
+			strategy:          HighestTickFirstStrategy{},
+			limit:             3,
+			expectedNodeNames: []string{"worker2", "worker4", "worker5"},
+		},
+		{
+			name:              "test 1 - highest tick first with custom tick annotation",
+			strategy:          HighestTickFirstStrategy{TickAnnotationKey: "example.com/tick"},
+			limit:             2,
+			expectedNodeNames: []string{"worker1", "worker2"},
+		},
+		{
+			name:              "test 2 - oldest node first",
+			strategy:          OldestNodeFirstStrategy{},
+			limit:             3,
+			expectedNodeNames: []string{"worker6", "worker4", "worker5"},
+		},
+		{
+			name:              "test 3 - round robin through the zones",
+			strategy:          RoundRobinStrategy{},
+			limit:             5,
+			expectedNodeNames: []string{"worker6", "worker1", "worker3", "worker5", "worker2"},
+		},
+		{
+			name:              "test 4 - round robin with more capacity than candidates",
+			strategy:          RoundRobinStrategy{},
+			limit:             10,
+			expectedNodeNames: []string{"worker6", "worker1", "worker3", "worker5", "worker2", "worker4"},
+		},
+		{
+			name:              "test 5 - round robin with custom zone label",
+			strategy:          RoundRobinStrategy{ZoneLabel: "example.com/zone"},
+			limit:             2,
+			expectedNodeNames: []string{"worker1", "worker2"},
+		},
+		{
+			name:              "test 6 - limit higher than candidates",
+			strategy:          OldestNodeFirstStrategy{},
+			limit:             10,
+			expectedNodeNames: []string{"worker6", "worker4", "worker5", "worker2", "worker3", "worker1"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			selected := tc.strategy.Select(candidates, tc.limit)
+
+			var nodeNames []string
+			for _, n := range selected {
+				nodeNames = append(nodeNames, n.Name)
+			}
+			if !cmp.Equal(nodeNames, tc.expectedNodeNames) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodeNames, nodeNames))
+			}
+			if candidates[0].Name != "worker1" || candidates[5].Name != "worker6" {
+				t.Fatalf("expected the candidates to not be modified")
+			}
+		})
+	}
+}
+
+func Test_RandomStrategy(t *testing.T) {
+	var candidates []corev1.Node
+	for i := 0; i < 20; i++ {
+		candidates = append(candidates, testNode("worker"+strconv.Itoa(i)).Build())
+	}
+
+	nodeNames := func(nodes []corev1.Node) []string {
+		var names []string
+		for _, n := range nodes {
+			names = append(names, n.Name)
+		}
+		return names
+	}
+
+	first := NewRandomStrategy(42)
+	second := NewRandomStrategy(42)
+	for i := 0; i < 3; i++ {
+		a := nodeNames(first.Select(candidates, 5))
+		b := nodeNames(second.Select(candidates, 5))
+		if len(a) != 5 {
+			t.Fatalf("expected 5 nodes but got %d", len(a))
+		}
+		if !cmp.Equal(a, b) {
+			t.Fatalf("expected the same seed to select the same nodes\n\n%s\n", cmp.Diff(a, b))
+		}
+	}
+
+	if cmp.Equal(nodeNames(NewRandomStrategy(1).Select(candidates, 20)), nodeNames(candidates)) {
+		t.Fatalf("expected the nodes to be shuffled")
+	}
+}
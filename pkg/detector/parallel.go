@@ -0,0 +1,133 @@
+package detector
+
+import (
+	"context"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// processNodes processes the nodes of a page and returns the nodes 'marked for termination' in the order of the page.
+// With a parallelism greater than 1 the nodes are processed by a bounded number of workers. Each worker only changes
+// its own nodes, the shared state of the run is guarded by its mutex and the results are merged by the index of the
+// node, so the returned nodes do not depend on the scheduling of the workers.
+func (d *Detector) processNodes(ctx context.Context, r *detectionRun, nodes []corev1.Node) ([]corev1.Node, error) {
+	bad := make([]bool, len(nodes))
+	errs := make([]error, len(nodes))
+
+	if d.parallelism <= 1 {
+		for i := range nodes {
+			// stop processing nodes as soon as the context is cancelled
+			if ctx.Err() != nil {
+				errs[i] = microerror.Mask(ctx.Err())
+				break
+			}
+
+			bad[i], errs[i] = d.processNodeWithEvent(ctx, r, &nodes[i])
+			if errs[i] != nil {
+				break
+			}
+		}
+	} else {
+		var failed bool
+		var mutex sync.Mutex
+		var wg sync.WaitGroup
+
+		indexes := make(chan int)
+		for w := 0; w < d.parallelism; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range indexes {
+					// stop processing nodes as soon as the context is cancelled or a node failed
+					mutex.Lock()
+					stop := failed
+					mutex.Unlock()
+					if stop {
+						continue
+					}
+					if ctx.Err() != nil {
+						errs[i] = microerror.Mask(ctx.Err())
+					} else {
+						bad[i], errs[i] = d.processNodeWithEvent(ctx, r, &nodes[i])
+					}
+					if errs[i] != nil {
+						mutex.Lock()
+						failed = true
+						mutex.Unlock()
+					}
+				}
+			}()
+		}
+		for i := range nodes {
+			indexes <- i
+		}
+		close(indexes)
+		wg.Wait()
+	}
+
+	var badNodes []corev1.Node
+	for i := range nodes {
+		if errs[i] != nil {
+			return badNodes, microerror.Mask(errs[i])
+		}
+		if bad[i] {
+			badNodes = append(badNodes, nodes[i])
+		}
+	}
+	return badNodes, nil
+}
+
+// setReason records why the node was 'marked for termination'.
+func (r *detectionRun) setReason(n corev1.Node, reason BadNodeReason) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.reasons[n.Name] = reason
+}
+
+// markSeen records the node as processed.
+func (r *detectionRun) markSeen(n corev1.Node) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.seen[n.UID] = struct{}{}
+}
+
+// setTickCount records the tick count of the node at the end of the run.
+func (r *detectionRun) setTickCount(n corev1.Node, tickCount int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.tickCounts[n.Name] = tickCount
+}
+
+// tickCount returns the tick count of the node recorded during the run.
+func (r *detectionRun) tickCount(n corev1.Node) (int, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tick, ok := r.tickCounts[n.Name]
+	return tick, ok
+}
+
+// markUnhealthyPool records the pool of the node as not recovered.
+func (r *detectionRun) markUnhealthyPool(pool string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.unhealthyPools[pool] = true
+}
+
+// trackRollback records the changed annotations of the node when RollbackOnError is enabled.
+func (r *detectionRun) trackRollback(original corev1.Node, updated corev1.Node) {
+	if r.rollback == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.rollback.track(original, updated)
+}
@@ -0,0 +1,182 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Test_DetectBadNodes_parallelism is meant to be run with `-race` to detect unsynchronized access to the run state.
+func Test_DetectBadNodes_parallelism(t *testing.T) {
+	const nodeCount = 300
+
+	newNodes := func() []client.Object {
+		var nodes []client.Object
+		for i := 0; i < nodeCount; i++ {
+			// every third node is not ready and reaches the threshold
+			status := corev1.ConditionTrue
+			if i%3 == 0 {
+				status = corev1.ConditionFalse
+			}
+			node := testNode(fmt.Sprintf("worker%03d", i)).
+				WithRole(labelNodeRoleWorker).
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, status, time.Minute*10).
+				Build()
+			node.UID = types.UID(node.Name)
+			nodes = append(nodes, &node)
+		}
+		return nodes
+	}
+
+	testCases := []struct {
+		name        string
+		parallelism int
+		pageSize    int64
+	}{
+		{
+			name:        "test 0 - sequential",
+			parallelism: 1,
+		},
+		{
+			name:        "test 1 - parallel",
+			parallelism: 8,
+		},
+		{
+			name:        "test 2 - parallel with pagination",
+			parallelism: 8,
+			pageSize:    50,
+		},
+		{
+			name:        "test 3 - more workers than nodes per page",
+			parallelism: 32,
+			pageSize:    10,
+		},
+	}
+
+	var expectedBadNodes []string
+	for i := 0; i < nodeCount; i += 3 {
+		expectedBadNodes = append(expectedBadNodes, fmt.Sprintf("worker%03d", i))
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			k8sClient := fake.NewClientBuilder().WithObjects(newNodes()...).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+				ListPageSize:                 tc.pageSize,
+				Parallelism:                  tc.parallelism,
+				RollbackOnError:              true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := d.DetectBadNodesWithResult(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, b := range result.BadNodes {
+				names = append(names, b.Node.Name)
+				if b.Reason != BadNodeReasonTickThreshold {
+					t.Fatalf("Expected reason '%s' for node %s but got '%s'.\n", BadNodeReasonTickThreshold, b.Node.Name, b.Reason)
+				}
+			}
+			sort.Strings(names)
+			if !cmp.Equal(names, expectedBadNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(expectedBadNodes, names))
+			}
+
+			// every tick write must have reached the api
+			var nodeList corev1.NodeList
+			err = k8sClient.List(context.Background(), &nodeList)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, n := range nodeList.Items {
+				// ready nodes recover one tick per run
+				expected := "4"
+				if n.Status.Conditions[0].Status == corev1.ConditionFalse {
+					expected = "6"
+				}
+				if n.Annotations[annotationNodeNotReadyTick] != expected {
+					t.Fatalf("Expected tick count '%s' for node %s but got '%s'.\n", expected, n.Name, n.Annotations[annotationNodeNotReadyTick])
+				}
+			}
+		})
+	}
+}
+
+func Test_processNodes_order(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	var nodes []corev1.Node
+	var objects []client.Object
+	var expectedBadNodes []string
+	for i := 0; i < 200; i++ {
+		status := corev1.ConditionTrue
+		if i%2 == 0 {
+			status = corev1.ConditionFalse
+			expectedBadNodes = append(expectedBadNodes, fmt.Sprintf("worker%03d", i))
+		}
+		node := testNode(fmt.Sprintf("worker%03d", i)).
+			WithAnnotation(annotationNodeNotReadyTick, "5").
+			WithCondition(corev1.NodeReady, status, time.Minute*10).
+			Build()
+		nodes = append(nodes, node)
+		objects = append(objects, node.DeepCopy())
+	}
+
+	d, err := NewDetector(Config{
+		Clock:       &FakeClock{Time: testNow},
+		Logger:      logger,
+		K8sClient:   fake.NewClientBuilder().WithObjects(objects...).Build(),
+		Parallelism: 16,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &detectionRun{
+		logger:     logger,
+		threshold:  d.notReadyTickThreshold,
+		now:        testNow,
+		reasons:    map[string]BadNodeReason{},
+		seen:       map[types.UID]struct{}{},
+		tickCounts: map[string]int{},
+	}
+
+	badNodes, err := d.processNodes(context.Background(), r, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the nodes are returned in the order of the page regardless of the worker scheduling
+	var names []string
+	for _, n := range badNodes {
+		names = append(names, n.Name)
+	}
+	if !cmp.Equal(names, expectedBadNodes) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedBadNodes, names))
+	}
+}
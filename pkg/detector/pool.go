@@ -0,0 +1,144 @@
+package detector
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/nodehealth"
+	"github.com/giantswarm/badnodedetector/v3/pkg/nodepool"
+)
+
+// countReadyNodesPerPool adds the Ready nodes of each pool to readyNodes.
+// Nodes without the pool label are not counted.
+func countReadyNodesPerPool(readyNodes map[string]int, nodes []corev1.Node, poolLabel string) {
+	if poolLabel == "" {
+		return
+	}
+
+	for _, n := range nodes {
+		pool, ok := n.Labels[poolLabel]
+		if ok && isNodeReady(n) {
+			readyNodes[pool]++
+		}
+	}
+}
+
+// limitPoolTerminations removes bad nodes from the list which would leave their node pool with fewer than minReady Ready nodes.
+// readyNodes contains the number of Ready nodes per pool. Nodes without the pool label are unaffected.
+func limitPoolTerminations(readyNodes map[string]int, badNodes []corev1.Node, poolLabel string, minReady int) []corev1.Node {
+	// copy the counts as they are decreased for every node marked for termination
+	remainingReadyNodes := map[string]int{}
+	for pool, count := range readyNodes {
+		remainingReadyNodes[pool] = count
+	}
+
+	var filteredNodes []corev1.Node
+	for _, n := range badNodes {
+		pool, ok := n.Labels[poolLabel]
+		if !ok {
+			filteredNodes = append(filteredNodes, n)
+			continue
+		}
+
+		remaining := remainingReadyNodes[pool]
+		if isNodeReady(n) {
+			remaining--
+		}
+		// terminating this node would leave too few Ready nodes in the pool
+		if remaining < minReady {
+			continue
+		}
+
+		remainingReadyNodes[pool] = remaining
+		filteredNodes = append(filteredNodes, n)
+	}
+	return filteredNodes
+}
+
+// isNodeReady returns true if the node reports the NodeReady condition as true.
+func isNodeReady(n corev1.Node) bool {
+	c, ok := nodehealth.GetCondition(n, corev1.NodeReady)
+	return ok && c.Status == corev1.ConditionTrue
+}
+
+// countNodesPerPool adds the nodes of each pool to poolNodes.
+// Nodes without the pool label are not counted.
+func countNodesPerPool(poolNodes map[string]int, nodes []corev1.Node, poolLabel string) {
+	if poolLabel == "" {
+		return
+	}
+
+	for _, n := range nodes {
+		pool, ok := n.Labels[poolLabel]
+		if ok {
+			poolNodes[pool]++
+		}
+	}
+}
+
+// poolConfig returns the config of the node pool with all zero values replaced by the given defaults.
+// The second return value is false when the pool has no config.
+func (d *Detector) poolConfig(pool string, defaults nodepool.PoolConfig) (nodepool.PoolConfig, bool) {
+	config, ok := d.nodePoolConfigs[pool]
+	if !ok || pool == "" {
+		return nodepool.PoolConfig{}, false
+	}
+
+	return config.WithDefaults(defaults), true
+}
+
+// poolThreshold returns the tick threshold of the node pool of the node, which defaults to the given threshold.
+func (d *Detector) poolThreshold(threshold int, n corev1.Node) int {
+	if d.nodePoolLabel == "" {
+		return threshold
+	}
+
+	config, ok := d.poolConfig(n.Labels[d.nodePoolLabel], nodepool.PoolConfig{NotReadyTickThreshold: threshold})
+	if !ok {
+		return threshold
+	}
+	return config.NotReadyTickThreshold
+}
+
+// limitNodePools applies the termination limits of the configured node pools to the bad nodes.
+// The maxMasterTerminations limit applies once across all pools, so several master pools can not exceed it,
+// and the limit of a pool can only lower it further.
+// nodesPerPool contains the number of nodes per pool. The order of the bad nodes is kept.
+func (d *Detector) limitNodePools(badNodes []corev1.Node, nodesPerPool map[string]int, maxMasterTerminations int) []corev1.Node {
+	defaults := nodepool.PoolConfig{
+		MaxTerminationPercentage: d.maxNodeTerminationPercentage,
+		NotReadyTickThreshold:    d.notReadyTickThreshold,
+		MaxMasterTerminations:    maxMasterTerminations,
+	}
+
+	// the cluster-wide master limit protects the quorum of all master nodes
+	badNodes = limitMasterNodes(badNodes, maxMasterTerminations)
+
+	keptNodes := map[string]bool{}
+	for _, pool := range nodepool.GroupNodesByPool(badNodes, d.nodePoolLabel) {
+		config, ok := d.poolConfig(pool.Name, defaults)
+		if !ok {
+			for _, n := range pool.Nodes {
+				keptNodes[n.Name] = true
+			}
+			continue
+		}
+		pool.Config = config
+
+		nodes := limitMasterNodes(pool.Nodes, pool.Config.MaxMasterTerminations)
+		maxNodeTermination := maximumNodeTermination(nodesPerPool[pool.Name], pool.Config.MaxTerminationPercentage)
+		if len(nodes) > maxNodeTermination {
+			nodes = d.strategy.Select(nodes, maxNodeTermination)
+		}
+		for _, n := range nodes {
+			keptNodes[n.Name] = true
+		}
+	}
+
+	var filteredNodes []corev1.Node
+	for _, n := range badNodes {
+		if keptNodes[n.Name] {
+			filteredNodes = append(filteredNodes, n)
+		}
+	}
+	return filteredNodes
+}
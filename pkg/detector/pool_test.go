@@ -0,0 +1,330 @@
+package detector
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/nodepool"
+)
+
+const testPoolLabel = "giantswarm.io/machine-deployment"
+
+func Test_limitPoolTerminations(t *testing.T) {
+	testCases := []struct {
+		name             string
+		nodes            []corev1.Node
+		badNodes         []string
+		minReady         int
+		expectedBadNodes []string
+	}{
+		{
+			name: "test 0 - enough Ready nodes left",
+			nodes: []corev1.Node{
+				poolNode("worker1", "a", false),
+				poolNode("worker2", "a", true),
+				poolNode("worker3", "a", true),
+			},
+			badNodes:         []string{"worker1"},
+			minReady:         2,
+			expectedBadNodes: []string{"worker1"},
+		},
+		{
+			name: "test 1 - only one Ready node left in pool",
+			nodes: []corev1.Node{
+				poolNode("worker1", "a", false),
+				poolNode("worker2", "a", true),
+			},
+			badNodes:         []string{"worker1"},
+			minReady:         2,
+			expectedBadNodes: nil,
+		},
+		{
+			name: "test 2 - terminating a Ready bad node would leave too few Ready nodes",
+			nodes: []corev1.Node{
+				poolNode("worker1", "a", true),
+				poolNode("worker2", "a", true),
+			},
+			badNodes:         []string{"worker1"},
+			minReady:         2,
+			expectedBadNodes: nil,
+		},
+		{
+			name: "test 3 - multiple Ready bad nodes, only some can be terminated",
+			nodes: []corev1.Node{
+				poolNode("worker1", "a", true),
+				poolNode("worker2", "a", true),
+				poolNode("worker3", "a", true),
+				poolNode("worker4", "a", true),
+			},
+			badNodes:         []string{"worker1", "worker2", "worker3"},
+			minReady:         2,
+			expectedBadNodes: []string{"worker1", "worker2"},
+		},
+		{
+			name: "test 4 - pools are counted separately",
+			nodes: []corev1.Node{
+				poolNode("worker1", "a", false),
+				poolNode("worker2", "a", true),
+				poolNode("worker3", "b", false),
+				poolNode("worker4", "b", true),
+				poolNode("worker5", "b", true),
+			},
+			badNodes:         []string{"worker1", "worker3"},
+			minReady:         2,
+			expectedBadNodes: []string{"worker3"},
+		},
+		{
+			name: "test 5 - nodes without pool label are unaffected",
+			nodes: []corev1.Node{
+				poolNode("worker1", "", false),
+				poolNode("worker2", "a", true),
+			},
+			badNodes:         []string{"worker1"},
+			minReady:         2,
+			expectedBadNodes: []string{"worker1"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var badNodes []corev1.Node
+			for _, n := range tc.nodes {
+				for _, name := range tc.badNodes {
+					if n.Name == name {
+						badNodes = append(badNodes, n)
+					}
+				}
+			}
+
+			readyNodes := map[string]int{}
+			countReadyNodesPerPool(readyNodes, tc.nodes, testPoolLabel)
+
+			filteredNodes := limitPoolTerminations(readyNodes, badNodes, testPoolLabel, tc.minReady)
+
+			var names []string
+			for _, n := range filteredNodes {
+				names = append(names, n.Name)
+			}
+
+			if len(names) != len(tc.expectedBadNodes) {
+				t.Fatalf("Expected nodes %v but got %v.\n", tc.expectedBadNodes, names)
+			}
+			for j := range names {
+				if names[j] != tc.expectedBadNodes[j] {
+					t.Fatalf("Expected nodes %v but got %v.\n", tc.expectedBadNodes, names)
+				}
+			}
+		})
+	}
+}
+
+func poolNode(name string, pool string, ready bool) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+
+	n := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{},
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{
+					Type:   corev1.NodeReady,
+					Status: status,
+				},
+			},
+		},
+	}
+	if pool != "" {
+		n.Labels[testPoolLabel] = pool
+	}
+	return n
+}
+
+func Test_DetectBadNodes_nodePoolConfigs(t *testing.T) {
+	newNode := func(name string, pool string, role string, tick string) client.Object {
+		node := testNode(name).
+			WithRole(role).
+			WithLabel(testPoolLabel, pool).
+			WithAnnotation(annotationNodeNotReadyTick, tick).
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+			Build()
+		return &node
+	}
+
+	testCases := []struct {
+		name                  string
+		maxMasterTerminations int
+		poolConfigs           map[string]nodepool.PoolConfig
+		expectedBadNodes      []string
+	}{
+		{
+			name:             "test 0 - no pool configs",
+			poolConfigs:      nil,
+			expectedBadNodes: []string{"a4", "m1"},
+		},
+		{
+			name:                  "test 1 - pool specific threshold and limits",
+			maxMasterTerminations: 3,
+			poolConfigs: map[string]nodepool.PoolConfig{
+				"a": {
+					NotReadyTickThreshold:    3,
+					MaxTerminationPercentage: 0.5,
+				},
+				"m": {
+					MaxMasterTerminations: 2,
+				},
+			},
+			expectedBadNodes: []string{"a3", "a4", "m1", "m2"},
+		},
+		{
+			name: "test 2 - zero values fall back to the detector config",
+			poolConfigs: map[string]nodepool.PoolConfig{
+				"a": {},
+				"m": {},
+			},
+			expectedBadNodes: []string{"a4", "m1"},
+		},
+		{
+			name: "test 3 - master pools share the cluster-wide master limit",
+			poolConfigs: map[string]nodepool.PoolConfig{
+				"m": {
+					MaxMasterTerminations: 2,
+				},
+				"n": {
+					MaxMasterTerminations: 2,
+				},
+			},
+			expectedBadNodes: []string{"a4", "m1"},
+		},
+		{
+			name:                  "test 4 - master pools do not exceed the raised cluster-wide master limit",
+			maxMasterTerminations: 2,
+			poolConfigs: map[string]nodepool.PoolConfig{
+				"m": {
+					MaxMasterTerminations: 2,
+				},
+				"n": {
+					MaxMasterTerminations: 2,
+				},
+			},
+			expectedBadNodes: []string{"a4", "m1", "m2"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			objects := []client.Object{
+				newNode("a1", "a", labelNodeRoleWorker, "2"),
+				newNode("a2", "a", labelNodeRoleWorker, "3"),
+				newNode("a3", "a", labelNodeRoleWorker, "4"),
+				newNode("a4", "a", labelNodeRoleWorker, "5"),
+				newNode("b1", "b", labelNodeRoleWorker, "3"),
+				newNode("m1", "m", labelNodeRoleMaster, "5"),
+				newNode("m2", "m", labelNodeRoleMaster, "5"),
+				newNode("m3", "m", labelNodeRoleMaster, "5"),
+				newNode("n1", "n", labelNodeRoleMaster, "5"),
+				newNode("n2", "n", labelNodeRoleMaster, "5"),
+			}
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    fake.NewClientBuilder().WithObjects(objects...).Build(),
+				MaxNodeTerminationPercentage: 1,
+				NodePoolLabel:                testPoolLabel,
+				MaxMasterTerminations:        tc.maxMasterTerminations,
+				NodePoolConfigs:              tc.poolConfigs,
+				SortBadNodesByTickCount:      true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, n := range badNodes {
+				names = append(names, n.Name)
+			}
+			sort.Strings(names)
+			if !cmp.Equal(names, tc.expectedBadNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedBadNodes, names))
+			}
+		})
+	}
+}
+
+func Test_NewDetector_nodePoolConfigs(t *testing.T) {
+	testCases := []struct {
+		name         string
+		poolLabel    string
+		poolConfigs  map[string]nodepool.PoolConfig
+		errorMatcher func(error) bool
+	}{
+		{
+			name:        "test 0 - valid pool configs",
+			poolLabel:   testPoolLabel,
+			poolConfigs: map[string]nodepool.PoolConfig{"a": {NotReadyTickThreshold: 3}},
+		},
+		{
+			name:         "test 1 - missing pool label",
+			poolConfigs:  map[string]nodepool.PoolConfig{"a": {NotReadyTickThreshold: 3}},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "test 2 - negative value",
+			poolLabel:    testPoolLabel,
+			poolConfigs:  map[string]nodepool.PoolConfig{"a": {MaxMasterTerminations: -1}},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			_, err := NewDetector(Config{
+				Logger:          logger,
+				K8sClient:       fake.NewClientBuilder().Build(),
+				NodePoolLabel:   tc.poolLabel,
+				NodePoolConfigs: tc.poolConfigs,
+			})
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package detector
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/nodehealth"
+)
+
+// Predicate decides if a node is unhealthy. Predicates are combined with AndPredicate, OrPredicate and NotPredicate
+// to express compound rules, ie: `(NotReady OR Unknown) AND NOT UnderMaintenance`:
+//
+//	AndPredicate(
+//		OrPredicate(
+//			ConditionPredicate(corev1.NodeReady, corev1.ConditionFalse, 5*time.Minute),
+//			ConditionPredicate(corev1.NodeReady, corev1.ConditionUnknown, 5*time.Minute),
+//		),
+//		NotPredicate(LabelPredicate("maintenance", "true")),
+//	)
+type Predicate interface {
+	// Match returns true if the node is unhealthy at the given time.
+	Match(node corev1.Node, now time.Time) bool
+}
+
+// PredicateFunc allows to use an ordinary function as Predicate.
+type PredicateFunc func(node corev1.Node, now time.Time) bool
+
+// Match calls f(node, now).
+func (f PredicateFunc) Match(node corev1.Node, now time.Time) bool {
+	return f(node, now)
+}
+
+// AndPredicate matches when all predicates match. Without predicates it always matches.
+func AndPredicate(predicates ...Predicate) Predicate {
+	return PredicateFunc(func(node corev1.Node, now time.Time) bool {
+		for _, p := range predicates {
+			if !p.Match(node, now) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// OrPredicate matches when any of the predicates matches. Without predicates it never matches.
+func OrPredicate(predicates ...Predicate) Predicate {
+	return PredicateFunc(func(node corev1.Node, now time.Time) bool {
+		for _, p := range predicates {
+			if p.Match(node, now) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// NotPredicate matches when the predicate does not match.
+func NotPredicate(predicate Predicate) Predicate {
+	return PredicateFunc(func(node corev1.Node, now time.Time) bool {
+		return !predicate.Match(node, now)
+	})
+}
+
+// ConditionPredicate matches when the node condition has the given status and the latest heartbeat
+// of the condition is at least duration old. Nodes without the condition do not match.
+func ConditionPredicate(condType corev1.NodeConditionType, status corev1.ConditionStatus, duration time.Duration) Predicate {
+	return PredicateFunc(func(node corev1.Node, now time.Time) bool {
+		c, ok := nodehealth.GetCondition(node, condType)
+		return ok && c.Status == status && now.Sub(c.LastHeartbeatTime.Time) >= duration
+	})
+}
+
+// LabelPredicate matches nodes with the given label. An empty value matches any value of the label.
+func LabelPredicate(key, value string) Predicate {
+	return PredicateFunc(func(node corev1.Node, now time.Time) bool {
+		v, ok := node.Labels[key]
+		return ok && (value == "" || v == value)
+	})
+}
+
+// ConditionsPredicate compiles flat lists of conditions to a predicate matching when any of the
+// trueConditions is not true or any of the falseConditions is true for at least duration.
+// This is how the detector evaluates the node conditions by default.
+func ConditionsPredicate(trueConditions, falseConditions []string, duration time.Duration) Predicate {
+	var predicates []Predicate
+	for _, c := range trueConditions {
+		predicates = append(predicates,
+			ConditionPredicate(corev1.NodeConditionType(c), corev1.ConditionFalse, duration),
+			ConditionPredicate(corev1.NodeConditionType(c), corev1.ConditionUnknown, duration),
+		)
+	}
+	for _, c := range falseConditions {
+		predicates = append(predicates, ConditionPredicate(corev1.NodeConditionType(c), corev1.ConditionTrue, duration))
+	}
+	return OrPredicate(predicates...)
+}
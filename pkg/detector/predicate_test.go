@@ -0,0 +1,150 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_Predicate(t *testing.T) {
+	// (NotReady OR Unknown) AND NOT UnderMaintenance
+	notReadyNotInMaintenance := AndPredicate(
+		OrPredicate(
+			ConditionPredicate(corev1.NodeReady, corev1.ConditionFalse, time.Minute*5),
+			ConditionPredicate(corev1.NodeReady, corev1.ConditionUnknown, time.Minute*5),
+		),
+		NotPredicate(LabelPredicate("maintenance", "true")),
+	)
+	// NotReady OR (DiskPressure AND spot instance)
+	notReadyOrFullSpotDisk := OrPredicate(
+		ConditionPredicate(corev1.NodeReady, corev1.ConditionFalse, time.Minute*5),
+		AndPredicate(
+			ConditionPredicate(corev1.NodeDiskPressure, corev1.ConditionTrue, 0),
+			LabelPredicate("node.kubernetes.io/lifecycle", "spot"),
+		),
+	)
+
+	testCases := []struct {
+		name      string
+		predicate Predicate
+		node      corev1.Node
+		expected  bool
+	}{
+		{
+			name:      "test 0 - not ready node matches",
+			predicate: notReadyNotInMaintenance,
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			expected: true,
+		},
+		{
+			name:      "test 1 - unknown node matches",
+			predicate: notReadyNotInMaintenance,
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionUnknown, time.Minute*10).
+				Build(),
+			expected: true,
+		},
+		{
+			name:      "test 2 - not ready node under maintenance does not match",
+			predicate: notReadyNotInMaintenance,
+			node: testNode("worker1").
+				WithLabel("maintenance", "true").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build(),
+			expected: false,
+		},
+		{
+			name:      "test 3 - recently not ready node does not match",
+			predicate: notReadyNotInMaintenance,
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute).
+				Build(),
+			expected: false,
+		},
+		{
+			name:      "test 4 - ready node does not match",
+			predicate: notReadyNotInMaintenance,
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build(),
+			expected: false,
+		},
+		{
+			name:      "test 5 - spot node with disk pressure matches",
+			predicate: notReadyOrFullSpotDisk,
+			node: testNode("worker1").
+				WithLabel("node.kubernetes.io/lifecycle", "spot").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionTrue, 0).
+				Build(),
+			expected: true,
+		},
+		{
+			name:      "test 6 - on demand node with disk pressure does not match",
+			predicate: notReadyOrFullSpotDisk,
+			node: testNode("worker1").
+				WithLabel("node.kubernetes.io/lifecycle", "on-demand").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition(corev1.NodeDiskPressure, corev1.ConditionTrue, 0).
+				Build(),
+			expected: false,
+		},
+		{
+			name:      "test 7 - condition list matches false condition",
+			predicate: ConditionsPredicate(trueConditions, falseConditions, time.Minute*5),
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition("DiskFullKubelet", corev1.ConditionTrue, time.Minute*10).
+				Build(),
+			expected: true,
+		},
+		{
+			name:      "test 8 - condition list does not match healthy node",
+			predicate: ConditionsPredicate(trueConditions, falseConditions, time.Minute*5),
+			node: testNode("worker1").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				WithCondition("DiskFullKubelet", corev1.ConditionFalse, 0).
+				Build(),
+			expected: false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result := tc.predicate.Match(tc.node, testNow)
+			if result != tc.expected {
+				t.Fatalf("expected %t but got %t", tc.expected, result)
+			}
+		})
+	}
+}
+
+func Test_nodeHealthCheck_predicate(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	h := testHealthCheck()
+	h.predicate = NotPredicate(LabelPredicate("healthy", ""))
+
+	node := testNode("worker1").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		WithLabel("healthy", "true").
+		Build()
+	if h.isNodeUnhealthy(context.Background(), logger, node) {
+		t.Fatalf("expected the predicate to replace the evaluation of the node conditions")
+	}
+
+	node = testNode("worker2").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+		Build()
+	if !h.isNodeUnhealthy(context.Background(), logger, node) {
+		t.Fatalf("expected the node to be unhealthy")
+	}
+}
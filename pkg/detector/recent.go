@@ -0,0 +1,156 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// recentTerminationsConfigMapKey is the key of the ConfigMap data holding the JSON encoded recent terminations.
+	recentTerminationsConfigMapKey = "terminations"
+)
+
+// RecentTermination records a node which was 'marked for termination'.
+type RecentTermination struct {
+	NodeName   string      `json:"nodeName"`
+	ProviderID string      `json:"providerID,omitempty"`
+	Time       metav1.Time `json:"time"`
+}
+
+// RecentTerminationStore persists the recently terminated nodes of the cluster,
+// so the suppression is enforced across restarts and multiple replicas.
+type RecentTerminationStore interface {
+	// Update calls fn with the current terminations and persists the terminations returned by fn.
+	Update(ctx context.Context, fn func(terminations []RecentTermination) []RecentTermination) error
+}
+
+// ConfigMapRecentTerminationStore is a RecentTerminationStore persisting the terminations as JSON in the data of a ConfigMap.
+type ConfigMapRecentTerminationStore struct {
+	k8sClient client.Client
+	namespace string
+	name      string
+
+	mutex sync.Mutex
+}
+
+// NewConfigMapRecentTerminationStore returns a store using the ConfigMap with the given name,
+// the ConfigMap is created if it does not exist.
+func NewConfigMapRecentTerminationStore(k8sClient client.Client, namespace string, name string) *ConfigMapRecentTerminationStore {
+	return &ConfigMapRecentTerminationStore{
+		k8sClient: k8sClient,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Update reads the terminations from the ConfigMap, calls fn and writes the returned terminations back.
+// Invalid data in the ConfigMap is dropped. fn is called again when another replica changed the terminations in the meantime.
+func (s *ConfigMapRecentTerminationStore) Update(ctx context.Context, fn func(terminations []RecentTermination) []RecentTermination) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := updateConfigMap(ctx, s.k8sClient, s.namespace, s.name, func(data map[string]string) (map[string]string, error) {
+		var terminations []RecentTermination
+		if v, ok := data[recentTerminationsConfigMapKey]; ok {
+			err := json.Unmarshal([]byte(v), &terminations)
+			if err != nil {
+				terminations = nil
+			}
+		}
+
+		encoded, err := json.Marshal(fn(terminations))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if data == nil {
+			data = map[string]string{}
+		}
+		data[recentTerminationsConfigMapKey] = string(encoded)
+		return data, nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// MatchRecentTermination is the default RecentTerminationMatch. It matches nodes with the name or the provider id
+// of the terminated node, as replacements can reuse either of them.
+func MatchRecentTermination(n corev1.Node, termination RecentTermination) bool {
+	if n.Name == termination.NodeName {
+		return true
+	}
+	return termination.ProviderID != "" && n.Spec.ProviderID == termination.ProviderID
+}
+
+// suppressRecentTerminations expires the terminations older than RecentTerminationWindow and removes the bad nodes
+// matching a recent termination, as they are likely replacements which are still bootstrapping.
+func (d *Detector) suppressRecentTerminations(ctx context.Context, logger micrologger.Logger, badNodes []corev1.Node, now time.Time) ([]corev1.Node, error) {
+	var filteredNodes []corev1.Node
+	err := d.recentTerminations.Update(ctx, func(terminations []RecentTermination) []RecentTermination {
+		filteredNodes = nil
+
+		var recent []RecentTermination
+		for _, t := range terminations {
+			if now.Sub(t.Time.Time) < d.recentTerminationWindow {
+				recent = append(recent, t)
+			}
+		}
+
+		for _, n := range badNodes {
+			if d.matchesRecentTermination(ctx, logger, n, recent) {
+				continue
+			}
+			filteredNodes = append(filteredNodes, n)
+		}
+
+		return recent
+	})
+	if err != nil {
+		return nil, microerror.Maskf(recentTerminationsError, "%s", err.Error())
+	}
+
+	return filteredNodes, nil
+}
+
+func (d *Detector) matchesRecentTermination(ctx context.Context, logger micrologger.Logger, n corev1.Node, terminations []RecentTermination) bool {
+	for _, t := range terminations {
+		if d.recentTerminationMatch(n, t) {
+			logger.Debugf(ctx, "held back node %s as it matches node %s terminated at %s", n.Name, t.NodeName, t.Time.Format(time.RFC3339))
+			return true
+		}
+	}
+	return false
+}
+
+// recordRecentTerminations adds the nodes 'marked for termination' to the recent terminations.
+func (d *Detector) recordRecentTerminations(ctx context.Context, badNodes []corev1.Node, now time.Time) error {
+	if len(badNodes) == 0 {
+		return nil
+	}
+
+	err := d.recentTerminations.Update(ctx, func(terminations []RecentTermination) []RecentTermination {
+		for _, n := range badNodes {
+			terminations = append(terminations, RecentTermination{
+				NodeName:   n.Name,
+				ProviderID: n.Spec.ProviderID,
+				Time:       metav1.NewTime(now),
+			})
+		}
+		return terminations
+	})
+	if err != nil {
+		return microerror.Maskf(recentTerminationsError, "%s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodes_recentTerminations(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		terminations           []RecentTermination
+		match                  func(n corev1.Node, termination RecentTermination) bool
+		expectedNodeNames      []string
+		expectedTerminatedKeys []string
+	}{
+		{
+			name:                   "test 0 - no recent terminations",
+			expectedNodeNames:      []string{"worker1", "worker2"},
+			expectedTerminatedKeys: []string{"worker1", "worker2"},
+		},
+		{
+			name: "test 1 - node matching the name of a recent termination is suppressed",
+			terminations: []RecentTermination{
+				{NodeName: "worker1", Time: metav1.NewTime(testNow.Add(-time.Minute * 5))},
+			},
+			expectedNodeNames:      []string{"worker2"},
+			expectedTerminatedKeys: []string{"worker1", "worker2"},
+		},
+		{
+			name: "test 2 - node matching the provider id of a recent termination is suppressed",
+			terminations: []RecentTermination{
+				{NodeName: "old-worker", ProviderID: "aws:///eu-west-1a/i-2", Time: metav1.NewTime(testNow.Add(-time.Minute * 5))},
+			},
+			expectedNodeNames:      []string{"worker1"},
+			expectedTerminatedKeys: []string{"old-worker", "worker1"},
+		},
+		{
+			name: "test 3 - expired termination is forgotten",
+			terminations: []RecentTermination{
+				{NodeName: "worker1", Time: metav1.NewTime(testNow.Add(-time.Hour))},
+			},
+			expectedNodeNames:      []string{"worker1", "worker2"},
+			expectedTerminatedKeys: []string{"worker1", "worker2"},
+		},
+		{
+			name: "test 4 - custom comparison",
+			terminations: []RecentTermination{
+				{NodeName: "worker1", Time: metav1.NewTime(testNow.Add(-time.Minute * 5))},
+			},
+			match: func(n corev1.Node, termination RecentTermination) bool {
+				return termination.ProviderID != "" && n.Spec.ProviderID == termination.ProviderID
+			},
+			expectedNodeNames:      []string{"worker1", "worker2"},
+			expectedTerminatedKeys: []string{"worker1", "worker1", "worker2"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var objects []client.Object
+			for j, name := range []string{"worker1", "worker2"} {
+				node := testNode(name).
+					WithAnnotation(annotationNodeNotReadyTick, "5").
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build()
+				node.Spec.ProviderID = "aws:///eu-west-1a/i-" + strconv.Itoa(j+1)
+				objects = append(objects, &node)
+			}
+			if tc.terminations != nil {
+				data, err := json.Marshal(tc.terminations)
+				if err != nil {
+					t.Fatal(err)
+				}
+				objects = append(objects, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "kube-system",
+						Name:      "recent-terminations",
+					},
+					Data: map[string]string{
+						recentTerminationsConfigMapKey: string(data),
+					},
+				})
+			}
+
+			logger, _ := micrologger.New(micrologger.Config{})
+			k8sClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+				RecentTerminationWindow:      time.Minute * 30,
+				RecentTerminationConfigMap:   "recent-terminations",
+				RecentTerminationMatch:       tc.match,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var nodeNames []string
+			for _, n := range badNodes {
+				nodeNames = append(nodeNames, n.Name)
+			}
+			sort.Strings(nodeNames)
+			if !cmp.Equal(nodeNames, tc.expectedNodeNames) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodeNames, nodeNames))
+			}
+
+			var configMap corev1.ConfigMap
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "kube-system", Name: "recent-terminations"}, &configMap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var terminations []RecentTermination
+			err = json.Unmarshal([]byte(configMap.Data[recentTerminationsConfigMapKey]), &terminations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var terminatedKeys []string
+			for _, termination := range terminations {
+				terminatedKeys = append(terminatedKeys, termination.NodeName)
+			}
+			sort.Strings(terminatedKeys)
+			if !cmp.Equal(terminatedKeys, tc.expectedTerminatedKeys) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedTerminatedKeys, terminatedKeys))
+			}
+		})
+	}
+}
+
+func Test_MatchRecentTermination(t *testing.T) {
+	testCases := []struct {
+		name        string
+		nodeName    string
+		providerID  string
+		termination RecentTermination
+		expected    bool
+	}{
+		{
+			name:        "test 0 - same name",
+			nodeName:    "worker1",
+			termination: RecentTermination{NodeName: "worker1"},
+			expected:    true,
+		},
+		{
+			name:        "test 1 - same provider id",
+			nodeName:    "worker2",
+			providerID:  "aws:///eu-west-1a/i-1",
+			termination: RecentTermination{NodeName: "worker1", ProviderID: "aws:///eu-west-1a/i-1"},
+			expected:    true,
+		},
+		{
+			name:        "test 2 - empty provider ids do not match",
+			nodeName:    "worker2",
+			termination: RecentTermination{NodeName: "worker1"},
+			expected:    false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			node := testNode(tc.nodeName).Build()
+			node.Spec.ProviderID = tc.providerID
+
+			result := MatchRecentTermination(node, tc.termination)
+			if result != tc.expected {
+				t.Fatalf("Expected '%t' but got '%t'.\n", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package detector
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/rand"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// defaultReconcileInterval is the minimum time between two ticks of a node when RunInterval is not set.
+const defaultReconcileInterval = time.Minute
+
+// NodeReconciler is a controller-runtime reconciler updating the tick count of a single node per reconciliation,
+// so the detection can be embedded into an operator and driven by its work queue instead of periodic runs.
+// The tick count of a node changes at most once per RunInterval (one minute when not set), recorded
+// with the LastDetectionRunAnnotation, and unhealthy nodes are requeued until they recover, since the heartbeats
+// making them cross the condition thresholds are filtered.
+// Bad nodes are requeued after PauseBetweenTermination. The termination limits of DetectBadNodes apply
+// to a whole run and are not enforced per node, so callers acting on bad nodes must limit the terminations themselves.
+type NodeReconciler struct {
+	Detector *Detector
+
+	// mutex guards the cluster-wide data shared by the reconciliations of one interval.
+	mutex       sync.Mutex
+	inputs      *reconcileInputs
+	inputsUntil time.Time
+}
+
+// reconcileInputs holds the data of a detection run which depends on the whole cluster instead of a single node.
+type reconcileInputs struct {
+	threshold      int
+	activePods     map[string]int
+	escalatedPools map[string]bool
+}
+
+// SetupWithManager registers the reconciler for Node objects with the manager.
+func (r *NodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Node{}, builder.WithPredicates(r.nodeHealthChanged())).
+		Complete(r)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Reconcile updates the tick count of the requested node and requeues it after PauseBetweenTermination
+// if it is 'marked for termination', or after the interval if it is unhealthy, has a condition with an
+// unhealthy status or is cordoned. Nodes which are gone, not handled by the detector or ticked less than
+// an interval ago are not changed.
+// Errors are returned with an empty result, so controller-runtime retries with its backoff.
+func (r *NodeReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	d := r.Detector
+	interval := r.interval()
+
+	var n corev1.Node
+	err := d.k8sClient.Get(ctx, client.ObjectKey{Name: req.Name}, &n)
+	if apierrors.IsNotFound(err) {
+		return reconcile.Result{}, nil
+	} else if err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	if !d.handlesNode(ctx, n) {
+		return reconcile.Result{}, nil
+	}
+
+	// the node was already ticked within the interval, ie: the event was caused by our own update
+	now := d.clock.Now()
+	if last, ok := lastDetectionRunTime(n); ok && now.Sub(last) < interval {
+		return reconcile.Result{RequeueAfter: interval - now.Sub(last)}, nil
+	}
+
+	inputs, err := r.clusterInputs(ctx, now)
+	if err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	runID := rand.String(runIDLength)
+	run := d.initDetectionRun(runID, d.logger.With("run", runID), inputs.threshold, inputs.activePods, nil)
+	run.escalatedPools = inputs.escalatedPools
+	run.recordLastDetectionRun = true
+	run.patch = true
+
+	bad, err := d.processNode(ctx, run, &n)
+	if err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+	if bad && n.Name != d.selfNodeName {
+		return reconcile.Result{RequeueAfter: d.pauseBetweenTermination}, nil
+	}
+	// nodes with an unhealthy status or cordoned nodes can become unhealthy without any further event
+	// passing the predicate, once their conditions are older than the thresholds
+	if nodeTickCount(n, d.tickAnnotationKey) > 0 || !d.healthCheck.hasHealthyConditionStatus(n) || n.Spec.Unschedulable {
+		return reconcile.Result{RequeueAfter: interval}, nil
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// interval returns the minimum time between two ticks of a node.
+func (r *NodeReconciler) interval() time.Duration {
+	if r.Detector.runInterval > 0 {
+		return r.Detector.runInterval
+	}
+	return defaultReconcileInterval
+}
+
+// clusterInputs returns the cluster-wide data of the detection, which is computed at most once per interval
+// instead of for every reconciled node.
+func (r *NodeReconciler) clusterInputs(ctx context.Context, now time.Time) (*reconcileInputs, error) {
+	d := r.Detector
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.inputs != nil && now.Before(r.inputsUntil) {
+		return r.inputs, nil
+	}
+
+	runID := rand.String(runIDLength)
+	run, err := d.newDetectionRun(ctx, runID, d.logger.With("run", runID), nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// the node count of a previous DetectBadNodes run is not available when only the reconciler is used
+	threshold := run.threshold
+	if d.thresholdFormula != nil && d.previousNodeCount() == 0 {
+		nodeCount, err := d.countNodes(ctx)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		threshold = d.effectiveThreshold(nodeCount)
+	}
+
+	r.inputs = &reconcileInputs{
+		threshold:      threshold,
+		activePods:     run.activePods,
+		escalatedPools: run.escalatedPools,
+	}
+	r.inputsUntil = now.Add(r.interval())
+
+	return r.inputs, nil
+}
+
+// nodeHealthChanged filters the node updates which do not affect the detection, ie: the annotation updates
+// written by the reconciler itself or the heartbeats of the kubelet. Creations and deletions always pass.
+func (r *NodeReconciler) nodeHealthChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
+				return true
+			}
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
+				return true
+			}
+			return !sameConditionStatus(*oldNode, *newNode) ||
+				!reflect.DeepEqual(r.relevantLabels(*oldNode), r.relevantLabels(*newNode)) ||
+				oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable ||
+				oldNode.Annotations[NodeSkipAnnotation] != newNode.Annotations[NodeSkipAnnotation]
+		},
+	}
+}
+
+// relevantLabels returns the labels of the node without the bad node label written by the reconciler itself.
+func (r *NodeReconciler) relevantLabels(n corev1.Node) map[string]string {
+	relevant := map[string]string{}
+	for k, v := range n.Labels {
+		if k != r.Detector.badNodeLabel {
+			relevant[k] = v
+		}
+	}
+	return relevant
+}
+
+// sameConditionStatus returns true if both nodes have the same conditions with the same status,
+// ignoring the heartbeat and transition times.
+func sameConditionStatus(a corev1.Node, b corev1.Node) bool {
+	if len(a.Status.Conditions) != len(b.Status.Conditions) {
+		return false
+	}
+	for i := range a.Status.Conditions {
+		if a.Status.Conditions[i].Type != b.Status.Conditions[i].Type || a.Status.Conditions[i].Status != b.Status.Conditions[i].Status {
+			return false
+		}
+	}
+	return true
+}
+
+// lastDetectionRunTime returns the time recorded in the LastDetectionRunAnnotation of the node.
+func lastDetectionRunTime(n corev1.Node) (time.Time, bool) {
+	value, ok := n.Annotations[LastDetectionRunAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, fields[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// handlesNode returns true if the node is selected by the detector the same way as listed nodes are.
+func (d *Detector) handlesNode(ctx context.Context, n corev1.Node) bool {
+	if !labels.SelectorFromSet(labels.Set(d.nodeSelector)).Matches(labels.Set(n.Labels)) {
+		return false
+	}
+	return len(d.handledNodes(ctx, []corev1.Node{n})) == 1
+}
@@ -0,0 +1,26 @@
+// Package reconciler adapts the detector to controller-runtime reconcilers.
+package reconciler
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/detector"
+)
+
+// Reconcile runs the detection and returns the nodes 'marked for termination' together with a result
+// requeueing the reconciliation after interval, so the detection runs periodically.
+// The result does not requeue when interval is zero. Errors are returned with an empty result,
+// so controller-runtime retries with its backoff.
+func Reconcile(ctx context.Context, d *detector.Detector, interval time.Duration) (reconcile.Result, []corev1.Node, error) {
+	badNodes, err := d.DetectBadNodes(ctx)
+	if err != nil {
+		return reconcile.Result{}, nil, microerror.Mask(err)
+	}
+
+	return reconcile.Result{RequeueAfter: interval}, badNodes, nil
+}
@@ -0,0 +1,113 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/detector"
+)
+
+var testNow = time.Date(2023, 11, 9, 12, 0, 0, 0, time.UTC)
+
+// errorClient wraps a client and fails all List calls.
+type errorClient struct {
+	client.Client
+}
+
+func (c errorClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return errors.New("api server unavailable")
+}
+
+func Test_Reconcile(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		interval             time.Duration
+		listError            bool
+		expectedRequeueAfter time.Duration
+		expectedBadNodes     int
+		expectError          bool
+	}{
+		{
+			name:                 "test 0 - requeue after interval",
+			interval:             time.Minute,
+			expectedRequeueAfter: time.Minute,
+			expectedBadNodes:     1,
+		},
+		{
+			name:                 "test 1 - no requeue without interval",
+			interval:             0,
+			expectedRequeueAfter: 0,
+			expectedBadNodes:     1,
+		},
+		{
+			name:                 "test 2 - error",
+			interval:             time.Minute,
+			listError:            true,
+			expectedRequeueAfter: 0,
+			expectedBadNodes:     0,
+			expectError:          true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker1",
+					Annotations: map[string]string{"giantswarm.io/node-not-ready-tick": "5"},
+				},
+				Status: corev1.NodeStatus{
+					Conditions: []corev1.NodeCondition{
+						{
+							Type:              corev1.NodeReady,
+							Status:            corev1.ConditionFalse,
+							LastHeartbeatTime: metav1.NewTime(testNow.Add(-time.Minute * 10)),
+						},
+					},
+				},
+			}
+			var k8sClient client.Client = fake.NewClientBuilder().WithObjects(node).Build()
+			if tc.listError {
+				k8sClient = errorClient{Client: k8sClient}
+			}
+
+			config := detector.DefaultConfig()
+			config.Clock = &detector.FakeClock{Time: testNow}
+			config.Logger = logger
+			config.K8sClient = k8sClient
+
+			d, err := detector.NewDetector(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, badNodes, err := Reconcile(context.Background(), d, tc.interval)
+			if tc.expectError && err == nil {
+				t.Fatalf("error == nil, want non-nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatal(err)
+			}
+
+			if result.RequeueAfter != tc.expectedRequeueAfter {
+				t.Fatalf("Expected requeue after '%s' but got '%s'.\n", tc.expectedRequeueAfter, result.RequeueAfter)
+			}
+			if len(badNodes) != tc.expectedBadNodes {
+				t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", tc.expectedBadNodes, len(badNodes))
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func Test_NodeReconciler_Reconcile(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		nodeName             string
+		expectedRequeueAfter time.Duration
+		expectedTick         string
+	}{
+		{
+			name:                 "test 0 - bad node is requeued",
+			nodeName:             "worker1",
+			expectedRequeueAfter: defaultPauseBetweenTermination,
+			expectedTick:         "6",
+		},
+		{
+			name:                 "test 1 - not ready node below threshold is requeued after the interval",
+			nodeName:             "worker2",
+			expectedRequeueAfter: defaultReconcileInterval,
+			expectedTick:         "1",
+		},
+		{
+			name:         "test 2 - healthy node is not requeued",
+			nodeName:     "worker3",
+			expectedTick: "",
+		},
+		{
+			name:         "test 3 - skipped node is not changed",
+			nodeName:     "worker4",
+			expectedTick: "5",
+		},
+		{
+			name:     "test 4 - missing node is ignored",
+			nodeName: "worker5",
+		},
+		{
+			name:                 "test 5 - node ticked within the interval is not ticked again",
+			nodeName:             "worker6",
+			expectedRequeueAfter: time.Second * 40,
+			expectedTick:         "2",
+		},
+		{
+			name:                 "test 6 - node with an unknown status within the threshold is requeued after the interval",
+			nodeName:             "worker7",
+			expectedRequeueAfter: defaultReconcileInterval,
+			expectedTick:         "",
+		},
+		{
+			name:                 "test 7 - cordoned healthy node is requeued after the interval",
+			nodeName:             "worker8",
+			expectedRequeueAfter: defaultReconcileInterval,
+			expectedTick:         "",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			worker1 := testNode("worker1").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build()
+			worker2 := testNode("worker2").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build()
+			worker3 := testNode("worker3").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build()
+			worker4 := testNode("worker4").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithAnnotation(NodeSkipAnnotation, "true").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build()
+			worker6 := testNode("worker6").
+				WithAnnotation(annotationNodeNotReadyTick, "2").
+				WithAnnotation(LastDetectionRunAnnotation, fmt.Sprintf("Ab3dE5gH7jK9mN1p %s", testNow.Add(-time.Second*20).Format(time.RFC3339))).
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build()
+			worker7 := testNode("worker7").
+				WithCondition(corev1.NodeReady, corev1.ConditionUnknown, time.Second*10).
+				Build()
+			worker8 := testNode("worker8").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build()
+			worker8.Spec.Unschedulable = true
+
+			logger, _ := micrologger.New(micrologger.Config{})
+			k8sClient := fake.NewClientBuilder().WithObjects(&worker1, &worker2, &worker3, &worker4, &worker6, &worker7, &worker8).Build()
+
+			d, err := NewDetector(Config{
+				Clock:     &FakeClock{Time: testNow},
+				Logger:    logger,
+				K8sClient: k8sClient,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := &NodeReconciler{Detector: d}
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: tc.nodeName}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.RequeueAfter != tc.expectedRequeueAfter {
+				t.Fatalf("Expected requeue after '%s' but got '%s'.\n", tc.expectedRequeueAfter, result.RequeueAfter)
+			}
+
+			var n corev1.Node
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: tc.nodeName}, &n)
+			if err != nil {
+				// the missing node has no tick count to check
+				return
+			}
+			if n.Annotations[annotationNodeNotReadyTick] != tc.expectedTick {
+				t.Fatalf("Expected tick '%s' but got '%s'.\n", tc.expectedTick, n.Annotations[annotationNodeNotReadyTick])
+			}
+		})
+	}
+}
+
+func Test_NodeReconciler_Reconcile_thresholdCrossing(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	// the status is already unknown, but the heartbeat is still within the threshold
+	node := testNode("worker1").
+		WithCondition(corev1.NodeReady, corev1.ConditionUnknown, time.Second*45).
+		Build()
+	k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+
+	clock := &FakeClock{Time: testNow}
+	d, err := NewDetector(Config{
+		Clock:                     clock,
+		Logger:                    logger,
+		K8sClient:                 k8sClient,
+		NodeReadyUnknownThreshold: time.Minute * 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no event passes the predicate, the node is only reconciled again when it is requeued
+	r := &NodeReconciler{Detector: d}
+	expectedTickCounts := []string{"", "1"}
+	for i, expected := range expectedTickCounts {
+		result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "worker1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.RequeueAfter != defaultReconcileInterval {
+			t.Fatalf("Expected requeue after '%s' in reconciliation %d but got '%s'.\n", defaultReconcileInterval, i, result.RequeueAfter)
+		}
+
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Annotations[annotationNodeNotReadyTick] != expected {
+			t.Fatalf("Expected tick '%s' after reconciliation %d but got '%s'.\n", expected, i, node.Annotations[annotationNodeNotReadyTick])
+		}
+
+		clock.Time = clock.Time.Add(time.Minute * 3)
+	}
+}
+
+func Test_NodeReconciler_nodeHealthChanged(t *testing.T) {
+	const badNodeLabel = "example.com/bad-node"
+
+	testCases := []struct {
+		name            string
+		update          func(n *corev1.Node)
+		expectedChanged bool
+	}{
+		{
+			name: "test 0 - heartbeat is filtered",
+			update: func(n *corev1.Node) {
+				n.Status.Conditions[0].LastHeartbeatTime.Time = testNow
+			},
+			expectedChanged: false,
+		},
+		{
+			name: "test 1 - tick annotation is filtered",
+			update: func(n *corev1.Node) {
+				n.Annotations[annotationNodeNotReadyTick] = "3"
+				n.Annotations[LastDetectionRunAnnotation] = fmt.Sprintf("Ab3dE5gH7jK9mN1p %s", testNow.Format(time.RFC3339))
+			},
+			expectedChanged: false,
+		},
+		{
+			name: "test 2 - bad node label is filtered",
+			update: func(n *corev1.Node) {
+				n.Labels[badNodeLabel] = "true"
+			},
+			expectedChanged: false,
+		},
+		{
+			name: "test 3 - condition status passes",
+			update: func(n *corev1.Node) {
+				n.Status.Conditions[0].Status = corev1.ConditionFalse
+			},
+			expectedChanged: true,
+		},
+		{
+			name: "test 4 - skip annotation passes",
+			update: func(n *corev1.Node) {
+				n.Annotations[NodeSkipAnnotation] = "true"
+			},
+			expectedChanged: true,
+		},
+		{
+			name: "test 5 - cordon passes",
+			update: func(n *corev1.Node) {
+				n.Spec.Unschedulable = true
+			},
+			expectedChanged: true,
+		},
+		{
+			name: "test 6 - other label passes",
+			update: func(n *corev1.Node) {
+				n.Labels["example.com/pool"] = "b"
+			},
+			expectedChanged: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			oldNode := testNode("worker1").
+				WithLabel("example.com/pool", "a").
+				WithAnnotation(annotationNodeNotReadyTick, "2").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, time.Minute).
+				Build()
+			newNode := oldNode.DeepCopy()
+			tc.update(newNode)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:        &FakeClock{Time: testNow},
+				Logger:       logger,
+				K8sClient:    fake.NewClientBuilder().Build(),
+				BadNodeLabel: badNodeLabel,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := &NodeReconciler{Detector: d}
+			changed := r.nodeHealthChanged().Update(event.UpdateEvent{ObjectOld: &oldNode, ObjectNew: newNode})
+			if changed != tc.expectedChanged {
+				t.Fatalf("Expected changed '%t' but got '%t'.\n", tc.expectedChanged, changed)
+			}
+		})
+	}
+}
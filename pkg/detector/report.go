@@ -0,0 +1,100 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	reportStatusHealthy  = "Healthy"
+	reportStatusWarning  = "Warning"
+	reportStatusCritical = "Critical"
+)
+
+// MarkdownReport returns a Markdown table describing the health of all nodes handled by the detector sorted by
+// tick count in descending order, ie: to paste into an issue. Nodes with more than half of the tick threshold are
+// reported as `Warning`, nodes which reached it as `Critical`. The nodes are not modified.
+func (d *Detector) MarkdownReport(ctx context.Context) (string, error) {
+	var allNodes []corev1.Node
+	err := d.forEachNodePage(ctx, func(nodes []corev1.Node) error {
+		allNodes = append(allNodes, d.handledNodes(ctx, nodes)...)
+		return nil
+	})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	sort.SliceStable(allNodes, func(i, j int) bool {
+		ti := nodeTickCount(allNodes[i], d.tickAnnotationKey)
+		tj := nodeTickCount(allNodes[j], d.tickAnnotationKey)
+		if ti != tj {
+			return ti > tj
+		}
+		return allNodes[i].Name < allNodes[j].Name
+	})
+
+	threshold := d.effectiveThreshold(len(allNodes))
+
+	var b strings.Builder
+	b.WriteString("| Node Name | Role | Tick Count | Threshold | Status | Unhealthy Conditions | First Unhealthy At |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, n := range allNodes {
+		tick := nodeTickCount(n, d.tickAnnotationKey)
+		nodeThreshold := d.poolThreshold(threshold, n)
+
+		var conditions []string
+		var firstUnhealthyAt time.Time
+		for _, c := range d.healthCheck.unhealthyConditions(n) {
+			conditions = append(conditions, string(c.Type))
+			if firstUnhealthyAt.IsZero() || c.LastTransitionTime.Time.Before(firstUnhealthyAt) {
+				firstUnhealthyAt = c.LastTransitionTime.Time
+			}
+		}
+
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %s | %s | %s |\n",
+			n.Name,
+			reportValue(n.Labels[labelNodeRole]),
+			tick,
+			nodeThreshold,
+			reportStatus(tick, nodeThreshold),
+			reportValue(strings.Join(conditions, ", ")),
+			reportTime(firstUnhealthyAt),
+		)
+	}
+
+	return b.String(), nil
+}
+
+// reportStatus returns the status of a node with the given tick count for the report.
+func reportStatus(tick int, threshold int) string {
+	switch {
+	case tick >= threshold:
+		return reportStatusCritical
+	case tick*2 > threshold:
+		return reportStatusWarning
+	default:
+		return reportStatusHealthy
+	}
+}
+
+// reportValue returns the value for a table cell, empty cells are shown as `-`.
+func reportValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// reportTime returns the time for a table cell, zero times are shown as `-`.
+func reportTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
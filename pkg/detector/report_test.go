@@ -0,0 +1,60 @@
+package detector
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_MarkdownReport(t *testing.T) {
+	newNode := func(name string, role string, tick string, status corev1.ConditionStatus, transitionAge time.Duration) *corev1.Node {
+		node := testNode(name).
+			WithRole(role).
+			WithAnnotation(annotationNodeNotReadyTick, tick).
+			WithCondition(corev1.NodeReady, status, time.Minute).
+			Build()
+		node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(testNow.Add(-transitionAge))
+		return &node
+	}
+
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	d, err := NewDetector(Config{
+		Clock:  &FakeClock{Time: testNow},
+		Logger: logger,
+		K8sClient: fake.NewClientBuilder().WithObjects(
+			newNode("master1", labelNodeRoleMaster, "0", corev1.ConditionTrue, time.Hour),
+			newNode("worker1", labelNodeRoleWorker, "6", corev1.ConditionFalse, time.Minute*10),
+			newNode("worker2", labelNodeRoleWorker, "4", corev1.ConditionFalse, time.Minute*5),
+			newNode("worker3", labelNodeRoleWorker, "2", corev1.ConditionTrue, time.Hour),
+		).Build(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := d.MarkdownReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLines := []string{
+		"| Node Name | Role | Tick Count | Threshold | Status | Unhealthy Conditions | First Unhealthy At |",
+		"| --- | --- | --- | --- | --- | --- | --- |",
+		"| worker1 | worker | 6 | 6 | Critical | Ready | 2023-11-09T11:50:00Z |",
+		"| worker2 | worker | 4 | 6 | Warning | Ready | 2023-11-09T11:55:00Z |",
+		"| worker3 | worker | 2 | 6 | Healthy | - | - |",
+		"| master1 | master | 0 | 6 | Healthy | - | - |",
+	}
+	lines := strings.Split(strings.TrimSuffix(report, "\n"), "\n")
+	if !cmp.Equal(lines, expectedLines) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedLines, lines))
+	}
+}
@@ -0,0 +1,161 @@
+package detector
+
+import (
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BadNodeReason describes why a node was 'marked for termination'.
+type BadNodeReason string
+
+const (
+	// BadNodeReasonTickThreshold is the reason of nodes which reached the not ready tick threshold.
+	BadNodeReasonTickThreshold BadNodeReason = "TickThreshold"
+	// BadNodeReasonIdleCordoned is the reason of nodes which are cordoned for longer than IdleCordonedNodeDuration.
+	BadNodeReasonIdleCordoned BadNodeReason = "IdleCordoned"
+	// BadNodeReasonNeverReady is the reason of nodes which did not become Ready within NeverReadyTimeout.
+	BadNodeReasonNeverReady BadNodeReason = "NeverReady"
+	// BadNodeReasonLifetimeTickThreshold is the reason of nodes which reached the LifetimeTickThreshold.
+	BadNodeReasonLifetimeTickThreshold BadNodeReason = "LifetimeTickThreshold"
+
+	// BadNodeReasonNotReady groups nodes which reached the tick threshold with a Ready condition which is not true,
+	// see DetectBadNodesResult.ByReason.
+	BadNodeReasonNotReady BadNodeReason = "NotReady"
+	// BadNodeReasonDiskFull groups nodes which reached the tick threshold with a full disk,
+	// see DetectBadNodesResult.ByReason.
+	BadNodeReasonDiskFull BadNodeReason = "DiskFull"
+)
+
+// BadNode is a node 'marked for termination' together with details about the detection.
+type BadNode struct {
+	Node corev1.Node
+	// TickCount is the not ready tick count of the node after the detection run.
+	TickCount int
+	// LifecycleState is the lifecycle state of the node at the time of the detection run.
+	LifecycleState NodeLifecycleState
+	// Reason is the reason why the node was 'marked for termination'.
+	Reason BadNodeReason
+	// MachineType, Zone and Region describe the cloud instance of the node, ie: for cost attribution.
+	// They are empty when the node does not carry the well-known labels.
+	MachineType string
+	Zone        string
+	Region      string
+	// Conditions contains the types of the unhealthy conditions of the node at the time of the detection run.
+	Conditions []string
+}
+
+// DetectBadNodesResult is the result of a single DetectBadNodesWithResult run.
+type DetectBadNodesResult struct {
+	// BadNodes contains the nodes 'marked for termination'.
+	BadNodes []BadNode
+	// LifecycleStateCounts contains the number of nodes 'marked for termination' per lifecycle state.
+	LifecycleStateCounts map[NodeLifecycleState]int
+	// RunTime is the time at which the detection run started.
+	RunTime time.Time
+	// DegradedMode is set when the nodes could not be listed and the result of the last successful run
+	// is returned instead, see Config.DegradedMode.
+	DegradedMode *DegradedModeResult
+}
+
+// Nodes returns the nodes 'marked for termination'.
+func (r DetectBadNodesResult) Nodes() []corev1.Node {
+	var nodes []corev1.Node
+	for _, b := range r.BadNodes {
+		nodes = append(nodes, b.Node)
+	}
+	return nodes
+}
+
+// ByReason returns the nodes 'marked for termination' grouped by the reason, ie: to route them to different playbooks.
+// Nodes which reached the tick threshold are grouped by their unhealthy conditions, BadNodeReasonNotReady takes
+// precedence over BadNodeReasonDiskFull. Nodes without unhealthy conditions, ie: because of a stale heartbeat,
+// are grouped as BadNodeReasonTickThreshold.
+func (r DetectBadNodesResult) ByReason() map[BadNodeReason][]corev1.Node {
+	byReason := map[BadNodeReason][]corev1.Node{}
+	for _, b := range r.BadNodes {
+		reason := b.Reason
+		if reason == BadNodeReasonTickThreshold {
+			reason = conditionsReason(b.Conditions)
+		}
+		byReason[reason] = append(byReason[reason], b.Node)
+	}
+	return byReason
+}
+
+// conditionsReason returns the reason of a node which reached the tick threshold with the given unhealthy conditions.
+// The Ready condition is the only condition which must be true, all others report a full disk.
+func conditionsReason(conditions []string) BadNodeReason {
+	if len(conditions) == 0 {
+		return BadNodeReasonTickThreshold
+	}
+	for _, c := range conditions {
+		if c == string(corev1.NodeReady) {
+			return BadNodeReasonNotReady
+		}
+	}
+	return BadNodeReasonDiskFull
+}
+
+func (d *Detector) newDetectBadNodesResult(badNodes []corev1.Node, r *detectionRun) DetectBadNodesResult {
+	result := DetectBadNodesResult{
+		LifecycleStateCounts: map[NodeLifecycleState]int{},
+		RunTime:              r.now,
+	}
+
+	for _, n := range badNodes {
+		b := BadNode{
+			Node:           n,
+			TickCount:      nodeTickCount(n, d.tickAnnotationKey),
+			LifecycleState: nodeLifecycleState(n, r.now, d.newNodeGracePeriod, d.establishedNodeAge),
+			Reason:         r.reasons[n.Name],
+			MachineType:    nodeLabel(n, corev1.LabelInstanceTypeStable, corev1.LabelInstanceType),
+			Zone:           nodeLabel(n, corev1.LabelTopologyZone, corev1.LabelFailureDomainBetaZone),
+			Region:         nodeLabel(n, corev1.LabelTopologyRegion, corev1.LabelFailureDomainBetaRegion),
+		}
+		for _, c := range d.healthCheck.unhealthyConditions(n) {
+			b.Conditions = append(b.Conditions, string(c.Type))
+		}
+		result.BadNodes = append(result.BadNodes, b)
+		result.LifecycleStateCounts[b.LifecycleState]++
+	}
+
+	return result
+}
+
+// nodeLabel returns the value of the first of the given labels set on the node,
+// so the stable label can be preferred over its deprecated beta variant.
+func nodeLabel(n corev1.Node, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := n.Labels[k]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// sortBadNodes sorts the nodes by tick count in descending order and nodes with the same tick count
+// by their lifecycle state, so established nodes are preferred over provisioning nodes.
+func (d *Detector) sortBadNodes(badNodes []corev1.Node, now time.Time) {
+	sort.SliceStable(badNodes, func(i, j int) bool {
+		ti := nodeTickCount(badNodes[i], d.tickAnnotationKey)
+		tj := nodeTickCount(badNodes[j], d.tickAnnotationKey)
+		if ti != tj {
+			return ti > tj
+		}
+
+		si := nodeLifecycleState(badNodes[i], now, d.newNodeGracePeriod, d.establishedNodeAge)
+		sj := nodeLifecycleState(badNodes[j], now, d.newNodeGracePeriod, d.establishedNodeAge)
+		return lifecycleStateTerminationOrder(si) < lifecycleStateTerminationOrder(sj)
+	})
+}
+
+// nodeTickCount returns the current tick count of the node, missing or invalid values count as 0.
+func nodeTickCount(n corev1.Node, tickAnnotationKey string) int {
+	tick, err := parseTickCount(n.Annotations[tickAnnotationKey])
+	if err != nil {
+		return 0
+	}
+	return tick
+}
@@ -0,0 +1,320 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodesWithResult(t *testing.T) {
+	newNode := func(name string, tick string, age time.Duration) *corev1.Node {
+		node := testNode(name).
+			WithRole(labelNodeRoleWorker).
+			WithCreationTime(testNow.Add(-age)).
+			WithAnnotation(annotationNodeNotReadyTick, tick).
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+			Build()
+		return &node
+	}
+
+	testCases := []struct {
+		name                         string
+		nodes                        []client.Object
+		sortBadNodesByTickCount      bool
+		maxNodeTerminationPercentage float64
+		expectedBadNodes             []string
+		expectedLifecycleStateCounts map[NodeLifecycleState]int
+	}{
+		{
+			name: "test 0 - lifecycle states",
+			nodes: []client.Object{
+				newNode("provisioning1", "5", time.Minute*5),
+				newNode("unknown1", "5", time.Hour*6),
+				newNode("established1", "6", time.Hour*48),
+				newNode("established2", "5", time.Hour*48),
+			},
+			sortBadNodesByTickCount:      true,
+			maxNodeTerminationPercentage: 1,
+			expectedBadNodes:             []string{"established1", "established2", "unknown1", "provisioning1"},
+			expectedLifecycleStateCounts: map[NodeLifecycleState]int{
+				NodeLifecycleStateProvisioning: 1,
+				NodeLifecycleStateUnknown:      1,
+				NodeLifecycleStateEstablished:  2,
+			},
+		},
+		{
+			name: "test 1 - highest tick count first",
+			nodes: []client.Object{
+				newNode("established1", "5", time.Hour*48),
+				newNode("established2", "8", time.Hour*48),
+				newNode("provisioning1", "9", time.Minute*5),
+			},
+			sortBadNodesByTickCount:      true,
+			maxNodeTerminationPercentage: 1,
+			expectedBadNodes:             []string{"provisioning1", "established2", "established1"},
+			expectedLifecycleStateCounts: map[NodeLifecycleState]int{
+				NodeLifecycleStateProvisioning: 1,
+				NodeLifecycleStateEstablished:  2,
+			},
+		},
+		{
+			name: "test 2 - limited termination prefers established nodes",
+			nodes: []client.Object{
+				newNode("provisioning1", "5", time.Minute*5),
+				newNode("provisioning2", "5", time.Minute*5),
+				newNode("established1", "5", time.Hour*48),
+				newNode("worker1", "0", time.Hour*48),
+			},
+			sortBadNodesByTickCount:      true,
+			maxNodeTerminationPercentage: 0.25,
+			expectedBadNodes:             []string{"established1"},
+			expectedLifecycleStateCounts: map[NodeLifecycleState]int{
+				NodeLifecycleStateEstablished: 1,
+			},
+		},
+		{
+			name: "test 3 - no node reached the threshold",
+			nodes: []client.Object{
+				newNode("worker1", "0", time.Hour*48),
+			},
+			maxNodeTerminationPercentage: 1,
+			expectedBadNodes:             nil,
+			expectedLifecycleStateCounts: map[NodeLifecycleState]int{},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    fake.NewClientBuilder().WithObjects(tc.nodes...).Build(),
+				MaxNodeTerminationPercentage: tc.maxNodeTerminationPercentage,
+				NotReadyTickThreshold:        6,
+				SortBadNodesByTickCount:      tc.sortBadNodesByTickCount,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := d.DetectBadNodesWithResult(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, b := range result.BadNodes {
+				names = append(names, b.Node.Name)
+				if b.TickCount < 6 {
+					t.Fatalf("Expected tick count of node %s to reach the threshold but got '%d'.\n", b.Node.Name, b.TickCount)
+				}
+			}
+
+			if !cmp.Equal(names, tc.expectedBadNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedBadNodes, names))
+			}
+			if !cmp.Equal(result.LifecycleStateCounts, tc.expectedLifecycleStateCounts) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedLifecycleStateCounts, result.LifecycleStateCounts))
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodesWithResult_neverReady(t *testing.T) {
+	newNode := func(name string, tick string, age time.Duration, transitionAge time.Duration) *corev1.Node {
+		node := testNode(name).
+			WithRole(labelNodeRoleWorker).
+			WithCreationTime(testNow.Add(-age)).
+			WithAnnotation(annotationNodeNotReadyTick, tick).
+			WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute).
+			Build()
+		node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(testNow.Add(-transitionAge))
+		return &node
+	}
+
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	d, err := NewDetector(Config{
+		Clock:  &FakeClock{Time: testNow},
+		Logger: logger,
+		K8sClient: fake.NewClientBuilder().WithObjects(
+			newNode("broken1", "0", time.Minute*20, time.Minute*20),
+			newNode("new1", "0", time.Minute*5, time.Minute*5),
+			newNode("worker1", "5", time.Hour*48, time.Minute*30),
+		).Build(),
+		MaxNodeTerminationPercentage: 1,
+		NeverReadyTimeout:            time.Minute * 15,
+		NotReadyTickThreshold:        6,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := d.DetectBadNodesWithResult(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reasons := map[string]BadNodeReason{}
+	for _, b := range result.BadNodes {
+		reasons[b.Node.Name] = b.Reason
+	}
+
+	expectedReasons := map[string]BadNodeReason{
+		"broken1": BadNodeReasonNeverReady,
+		"worker1": BadNodeReasonTickThreshold,
+	}
+	if !cmp.Equal(reasons, expectedReasons) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedReasons, reasons))
+	}
+}
+
+func Test_nodeLabel_cloudInstance(t *testing.T) {
+	testCases := []struct {
+		name                string
+		labels              map[string]string
+		expectedMachineType string
+		expectedZone        string
+		expectedRegion      string
+	}{
+		{
+			name: "test 0 - stable labels",
+			labels: map[string]string{
+				"node.kubernetes.io/instance-type": "m5.xlarge",
+				"topology.kubernetes.io/zone":      "eu-west-1a",
+				"topology.kubernetes.io/region":    "eu-west-1",
+			},
+			expectedMachineType: "m5.xlarge",
+			expectedZone:        "eu-west-1a",
+			expectedRegion:      "eu-west-1",
+		},
+		{
+			name: "test 1 - beta labels",
+			labels: map[string]string{
+				"beta.kubernetes.io/instance-type":         "m4.large",
+				"failure-domain.beta.kubernetes.io/zone":   "eu-west-1b",
+				"failure-domain.beta.kubernetes.io/region": "eu-west-1",
+			},
+			expectedMachineType: "m4.large",
+			expectedZone:        "eu-west-1b",
+			expectedRegion:      "eu-west-1",
+		},
+		{
+			name: "test 2 - stable labels are preferred",
+			labels: map[string]string{
+				"node.kubernetes.io/instance-type": "m5.xlarge",
+				"beta.kubernetes.io/instance-type": "m4.large",
+			},
+			expectedMachineType: "m5.xlarge",
+		},
+		{
+			name: "test 3 - no labels",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			b := testNode("worker1")
+			for k, v := range tc.labels {
+				b.WithLabel(k, v)
+			}
+			node := b.Build()
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Logger:    logger,
+				K8sClient: fake.NewClientBuilder().Build(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := d.newDetectBadNodesResult([]corev1.Node{node}, &detectionRun{now: testNow})
+			badNode := result.BadNodes[0]
+			if badNode.MachineType != tc.expectedMachineType {
+				t.Fatalf("Expected machine type '%s' but got '%s'.\n", tc.expectedMachineType, badNode.MachineType)
+			}
+			if badNode.Zone != tc.expectedZone {
+				t.Fatalf("Expected zone '%s' but got '%s'.\n", tc.expectedZone, badNode.Zone)
+			}
+			if badNode.Region != tc.expectedRegion {
+				t.Fatalf("Expected region '%s' but got '%s'.\n", tc.expectedRegion, badNode.Region)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodesResult_ByReason(t *testing.T) {
+	newBadNode := func(name string, reason BadNodeReason, conditions ...string) BadNode {
+		return BadNode{
+			Node:       testNode(name).Build(),
+			Reason:     reason,
+			Conditions: conditions,
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		badNodes         []BadNode
+		expectedByReason map[BadNodeReason][]string
+	}{
+		{
+			name: "test 0 - nodes with different reasons",
+			badNodes: []BadNode{
+				newBadNode("worker1", BadNodeReasonTickThreshold, "Ready"),
+				newBadNode("worker2", BadNodeReasonTickThreshold, "DiskFullKubelet"),
+				newBadNode("worker3", BadNodeReasonTickThreshold, "DiskPressure", "DiskFullVarLog"),
+				newBadNode("worker4", BadNodeReasonTickThreshold, "DiskPressure", "Ready"),
+				newBadNode("worker5", BadNodeReasonTickThreshold),
+				newBadNode("worker6", BadNodeReasonIdleCordoned),
+				newBadNode("worker7", BadNodeReasonNeverReady, "Ready"),
+			},
+			expectedByReason: map[BadNodeReason][]string{
+				BadNodeReasonNotReady:      {"worker1", "worker4"},
+				BadNodeReasonDiskFull:      {"worker2", "worker3"},
+				BadNodeReasonTickThreshold: {"worker5"},
+				BadNodeReasonIdleCordoned:  {"worker6"},
+				BadNodeReasonNeverReady:    {"worker7"},
+			},
+		},
+		{
+			name:             "test 1 - no bad nodes",
+			badNodes:         nil,
+			expectedByReason: map[BadNodeReason][]string{},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result := DetectBadNodesResult{BadNodes: tc.badNodes}
+
+			byReason := map[BadNodeReason][]string{}
+			for reason, nodes := range result.ByReason() {
+				for _, n := range nodes {
+					byReason[reason] = append(byReason[reason], n.Name)
+				}
+			}
+
+			if !cmp.Equal(byReason, tc.expectedByReason) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedByReason, byReason))
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package detector
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	labelNodeRoleMasterKubernetes       = "node-role.kubernetes.io/master"
+	labelNodeRoleControlPlaneKubernetes = "node-role.kubernetes.io/control-plane"
+	labelNodeRoleWorkerKubernetes       = "node-role.kubernetes.io/worker"
+)
+
+// validateNodeRole returns an ambiguousRoleError if the role labels of the node contradict each other,
+// ie: a node with the `role=worker` label and the `node-role.kubernetes.io/control-plane` label during a migration.
+func validateNodeRole(n corev1.Node) error {
+	var master, worker bool
+	switch n.Labels[labelNodeRole] {
+	case labelNodeRoleMaster:
+		master = true
+	case labelNodeRoleWorker:
+		worker = true
+	}
+	for _, l := range []string{labelNodeRoleMasterKubernetes, labelNodeRoleControlPlaneKubernetes} {
+		if _, ok := n.Labels[l]; ok {
+			master = true
+		}
+	}
+	if _, ok := n.Labels[labelNodeRoleWorkerKubernetes]; ok {
+		worker = true
+	}
+
+	if master && worker {
+		return microerror.Maskf(ambiguousRoleError, "node %s has both master and worker role labels, the %#q label decides the role", n.Name, labelNodeRole)
+	}
+	return nil
+}
+
+// logAmbiguousNodeRoles logs the nodes with contradicting role labels, as the master node limit
+// only considers the `role` label and might not treat them as expected.
+func logAmbiguousNodeRoles(ctx context.Context, logger micrologger.Logger, nodes []corev1.Node) {
+	for _, n := range nodes {
+		err := validateNodeRole(n)
+		if err != nil {
+			logger.Errorf(ctx, err, "ambiguous role of node %s", n.Name)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_validateNodeRole(t *testing.T) {
+	testCases := []struct {
+		name         string
+		labels       map[string]string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:   "test 0 - no role labels",
+			labels: nil,
+		},
+		{
+			name:   "test 1 - master role label",
+			labels: map[string]string{labelNodeRole: labelNodeRoleMaster, labelNodeRoleControlPlaneKubernetes: ""},
+		},
+		{
+			name:   "test 2 - worker role label",
+			labels: map[string]string{labelNodeRole: labelNodeRoleWorker, labelNodeRoleWorkerKubernetes: ""},
+		},
+		{
+			name:         "test 3 - worker role label with control plane label",
+			labels:       map[string]string{labelNodeRole: labelNodeRoleWorker, labelNodeRoleControlPlaneKubernetes: ""},
+			errorMatcher: IsAmbiguousRole,
+		},
+		{
+			name:         "test 4 - master role label with worker label",
+			labels:       map[string]string{labelNodeRole: labelNodeRoleMaster, labelNodeRoleWorkerKubernetes: ""},
+			errorMatcher: IsAmbiguousRole,
+		},
+		{
+			name:         "test 5 - kubernetes master and worker labels",
+			labels:       map[string]string{labelNodeRoleMasterKubernetes: "", labelNodeRoleWorkerKubernetes: ""},
+			errorMatcher: IsAmbiguousRole,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			b := testNode("node1")
+			for k, v := range tc.labels {
+				b.WithLabel(k, v)
+			}
+
+			err := validateNodeRole(b.Build())
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_ambiguousRole(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := micrologger.New(micrologger.Config{IOWriter: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := testNode("worker1").
+		WithRole(labelNodeRoleWorker).
+		WithLabel(labelNodeRoleControlPlaneKubernetes, "").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+
+	d, err := NewDetector(Config{
+		Clock:     &FakeClock{Time: testNow},
+		Logger:    logger,
+		K8sClient: fake.NewClientBuilder().WithObjects(&node).Build(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badNodes, err := d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the role label still decides the role
+	if len(badNodes) != 1 {
+		t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", 1, len(badNodes))
+	}
+	if !strings.Contains(buf.String(), "ambiguous role of node worker1") {
+		t.Fatalf("Expected ambiguous role to be logged, got %s.\n", buf.String())
+	}
+}
@@ -0,0 +1,127 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// annotationRollback tracks the annotations and labels changed during a run to be able to revert them.
+type annotationRollback struct {
+	// nodeNames maps the node UID to the node name.
+	nodeNames map[string]string
+	// originalAnnotations maps the node UID to the values of the changed annotations before the run.
+	// A nil value means the annotation did not exist before the run.
+	originalAnnotations map[string]map[string]*string
+	// originalLabels maps the node UID to the values of the changed labels before the run, ie: the BadNodeLabel.
+	// A nil value means the label did not exist before the run.
+	originalLabels map[string]map[string]*string
+}
+
+func newAnnotationRollback() *annotationRollback {
+	return &annotationRollback{
+		nodeNames:           map[string]string{},
+		originalAnnotations: map[string]map[string]*string{},
+		originalLabels:      map[string]map[string]*string{},
+	}
+}
+
+// track records the annotations and labels which differ between the original and the updated node.
+// Values recorded in a previous call for the same node are kept as they are the pre-run values.
+func (a *annotationRollback) track(original corev1.Node, updated corev1.Node) {
+	uid := string(updated.UID)
+	a.nodeNames[uid] = updated.Name
+
+	a.originalAnnotations[uid] = trackChangedValues(a.originalAnnotations[uid], original.Annotations, updated.Annotations)
+	if labels := trackChangedValues(a.originalLabels[uid], original.Labels, updated.Labels); len(labels) > 0 {
+		a.originalLabels[uid] = labels
+	}
+}
+
+// trackChangedValues adds the original values of the keys which differ between original and updated to tracked.
+func trackChangedValues(tracked map[string]*string, original map[string]string, updated map[string]string) map[string]*string {
+	if tracked == nil {
+		tracked = map[string]*string{}
+	}
+
+	changed := map[string]bool{}
+	for k, v := range updated {
+		if ov, ok := original[k]; !ok || ov != v {
+			changed[k] = true
+		}
+	}
+	for k := range original {
+		if _, ok := updated[k]; !ok {
+			changed[k] = true
+		}
+	}
+
+	for k := range changed {
+		if _, ok := tracked[k]; ok {
+			continue
+		}
+		if v, ok := original[k]; ok {
+			tracked[k] = &v
+		} else {
+			tracked[k] = nil
+		}
+	}
+
+	return tracked
+}
+
+// rollbackAnnotations reverts all annotation and label changes tracked during the run.
+// Failures are logged as the run already failed and the rollback is best effort.
+func (d *Detector) rollbackAnnotations(ctx context.Context, r *detectionRun) {
+	for uid, annotations := range r.rollback.originalAnnotations {
+		name := r.rollback.nodeNames[uid]
+		labels := r.rollback.originalLabels[uid]
+
+		err := d.patchMetadata(ctx, name, annotations, labels)
+		if err != nil {
+			r.logger.Errorf(ctx, err, "failed to revert annotations of node %s", name)
+			continue
+		}
+
+		for k := range annotations {
+			r.logger.LogCtx(ctx, "level", "debug", "message", "reverted annotation", "node", name, "annotation", k, "rollback", true)
+		}
+		for k := range labels {
+			r.logger.LogCtx(ctx, "level", "debug", "message", "reverted label", "node", name, "label", k, "rollback", true)
+		}
+	}
+}
+
+// patchMetadata sets the given annotations and labels on the node, keys with a nil value are removed.
+func (d *Detector) patchMetadata(ctx context.Context, name string, annotations map[string]*string, labels map[string]*string) error {
+	patch := struct {
+		Metadata struct {
+			Annotations map[string]*string `json:"annotations,omitempty"`
+			Labels      map[string]*string `json:"labels,omitempty"`
+		} `json:"metadata"`
+	}{}
+	patch.Metadata.Annotations = annotations
+	patch.Metadata.Labels = labels
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	err = d.k8sClient.Patch(ctx, node, client.RawPatch(types.MergePatchType, data))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// failingUpdateClient wraps a client and fails every Update call after the given number of successful calls.
+type failingUpdateClient struct {
+	client.Client
+
+	successfulUpdates int
+	updateCalls       int
+}
+
+func (c *failingUpdateClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.updateCalls++
+	if c.updateCalls > c.successfulUpdates {
+		return errors.New("connection refused")
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func Test_DetectBadNodes_rollbackOnError(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		rollbackOnError       bool
+		expectedChangedNodes  int
+		expectedUnchangedTick string
+		expectedChangedTick   string
+	}{
+		{
+			name:                  "test 0 - changes are kept without rollback",
+			rollbackOnError:       false,
+			expectedChangedNodes:  2,
+			expectedUnchangedTick: "2",
+			expectedChangedTick:   "3",
+		},
+		{
+			name:                  "test 1 - changes are reverted with rollback",
+			rollbackOnError:       true,
+			expectedChangedNodes:  0,
+			expectedUnchangedTick: "2",
+			expectedChangedTick:   "3",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var objects []client.Object
+			for j := 0; j < 3; j++ {
+				objects = append(objects, &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: fmt.Sprintf("worker%d", j),
+						UID:  types.UID(fmt.Sprintf("uid-%d", j)),
+						Annotations: map[string]string{
+							annotationNodeNotReadyTick: "2",
+						},
+					},
+					Status: corev1.NodeStatus{
+						Conditions: []corev1.NodeCondition{
+							{
+								Type:              corev1.NodeReady,
+								Status:            corev1.ConditionFalse,
+								LastHeartbeatTime: metav1.NewTime(testNow.Add(-time.Minute * 10)),
+							},
+						},
+					},
+				})
+			}
+
+			fakeClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+			logger, _ := micrologger.New(micrologger.Config{})
+
+			d, err := NewDetector(Config{
+				Clock:  &FakeClock{Time: testNow},
+				Logger: logger,
+				K8sClient: &failingUpdateClient{
+					Client:            fakeClient,
+					successfulUpdates: 2,
+				},
+				RollbackOnError: tc.rollbackOnError,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = d.DetectBadNodes(context.Background())
+			if !IsNodeUpdate(err) {
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			var nodeList corev1.NodeList
+			err = fakeClient.List(context.Background(), &nodeList)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			changedNodes := 0
+			for _, n := range nodeList.Items {
+				switch n.Annotations[annotationNodeNotReadyTick] {
+				case tc.expectedChangedTick:
+					changedNodes++
+				case tc.expectedUnchangedTick:
+				default:
+					t.Fatalf("Unexpected tick count '%s' for node %s.\n", n.Annotations[annotationNodeNotReadyTick], n.Name)
+				}
+			}
+
+			if changedNodes != tc.expectedChangedNodes {
+				t.Fatalf("Expected '%d' changed nodes but got '%d'.\n", tc.expectedChangedNodes, changedNodes)
+			}
+		})
+	}
+}
+
+func Test_annotationRollback_track(t *testing.T) {
+	original := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "worker1",
+			UID:  "uid-1",
+			Annotations: map[string]string{
+				annotationNodeNotReadyTick: "5",
+				annotationNodeCordonedAt:   "2023-11-09T11:55:00Z",
+				"unrelated":                "value",
+			},
+		},
+	}
+
+	updated := original.DeepCopy()
+	updated.Annotations[annotationNodeNotReadyTick] = "6"
+	updated.Annotations[NodeNotReadyFingerprintAnnotation] = "Ready=False"
+	delete(updated.Annotations, annotationNodeCordonedAt)
+
+	a := newAnnotationRollback()
+	a.track(original, *updated)
+
+	// a second change of the same node must keep the pre-run value
+	second := updated.DeepCopy()
+	second.Annotations[annotationNodeNotReadyTick] = "7"
+	a.track(*updated, *second)
+
+	annotations := a.originalAnnotations["uid-1"]
+	if len(annotations) != 3 {
+		t.Fatalf("Expected '3' tracked annotations but got '%d'.\n", len(annotations))
+	}
+	if v := annotations[annotationNodeNotReadyTick]; v == nil || *v != "5" {
+		t.Fatalf("Expected original tick count '5' but got '%v'.\n", v)
+	}
+	if v := annotations[annotationNodeCordonedAt]; v == nil || *v != "2023-11-09T11:55:00Z" {
+		t.Fatalf("Expected original cordoned at timestamp but got '%v'.\n", v)
+	}
+	if v, ok := annotations[NodeNotReadyFingerprintAnnotation]; !ok || v != nil {
+		t.Fatalf("Expected fingerprint to be tracked as absent but got '%v'.\n", v)
+	}
+	if a.nodeNames["uid-1"] != "worker1" {
+		t.Fatalf("Expected node name 'worker1' but got '%s'.\n", a.nodeNames["uid-1"])
+	}
+}
+
+func Test_annotationRollback_trackLabels(t *testing.T) {
+	const badNodeLabel = "example.com/bad-node"
+
+	original := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "worker1",
+			UID:  "uid-1",
+			Labels: map[string]string{
+				"unrelated": "value",
+			},
+		},
+	}
+
+	updated := original.DeepCopy()
+	setBadNodeLabel(updated, badNodeLabel, true)
+
+	a := newAnnotationRollback()
+	a.track(original, *updated)
+
+	labels := a.originalLabels["uid-1"]
+	if len(labels) != 1 {
+		t.Fatalf("Expected '1' tracked label but got '%d'.\n", len(labels))
+	}
+	if v, ok := labels[badNodeLabel]; !ok || v != nil {
+		t.Fatalf("Expected bad node label to be tracked as absent but got '%v'.\n", v)
+	}
+	if _, ok := a.originalAnnotations["uid-1"]; !ok {
+		t.Fatalf("Expected node to be tracked for the rollback.\n")
+	}
+}
@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodeSelector selects the nodes included by all of its filters. It implements NodeFilter,
+// so selectors can be used in Config.NodeFilters, ie:
+//
+//	ByRole("worker").And(ByTaintAbsent("example.com/maintenance"), Or(ByLabel("pool", "a"), ByLabel("pool", "b")))
+type NodeSelector []NodeFilter
+
+// Include returns true if the node is included by all filters of the selector.
+func (s NodeSelector) Include(node corev1.Node) bool {
+	return includeNode(node, s)
+}
+
+// Filter returns the nodes selected by the selector.
+func (s NodeSelector) Filter(nodes []corev1.Node) []corev1.Node {
+	return filterNodes(nodes, s)
+}
+
+// And returns a selector selecting the nodes selected by s and all given selectors.
+func (s NodeSelector) And(selectors ...NodeSelector) NodeSelector {
+	combined := append(NodeSelector{}, s...)
+	for _, selector := range selectors {
+		combined = append(combined, selector)
+	}
+	return combined
+}
+
+// Or returns a selector selecting the nodes selected by any of the given selectors.
+// Without selectors no node is selected.
+func Or(selectors ...NodeSelector) NodeSelector {
+	return NodeSelector{NodeFilterFunc(func(node corev1.Node) bool {
+		for _, selector := range selectors {
+			if selector.Include(node) {
+				return true
+			}
+		}
+		return false
+	})}
+}
+
+// ByLabel selects nodes with the given label. An empty value matches any value of the label.
+func ByLabel(key, value string) NodeSelector {
+	return NodeSelector{NodeFilterFunc(func(node corev1.Node) bool {
+		v, ok := node.Labels[key]
+		return ok && (value == "" || v == value)
+	})}
+}
+
+// ByAnnotationPresent selects nodes with the given annotation.
+func ByAnnotationPresent(key string) NodeSelector {
+	return NodeSelector{NodeFilterFunc(func(node corev1.Node) bool {
+		_, ok := node.Annotations[key]
+		return ok
+	})}
+}
+
+// ByAnnotationAbsent selects nodes without the given annotation.
+func ByAnnotationAbsent(key string) NodeSelector {
+	return NodeSelector{ExcludeAnnotationFilter(key, "")}
+}
+
+// ByRole selects nodes with the given value of the `role` label, ie: `master` or `worker`.
+func ByRole(role string) NodeSelector {
+	return ByLabel(labelNodeRole, role)
+}
+
+// ByCreationAgeAtLeast selects nodes which were created at least minAge ago.
+func ByCreationAgeAtLeast(clock Clock, minAge time.Duration) NodeSelector {
+	return NodeSelector{MinAgeFilter(clock, minAge)}
+}
+
+// ByTaintAbsent selects nodes without a taint of the given key.
+func ByTaintAbsent(key string) NodeSelector {
+	return NodeSelector{ExcludeTaintFilter(key, "")}
+}
@@ -0,0 +1,125 @@
+package detector
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_NodeSelector(t *testing.T) {
+	clock := &FakeClock{Time: testNow}
+
+	nodes := []corev1.Node{
+		testNode("master1").
+			WithRole(labelNodeRoleMaster).
+			WithCreationTime(testNow.Add(-time.Hour)).
+			Build(),
+		testNode("worker1").
+			WithRole(labelNodeRoleWorker).
+			WithLabel("pool", "a").
+			WithCreationTime(testNow.Add(-time.Hour)).
+			Build(),
+		testNode("worker2").
+			WithRole(labelNodeRoleWorker).
+			WithLabel("pool", "b").
+			WithAnnotation("example.com/maintenance", "planned").
+			WithCreationTime(testNow.Add(-time.Hour)).
+			Build(),
+		testNode("worker3").
+			WithRole(labelNodeRoleWorker).
+			WithLabel("pool", "c").
+			WithTaint("example.com/dedicated", "gpu", corev1.TaintEffectNoSchedule).
+			WithCreationTime(testNow.Add(-time.Hour)).
+			Build(),
+		testNode("worker4").
+			WithRole(labelNodeRoleWorker).
+			WithLabel("pool", "a").
+			WithCreationTime(testNow.Add(-time.Minute)).
+			Build(),
+	}
+
+	testCases := []struct {
+		name          string
+		selector      NodeSelector
+		expectedNodes []string
+	}{
+		{
+			name:          "test 0 - empty selector",
+			selector:      NodeSelector{},
+			expectedNodes: []string{"master1", "worker1", "worker2", "worker3", "worker4"},
+		},
+		{
+			name:          "test 1 - label with value",
+			selector:      ByLabel("pool", "a"),
+			expectedNodes: []string{"worker1", "worker4"},
+		},
+		{
+			name:          "test 2 - label with any value",
+			selector:      ByLabel("pool", ""),
+			expectedNodes: []string{"worker1", "worker2", "worker3", "worker4"},
+		},
+		{
+			name:          "test 3 - annotation present",
+			selector:      ByAnnotationPresent("example.com/maintenance"),
+			expectedNodes: []string{"worker2"},
+		},
+		{
+			name:          "test 4 - annotation absent",
+			selector:      ByAnnotationAbsent("example.com/maintenance"),
+			expectedNodes: []string{"master1", "worker1", "worker3", "worker4"},
+		},
+		{
+			name:          "test 5 - role",
+			selector:      ByRole(labelNodeRoleMaster),
+			expectedNodes: []string{"master1"},
+		},
+		{
+			name:          "test 6 - creation age",
+			selector:      ByCreationAgeAtLeast(clock, time.Minute*10),
+			expectedNodes: []string{"master1", "worker1", "worker2", "worker3"},
+		},
+		{
+			name:          "test 7 - taint absent",
+			selector:      ByTaintAbsent("example.com/dedicated"),
+			expectedNodes: []string{"master1", "worker1", "worker2", "worker4"},
+		},
+		{
+			name:          "test 8 - and",
+			selector:      ByRole(labelNodeRoleWorker).And(ByTaintAbsent("example.com/dedicated"), ByCreationAgeAtLeast(clock, time.Minute*10)),
+			expectedNodes: []string{"worker1", "worker2"},
+		},
+		{
+			name:          "test 9 - or",
+			selector:      Or(ByLabel("pool", "b"), ByLabel("pool", "c")),
+			expectedNodes: []string{"worker2", "worker3"},
+		},
+		{
+			name:          "test 10 - or without selectors",
+			selector:      Or(),
+			expectedNodes: nil,
+		},
+		{
+			name:          "test 11 - and with or",
+			selector:      ByAnnotationAbsent("example.com/maintenance").And(Or(ByRole(labelNodeRoleMaster), ByLabel("pool", "b"), ByLabel("pool", "c"))),
+			expectedNodes: []string{"master1", "worker3"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var names []string
+			for _, n := range tc.selector.Filter(nodes) {
+				names = append(names, n.Name)
+			}
+
+			if !cmp.Equal(names, tc.expectedNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodes, names))
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package detector
+
+import (
+	"context"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// removeSelfNode removes the node the detector runs on from the bad nodes, as terminating it would interrupt the detector.
+func (d *Detector) removeSelfNode(ctx context.Context, logger micrologger.Logger, badNodes []corev1.Node) []corev1.Node {
+	if d.selfNodeName == "" {
+		return badNodes
+	}
+
+	var filteredNodes []corev1.Node
+	for _, n := range badNodes {
+		if n.Name == d.selfNodeName {
+			logger.Debugf(ctx, "held back node %s as the detector runs on it", n.Name)
+			continue
+		}
+		filteredNodes = append(filteredNodes, n)
+	}
+	return filteredNodes
+}
@@ -0,0 +1,122 @@
+package detector
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func Test_DetectBadNodes_selfNodeName(t *testing.T) {
+	testCases := []struct {
+		name              string
+		selfNodeName      string
+		expectedNodeNames []string
+	}{
+		{
+			name:              "test 0 - all bad nodes are returned by default",
+			expectedNodeNames: []string{"worker1", "worker2"},
+		},
+		{
+			name:              "test 1 - unhealthy self node is excluded",
+			selfNodeName:      "worker1",
+			expectedNodeNames: []string{"worker2"},
+		},
+		{
+			name:              "test 2 - unknown self node does not exclude other nodes",
+			selfNodeName:      "worker3",
+			expectedNodeNames: []string{"worker1", "worker2"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			var objects []client.Object
+			for _, name := range []string{"worker1", "worker2"} {
+				node := testNode(name).
+					WithAnnotation(annotationNodeNotReadyTick, "5").
+					WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+					Build()
+				objects = append(objects, &node)
+			}
+
+			logger, _ := micrologger.New(micrologger.Config{})
+			k8sClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                        &FakeClock{Time: testNow},
+				Logger:                       logger,
+				K8sClient:                    k8sClient,
+				MaxNodeTerminationPercentage: 1,
+				SelfNodeName:                 tc.selfNodeName,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var nodeNames []string
+			for _, n := range badNodes {
+				nodeNames = append(nodeNames, n.Name)
+			}
+			sort.Strings(nodeNames)
+			if !cmp.Equal(nodeNames, tc.expectedNodeNames) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNodeNames, nodeNames))
+			}
+
+			// the tick count of the self node is still updated
+			var n corev1.Node
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.Annotations[annotationNodeNotReadyTick] != "6" {
+				t.Fatalf("Expected tick '6' but got '%s'.\n", n.Annotations[annotationNodeNotReadyTick])
+			}
+		})
+	}
+}
+
+func Test_NodeReconciler_selfNodeName(t *testing.T) {
+	node := testNode("worker1").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	d, err := NewDetector(Config{
+		Clock:        &FakeClock{Time: testNow},
+		Logger:       logger,
+		K8sClient:    fake.NewClientBuilder().WithObjects(&node).Build(),
+		SelfNodeName: "worker1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &NodeReconciler{Detector: d}
+	result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "worker1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the self node is not requeued for termination but keeps being ticked
+	if result.RequeueAfter != defaultReconcileInterval {
+		t.Fatalf("Expected the self node to be requeued after '%s' but got '%s'.\n", defaultReconcileInterval, result.RequeueAfter)
+	}
+}
@@ -0,0 +1,77 @@
+// Package simulation runs the bad node detection against simulated node states instead of a Kubernetes api server,
+// ie: to test detection scenarios or in documentation examples.
+package simulation
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/detector"
+)
+
+// SimNodeState is the simulated state of a node before the detection run.
+type SimNodeState struct {
+	// TickCount is the not ready tick count of the node before the run.
+	TickCount int
+	// Conditions are the node conditions, the heartbeat times are compared with the Clock of the config.
+	Conditions []corev1.NodeCondition
+	Labels     map[string]string
+}
+
+// SimulateDetection runs a single detection over the given node states keyed by node name
+// and returns the nodes 'marked for termination'.
+// The K8sClient of the config is replaced by an in-memory client holding the simulated nodes,
+// so the real cluster is never touched. Logs are discarded when the config has no Logger.
+func SimulateDetection(ctx context.Context, config detector.Config, nodeStates map[string]SimNodeState) ([]corev1.Node, error) {
+	if config.Logger == nil {
+		logger, err := micrologger.New(micrologger.Config{IOWriter: io.Discard})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		config.Logger = logger
+	}
+	if config.TickAnnotationKey == "" {
+		config.TickAnnotationKey = detector.DefaultConfig().TickAnnotationKey
+	}
+
+	var objects []client.Object
+	for name, state := range nodeStates {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				// the detector caches the health check results by uid
+				UID:    types.UID(name),
+				Labels: state.Labels,
+				Annotations: map[string]string{
+					config.TickAnnotationKey: strconv.Itoa(state.TickCount),
+				},
+			},
+			Status: corev1.NodeStatus{
+				Conditions: state.Conditions,
+			},
+		}
+		objects = append(objects, node)
+	}
+	config.K8sClient = fake.NewClientBuilder().WithObjects(objects...).Build()
+
+	d, err := detector.NewDetector(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	badNodes, err := d.DetectBadNodes(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return badNodes, nil
+}
@@ -0,0 +1,86 @@
+package simulation
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/badnodedetector/v3/pkg/detector"
+)
+
+var testNow = time.Date(2023, 11, 9, 12, 0, 0, 0, time.UTC)
+
+func readyCondition(status corev1.ConditionStatus) []corev1.NodeCondition {
+	return []corev1.NodeCondition{
+		{
+			Type:              corev1.NodeReady,
+			Status:            status,
+			LastHeartbeatTime: metav1.NewTime(testNow.Add(-time.Minute * 10)),
+		},
+	}
+}
+
+func Test_SimulateDetection(t *testing.T) {
+	testCases := []struct {
+		name             string
+		nodeStates       map[string]SimNodeState
+		expectedBadNodes []string
+	}{
+		{
+			name: "test 0 - healthy cluster",
+			nodeStates: map[string]SimNodeState{
+				"worker1": {TickCount: 0, Conditions: readyCondition(corev1.ConditionTrue)},
+				"worker2": {TickCount: 5, Conditions: readyCondition(corev1.ConditionTrue)},
+			},
+			expectedBadNodes: nil,
+		},
+		{
+			name: "test 1 - node reaching the threshold",
+			nodeStates: map[string]SimNodeState{
+				"worker1": {TickCount: 5, Conditions: readyCondition(corev1.ConditionFalse)},
+				"worker2": {TickCount: 4, Conditions: readyCondition(corev1.ConditionFalse)},
+				"worker3": {TickCount: 0, Conditions: readyCondition(corev1.ConditionTrue)},
+			},
+			expectedBadNodes: []string{"worker1"},
+		},
+		{
+			name: "test 2 - single master node",
+			nodeStates: map[string]SimNodeState{
+				"master1": {TickCount: 6, Conditions: readyCondition(corev1.ConditionFalse), Labels: map[string]string{"role": "master"}},
+				"master2": {TickCount: 6, Conditions: readyCondition(corev1.ConditionFalse), Labels: map[string]string{"role": "master"}},
+				"worker1": {TickCount: 6, Conditions: readyCondition(corev1.ConditionFalse), Labels: map[string]string{"role": "worker"}},
+			},
+			expectedBadNodes: []string{"master1", "worker1"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			config := detector.DefaultConfig()
+			config.Clock = &detector.FakeClock{Time: testNow}
+			config.MaxNodeTerminationPercentage = 1
+
+			badNodes, err := SimulateDetection(context.Background(), config, tc.nodeStates)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, n := range badNodes {
+				names = append(names, n.Name)
+			}
+			sort.Strings(names)
+			if !cmp.Equal(names, tc.expectedBadNodes) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedBadNodes, names))
+			}
+		})
+	}
+}
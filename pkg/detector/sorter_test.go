@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const propertyTestIterations = 10000
+
+// randomNodeList returns a shuffled list of master and worker nodes with unique names.
+func randomNodeList(seed int64, masterCount, workerCount int) []corev1.Node {
+	var nodes []corev1.Node
+	for i := 0; i < masterCount; i++ {
+		nodes = append(nodes, testNode(fmt.Sprintf("master%d", i)).WithRole(labelNodeRoleMaster).Build())
+	}
+	for i := 0; i < workerCount; i++ {
+		nodes = append(nodes, testNode(fmt.Sprintf("worker%d", i)).WithRole(labelNodeRoleWorker).Build())
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(nodes), func(i, j int) {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	})
+	return nodes
+}
+
+func countNodesWithRole(nodes []corev1.Node, role string) int {
+	var count int
+	for _, n := range nodes {
+		if n.Labels[labelNodeRole] == role {
+			count++
+		}
+	}
+	return count
+}
+
+func Test_removeMultipleMasterNodes_properties(t *testing.T) {
+	property := func(seed int64, masterCount, workerCount uint8) bool {
+		nodes := randomNodeList(seed, int(masterCount), int(workerCount))
+
+		filtered := removeMultipleMasterNodes(nodes)
+
+		// exactly one master node is kept when there is at least one
+		masters := countNodesWithRole(filtered, labelNodeRoleMaster)
+		if masterCount > 0 && masters != 1 {
+			return false
+		}
+		if masterCount == 0 && masters != 0 {
+			return false
+		}
+
+		// worker nodes are unaffected
+		return countNodesWithRole(filtered, labelNodeRoleWorker) == int(workerCount)
+	}
+
+	err := quick.Check(property, &quick.Config{MaxCount: propertyTestIterations})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_maximumNodeTermination_properties(t *testing.T) {
+	property := func(nodeCount uint16, percentage uint16) bool {
+		// the node count and percentage of a detection run, at least 1 node and a percentage within (0, 1]
+		n := int(nodeCount) + 1
+		p := float64(int(percentage)%1000+1) / 1000
+
+		limit := maximumNodeTermination(n, p)
+
+		return limit >= 1 && limit <= n
+	}
+
+	err := quick.Check(property, &quick.Config{MaxCount: propertyTestIterations})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,30 @@
+package detector
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// checkNodeDataFreshness returns a staleNodeDataError if the node data was synced longer than MaxNodeDataStaleness ago,
+// ie: because the watch of an informer backed client is broken. Nodes which never synced are stale.
+func (d *Detector) checkNodeDataFreshness(ctx context.Context, now time.Time) error {
+	if d.maxNodeDataStaleness == 0 {
+		return nil
+	}
+
+	lastSync, err := d.nodeDataFreshness(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if lastSync.IsZero() {
+		return microerror.Maskf(staleNodeDataError, "node data was never synced")
+	}
+	if staleness := now.Sub(lastSync); staleness > d.maxNodeDataStaleness {
+		return microerror.Maskf(staleNodeDataError, "node data was synced %s ago at %s, which is more than %s", staleness, lastSync.Format(time.RFC3339), d.maxNodeDataStaleness)
+	}
+
+	return nil
+}
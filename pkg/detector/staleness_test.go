@@ -0,0 +1,128 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_DetectBadNodes_maxNodeDataStaleness(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		maxNodeDataStaleness time.Duration
+		lastSync             time.Time
+		freshnessError       error
+		expectedBadNodes     int
+		expectedTick         string
+		errorMatcher         func(error) bool
+	}{
+		{
+			name:             "test 0 - staleness is not checked by default",
+			lastSync:         testNow.Add(-time.Hour),
+			expectedBadNodes: 1,
+			expectedTick:     "6",
+		},
+		{
+			name:                 "test 1 - fresh node data is acted on",
+			maxNodeDataStaleness: time.Minute * 5,
+			lastSync:             testNow.Add(-time.Minute),
+			expectedBadNodes:     1,
+			expectedTick:         "6",
+		},
+		{
+			name:                 "test 2 - stale cache suppresses termination",
+			maxNodeDataStaleness: time.Minute * 5,
+			lastSync:             testNow.Add(-time.Minute * 10),
+			expectedTick:         "5",
+			errorMatcher:         IsStaleNodeData,
+		},
+		{
+			name:                 "test 3 - never synced cache suppresses termination",
+			maxNodeDataStaleness: time.Minute * 5,
+			expectedTick:         "5",
+			errorMatcher:         IsStaleNodeData,
+		},
+		{
+			name:                 "test 4 - failing freshness check suppresses termination",
+			maxNodeDataStaleness: time.Minute * 5,
+			lastSync:             testNow,
+			freshnessError:       errors.New("informer not started"),
+			expectedTick:         "5",
+			errorMatcher: func(err error) bool {
+				return err != nil && !IsStaleNodeData(err)
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			node := testNode("worker1").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build()
+
+			logger, _ := micrologger.New(micrologger.Config{})
+			k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+
+			d, err := NewDetector(Config{
+				Clock:                &FakeClock{Time: testNow},
+				Logger:               logger,
+				K8sClient:            k8sClient,
+				MaxNodeDataStaleness: tc.maxNodeDataStaleness,
+				NodeDataFreshness: func(ctx context.Context) (time.Time, error) {
+					return tc.lastSync, tc.freshnessError
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			badNodes, err := d.DetectBadNodes(context.Background())
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if len(badNodes) != tc.expectedBadNodes {
+				t.Fatalf("Expected '%d' bad nodes but got '%d'.\n", tc.expectedBadNodes, len(badNodes))
+			}
+
+			var n corev1.Node
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.Annotations[annotationNodeNotReadyTick] != tc.expectedTick {
+				t.Fatalf("Expected tick '%s' but got '%s'.\n", tc.expectedTick, n.Annotations[annotationNodeNotReadyTick])
+			}
+		})
+	}
+}
+
+func Test_NewDetector_maxNodeDataStaleness(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	_, err := NewDetector(Config{
+		Logger:               logger,
+		K8sClient:            fake.NewClientBuilder().Build(),
+		MaxNodeDataStaleness: time.Minute,
+	})
+	if !IsInvalidConfig(err) {
+		t.Fatalf("error == %#v, want matching", err)
+	}
+}
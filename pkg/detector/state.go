@@ -0,0 +1,173 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// stateConfigMapKey is the key of the ConfigMap data holding the JSON encoded DetectorState.
+	stateConfigMapKey = "state"
+)
+
+// DetectorState is the bookkeeping of the detector which is kept in memory between runs.
+// It is persisted in the StateStore, so the detector continues consistently after a restart.
+type DetectorState struct {
+	// LastRunTime is the time of the last successful run.
+	LastRunTime metav1.Time `json:"lastRunTime"`
+	// NodeCount is the number of nodes processed by the last successful run.
+	NodeCount int `json:"nodeCount"`
+	// UnhealthyObservations contains the number of consecutive runs a node was observed unhealthy, keyed by node UID.
+	// Only recorded when ConsecutiveUnhealthyRuns is set.
+	UnhealthyObservations map[string]int `json:"unhealthyObservations,omitempty"`
+}
+
+// StateStore persists the DetectorState across restarts.
+type StateStore interface {
+	// Load returns the persisted state, or an empty state if none was saved yet.
+	Load(ctx context.Context) (DetectorState, error)
+	// Save persists the state.
+	Save(ctx context.Context, state DetectorState) error
+}
+
+// ConfigMapStateStore is a StateStore persisting the state as JSON in the data of a ConfigMap.
+type ConfigMapStateStore struct {
+	k8sClient client.Client
+	namespace string
+	name      string
+
+	mutex sync.Mutex
+}
+
+// NewConfigMapStateStore returns a store using the ConfigMap with the given name,
+// the ConfigMap is created if it does not exist.
+func NewConfigMapStateStore(k8sClient client.Client, namespace string, name string) *ConfigMapStateStore {
+	return &ConfigMapStateStore{
+		k8sClient: k8sClient,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Load reads the state from the ConfigMap. A missing ConfigMap returns an empty state.
+func (s *ConfigMapStateStore) Load(ctx context.Context) (DetectorState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var configMap corev1.ConfigMap
+	err := s.k8sClient.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, &configMap)
+	if apierrors.IsNotFound(err) {
+		return DetectorState{}, nil
+	} else if err != nil {
+		return DetectorState{}, microerror.Mask(err)
+	}
+
+	var state DetectorState
+	data, ok := configMap.Data[stateConfigMapKey]
+	if !ok {
+		return DetectorState{}, nil
+	}
+	err = json.Unmarshal([]byte(data), &state)
+	if err != nil {
+		return DetectorState{}, microerror.Mask(err)
+	}
+
+	return state, nil
+}
+
+// Save writes the state to the ConfigMap.
+func (s *ConfigMapStateStore) Save(ctx context.Context, state DetectorState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = updateConfigMap(ctx, s.k8sClient, s.namespace, s.name, func(data map[string]string) (map[string]string, error) {
+		if data == nil {
+			data = map[string]string{}
+		}
+		data[stateConfigMapKey] = string(encoded)
+		return data, nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// loadState restores the state persisted by a previous detector once, before the first run.
+// Failures are logged and the state is loaded with the next run instead.
+func (d *Detector) loadState(ctx context.Context, logger micrologger.Logger) {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+
+	if d.stateStore == nil || d.stateLoaded {
+		return
+	}
+
+	state, err := d.stateStore.Load(ctx)
+	if err != nil {
+		logger.Errorf(ctx, err, "failed to load the detector state")
+		return
+	}
+	d.stateLoaded = true
+
+	if state.NodeCount > 0 {
+		d.nodeCount = state.NodeCount
+	}
+	if d.unhealthyDebounce != nil {
+		d.unhealthyDebounce.restore(state.UnhealthyObservations)
+	}
+	if !state.LastRunTime.IsZero() {
+		logger.Debugf(ctx, "loaded the detector state of the run at %s", state.LastRunTime.Format(time.RFC3339))
+	}
+}
+
+// saveState persists the state after a successful run. Failures are logged, as the state is saved again with the next run.
+func (d *Detector) saveState(ctx context.Context, logger micrologger.Logger, now time.Time, nodeCount int) {
+	if d.stateStore == nil {
+		return
+	}
+
+	state := DetectorState{
+		LastRunTime: metav1.NewTime(now),
+		NodeCount:   nodeCount,
+	}
+	if d.unhealthyDebounce != nil {
+		state.UnhealthyObservations = d.unhealthyDebounce.snapshot()
+	}
+
+	err := d.stateStore.Save(ctx, state)
+	if err != nil {
+		logger.Errorf(ctx, err, "failed to save the detector state")
+	}
+}
+
+// setNodeCount remembers the node count of the run for the dynamic threshold and the NodeStateEvents capacity of the next run.
+func (d *Detector) setNodeCount(nodeCount int) {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+
+	d.nodeCount = nodeCount
+}
+
+// previousNodeCount returns the node count of the previous run or of the loaded state, zero when unknown.
+func (d *Detector) previousNodeCount() int {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+
+	return d.nodeCount
+}
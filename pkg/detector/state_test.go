@@ -0,0 +1,170 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ConfigMapStateStore(t *testing.T) {
+	testCases := []struct {
+		name      string
+		configMap *corev1.ConfigMap
+		states    []DetectorState
+	}{
+		{
+			name: "test 0 - missing ConfigMap is created",
+			states: []DetectorState{
+				{
+					LastRunTime: metav1.NewTime(testNow),
+					NodeCount:   3,
+					UnhealthyObservations: map[string]int{
+						"worker1": 1,
+					},
+				},
+			},
+		},
+		{
+			name: "test 1 - state is updated",
+			states: []DetectorState{
+				{
+					LastRunTime: metav1.NewTime(testNow),
+					NodeCount:   3,
+					UnhealthyObservations: map[string]int{
+						"worker1": 1,
+					},
+				},
+				{
+					LastRunTime: metav1.NewTime(testNow.Add(time.Minute)),
+					NodeCount:   2,
+					UnhealthyObservations: map[string]int{
+						"worker1": 2,
+						"worker2": 1,
+					},
+				},
+			},
+		},
+		{
+			name: "test 2 - other data of an existing ConfigMap is kept",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "kube-system",
+					Name:      "badnodedetector-state",
+				},
+				Data: map[string]string{
+					"other": "value",
+				},
+			},
+			states: []DetectorState{
+				{
+					LastRunTime: metav1.NewTime(testNow),
+					NodeCount:   1,
+				},
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			builder := fake.NewClientBuilder()
+			if tc.configMap != nil {
+				builder = builder.WithObjects(tc.configMap)
+			}
+			k8sClient := builder.Build()
+
+			store := NewConfigMapStateStore(k8sClient, "kube-system", "badnodedetector-state")
+
+			state, err := store.Load(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(state, DetectorState{}) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(DetectorState{}, state))
+			}
+
+			for _, expected := range tc.states {
+				err = store.Save(context.Background(), expected)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// a new store reads the state like a restarted detector
+				state, err = NewConfigMapStateStore(k8sClient, "kube-system", "badnodedetector-state").Load(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(state, expected) {
+					t.Fatalf("\n\n%s\n", cmp.Diff(expected, state))
+				}
+			}
+
+			if tc.configMap != nil {
+				var configMap corev1.ConfigMap
+				err = k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "kube-system", Name: "badnodedetector-state"}, &configMap)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if configMap.Data["other"] != "value" {
+					t.Fatalf("expected other data of the ConfigMap to be kept")
+				}
+			}
+		})
+	}
+}
+
+func Test_DetectBadNodes_stateAcrossRestarts(t *testing.T) {
+	node := testNode("worker1").
+		WithRole(labelNodeRoleWorker).
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+	node.UID = types.UID("worker1")
+
+	logger, _ := micrologger.New(micrologger.Config{})
+	k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+
+	newDetector := func() *Detector {
+		d, err := NewDetector(Config{
+			Clock:                    &FakeClock{Time: testNow},
+			Logger:                   logger,
+			K8sClient:                k8sClient,
+			ConsecutiveUnhealthyRuns: 2,
+			StateConfigMap:           "badnodedetector-state",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	// the first observation is debounced
+	_, err := newDetector().DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the restarted detector continues with the observation of the previous detector
+	_, err = newDetector().DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n corev1.Node
+	err = k8sClient.Get(context.Background(), client.ObjectKey{Name: "worker1"}, &n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Annotations[annotationNodeNotReadyTick] != "1" {
+		t.Fatalf("expected tick count '1' but got '%s'", n.Annotations[annotationNodeNotReadyTick])
+	}
+}
@@ -0,0 +1,46 @@
+package detector
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DetectorStatus is a compact summary of a detection run which can be embedded into the status
+// of a custom resource, ie: copied into the status subresource on every reconciliation.
+type DetectorStatus struct {
+	// BadNodes contains the names of the nodes 'marked for termination'.
+	BadNodes []string `json:"badNodes,omitempty"`
+	// BadNodeCount is the number of nodes 'marked for termination'.
+	BadNodeCount int `json:"badNodeCount"`
+	// ReasonCounts contains the number of nodes 'marked for termination' per reason.
+	ReasonCounts map[string]int `json:"reasonCounts,omitempty"`
+	// LifecycleStateCounts contains the number of nodes 'marked for termination' per lifecycle state.
+	LifecycleStateCounts map[string]int `json:"lifecycleStateCounts,omitempty"`
+	// LastRunTime is the time at which the detection run started.
+	LastRunTime metav1.Time `json:"lastRunTime"`
+}
+
+// Status returns the status of the detection run suitable for embedding into a custom resource.
+func (r DetectBadNodesResult) Status() DetectorStatus {
+	status := DetectorStatus{
+		BadNodeCount: len(r.BadNodes),
+		LastRunTime:  metav1.NewTime(r.RunTime),
+	}
+
+	for _, b := range r.BadNodes {
+		status.BadNodes = append(status.BadNodes, b.Node.Name)
+		if b.Reason != "" {
+			if status.ReasonCounts == nil {
+				status.ReasonCounts = map[string]int{}
+			}
+			status.ReasonCounts[string(b.Reason)]++
+		}
+	}
+	for state, count := range r.LifecycleStateCounts {
+		if status.LifecycleStateCounts == nil {
+			status.LifecycleStateCounts = map[string]int{}
+		}
+		status.LifecycleStateCounts[string(state)] = count
+	}
+
+	return status
+}
@@ -0,0 +1,83 @@
+package detector
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_DetectBadNodesResult_Status(t *testing.T) {
+	testCases := []struct {
+		name           string
+		result         DetectBadNodesResult
+		expectedStatus DetectorStatus
+	}{
+		{
+			name: "test 0 - bad nodes",
+			result: DetectBadNodesResult{
+				BadNodes: []BadNode{
+					{Node: testNode("worker1").Build(), LifecycleState: NodeLifecycleStateEstablished, Reason: BadNodeReasonTickThreshold},
+					{Node: testNode("worker2").Build(), LifecycleState: NodeLifecycleStateProvisioning, Reason: BadNodeReasonNeverReady},
+					{Node: testNode("worker3").Build(), LifecycleState: NodeLifecycleStateEstablished, Reason: BadNodeReasonTickThreshold},
+				},
+				LifecycleStateCounts: map[NodeLifecycleState]int{
+					NodeLifecycleStateEstablished:  2,
+					NodeLifecycleStateProvisioning: 1,
+				},
+				RunTime: testNow,
+			},
+			expectedStatus: DetectorStatus{
+				BadNodes:     []string{"worker1", "worker2", "worker3"},
+				BadNodeCount: 3,
+				ReasonCounts: map[string]int{
+					"TickThreshold": 2,
+					"NeverReady":    1,
+				},
+				LifecycleStateCounts: map[string]int{
+					"Established":  2,
+					"Provisioning": 1,
+				},
+				LastRunTime: metav1.NewTime(testNow),
+			},
+		},
+		{
+			name: "test 1 - no bad nodes",
+			result: DetectBadNodesResult{
+				LifecycleStateCounts: map[NodeLifecycleState]int{},
+				RunTime:              testNow,
+			},
+			expectedStatus: DetectorStatus{
+				LastRunTime: metav1.NewTime(testNow),
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			status := tc.result.Status()
+			if !cmp.Equal(status, tc.expectedStatus) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedStatus, status))
+			}
+
+			data, err := json.Marshal(status)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded DetectorStatus
+			err = json.Unmarshal(data, &decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(decoded, status) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(status, decoded))
+			}
+		})
+	}
+}
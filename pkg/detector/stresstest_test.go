@@ -0,0 +1,179 @@
+//go:build stresstest
+// +build stresstest
+
+package detector
+
+import (
+	"context"
+	"flag"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// The stress test runs the detector against nodes which concurrently flip their conditions and are deleted
+// and re-created, to catch race conditions and invariant violations. Run it with the race detector:
+//
+//	go test -race -tags stresstest -run Test_stress ./pkg/detector -stress-duration 1m
+var (
+	stressDuration  = flag.Duration("stress-duration", time.Second*10, "duration of the stress test")
+	stressNodeCount = flag.Int("stress-nodes", 50, "number of nodes of the stress test")
+)
+
+func Test_stress(t *testing.T) {
+	const (
+		maxTerminationPercentage = 0.2
+		pauseBetweenTermination  = time.Millisecond * 200
+	)
+
+	nodeCount := *stressNodeCount
+	duration := *stressDuration
+
+	newNode := func(name string, status corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{
+					{
+						Type:   corev1.NodeReady,
+						Status: status,
+						// the heartbeat is old enough for the node to count as unhealthy right away
+						LastHeartbeatTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+					},
+				},
+			},
+		}
+	}
+	nodeName := func(i int) string {
+		return "worker" + strconv.Itoa(i)
+	}
+
+	var objects []client.Object
+	for i := 0; i < nodeCount; i++ {
+		objects = append(objects, newNode(nodeName(i), corev1.ConditionTrue))
+	}
+	k8sClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+	logger, err := micrologger.New(micrologger.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDetector(Config{
+		Logger:                       logger,
+		K8sClient:                    k8sClient,
+		MaxNodeTerminationPercentage: maxTerminationPercentage,
+		PauseBetweenTermination:      pauseBetweenTermination,
+		NotReadyTickThreshold:        3,
+		Parallelism:                  4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var runs int64
+	var terminations int64
+	var wg sync.WaitGroup
+
+	// the detector terminates the returned nodes and pauses before the next termination
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			badNodes, err := d.DetectBadNodes(ctx)
+			atomic.AddInt64(&runs, 1)
+			if err != nil {
+				// conflicts with the concurrent changes of the nodes are expected
+				continue
+			}
+
+			for i := range badNodes {
+				err = k8sClient.Delete(context.Background(), &badNodes[i])
+				if err == nil {
+					atomic.AddInt64(&terminations, 1)
+				}
+			}
+			if len(badNodes) > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(pauseBetweenTermination):
+				}
+			}
+		}
+	}()
+
+	// the condition flipper randomly changes the Ready condition of the nodes
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r := rand.New(rand.NewSource(1))
+		for ctx.Err() == nil {
+			var n corev1.Node
+			err := k8sClient.Get(context.Background(), client.ObjectKey{Name: nodeName(r.Intn(nodeCount))}, &n)
+			if err != nil {
+				continue
+			}
+
+			n.Status.Conditions[0].Status = corev1.ConditionTrue
+			if r.Intn(2) == 0 {
+				n.Status.Conditions[0].Status = corev1.ConditionFalse
+			}
+			_ = k8sClient.Update(context.Background(), &n)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// the churn deletes nodes and re-creates the missing nodes
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r := rand.New(rand.NewSource(2))
+		for ctx.Err() == nil {
+			_ = k8sClient.Delete(context.Background(), newNode(nodeName(r.Intn(nodeCount)), corev1.ConditionTrue))
+			for i := 0; i < nodeCount; i++ {
+				_ = k8sClient.Create(context.Background(), newNode(nodeName(i), corev1.ConditionTrue))
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}()
+
+	wg.Wait()
+
+	// every run increases the tick count at most by one
+	var nodeList corev1.NodeList
+	err = k8sClient.List(context.Background(), &nodeList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range nodeList.Items {
+		tick, ok := n.Annotations[annotationNodeNotReadyTick]
+		if !ok {
+			continue
+		}
+		count, err := strconv.Atoi(tick)
+		if err != nil || count < 0 || int64(count) > runs {
+			t.Fatalf("node %s has tick count %#q outside of [0, %d]", n.Name, tick, runs)
+		}
+	}
+
+	maxTerminations := int64(maximumNodeTermination(nodeCount, maxTerminationPercentage)) * (int64(duration/pauseBetweenTermination) + 1)
+	if terminations > maxTerminations {
+		t.Fatalf("expected at most %d terminations but got %d", maxTerminations, terminations)
+	}
+
+	t.Logf("%d runs terminated %d nodes", runs, terminations)
+}
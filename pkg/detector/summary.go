@@ -0,0 +1,86 @@
+package detector
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type runSummaryKey struct{}
+
+// RunSummary summarizes a single DetectBadNodes run.
+type RunSummary struct {
+	// NodeCount is the number of nodes handled by the run.
+	NodeCount int
+	// Threshold is the effective tick threshold of the run.
+	Threshold int
+	// BadNodeCount is the number of nodes 'marked for termination'.
+	BadNodeCount int
+	// Reasons contains the number of nodes 'marked for termination' per unhealthy condition type.
+	// Nodes marked for another reason than an unhealthy condition, ie: idle cordoned nodes, are not counted.
+	Reasons map[string]int
+	// LifecycleStateCounts contains the number of nodes 'marked for termination' per lifecycle state.
+	LifecycleStateCounts map[NodeLifecycleState]int
+	// EscalatedPools contains the node pools with an escalated tick threshold, see EscalationTerminations.
+	EscalatedPools []string
+}
+
+// runSummaryRecorder holds the summary published to a context. It is safe for concurrent use,
+// concurrent runs using the same context overwrite the summary of each other.
+type runSummaryRecorder struct {
+	mutex   sync.Mutex
+	summary *RunSummary
+}
+
+// ContextWithRunSummary returns a context in which DetectBadNodes publishes the summary of its run,
+// so middleware wrapping the call can read it via RunSummaryFromContext afterwards.
+func ContextWithRunSummary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, runSummaryKey{}, &runSummaryRecorder{})
+}
+
+// RunSummaryFromContext returns the summary of the last run using the context and true,
+// or false if the context was not prepared with ContextWithRunSummary or no run published a summary yet.
+func RunSummaryFromContext(ctx context.Context) (RunSummary, bool) {
+	recorder, ok := ctx.Value(runSummaryKey{}).(*runSummaryRecorder)
+	if !ok {
+		return RunSummary{}, false
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if recorder.summary == nil {
+		return RunSummary{}, false
+	}
+	return *recorder.summary, true
+}
+
+// publishRunSummary stores the summary of the run in the context, if the context was prepared for it.
+func (d *Detector) publishRunSummary(ctx context.Context, r *detectionRun, nodeCount int, result DetectBadNodesResult) {
+	recorder, ok := ctx.Value(runSummaryKey{}).(*runSummaryRecorder)
+	if !ok {
+		return
+	}
+
+	summary := &RunSummary{
+		NodeCount:            nodeCount,
+		Threshold:            r.threshold,
+		BadNodeCount:         len(result.BadNodes),
+		Reasons:              map[string]int{},
+		LifecycleStateCounts: result.LifecycleStateCounts,
+	}
+	for pool := range r.escalatedPools {
+		summary.EscalatedPools = append(summary.EscalatedPools, pool)
+	}
+	sort.Strings(summary.EscalatedPools)
+	for _, b := range result.BadNodes {
+		for _, c := range d.healthCheck.unhealthyConditions(b.Node) {
+			summary.Reasons[string(c.Type)]++
+		}
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.summary = summary
+}
@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_RunSummaryFromContext(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	notReady := testNode("worker1").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		Build()
+	diskFull := testNode("worker2").
+		WithAnnotation(annotationNodeNotReadyTick, "5").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+		WithCondition(corev1.NodeDiskPressure, corev1.ConditionTrue, time.Minute*10).
+		Build()
+	healthy := testNode("worker3").
+		WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+		Build()
+
+	d, err := NewDetector(Config{
+		Clock:                        &FakeClock{Time: testNow},
+		Logger:                       logger,
+		K8sClient:                    fake.NewClientBuilder().WithObjects(&notReady, &diskFull, &healthy).Build(),
+		MaxNodeTerminationPercentage: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without a prepared context no summary is published
+	_, err = d.DetectBadNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := RunSummaryFromContext(context.Background())
+	if ok {
+		t.Fatalf("Expected no summary for an unprepared context.\n")
+	}
+
+	ctx := ContextWithRunSummary(context.Background())
+	_, ok = RunSummaryFromContext(ctx)
+	if ok {
+		t.Fatalf("Expected no summary before the run.\n")
+	}
+
+	_, err = d.DetectBadNodes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, ok := RunSummaryFromContext(ctx)
+	if !ok {
+		t.Fatalf("Expected a summary after the run.\n")
+	}
+
+	expected := RunSummary{
+		NodeCount:    3,
+		Threshold:    defaultNotReadyTickThreshold,
+		BadNodeCount: 2,
+		Reasons: map[string]int{
+			string(corev1.NodeReady):        1,
+			string(corev1.NodeDiskPressure): 1,
+		},
+		LifecycleStateCounts: map[NodeLifecycleState]int{
+			NodeLifecycleStateUnknown: 2,
+		},
+	}
+	if !cmp.Equal(summary, expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, summary))
+	}
+}
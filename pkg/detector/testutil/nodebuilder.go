@@ -0,0 +1,113 @@
+// Package testutil provides helpers to construct Kubernetes objects in tests of the detector
+// and of packages using the detector.
+package testutil
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	labelNodeRole = "role"
+)
+
+type nodeCondition struct {
+	conditionType corev1.NodeConditionType
+	status        corev1.ConditionStatus
+	heartbeatAge  time.Duration
+}
+
+// NodeBuilder constructs corev1.Node test fixtures.
+//
+//	node := testutil.NewNode("worker1").
+//		WithRole("worker").
+//		WithCondition(corev1.NodeReady, corev1.ConditionFalse, 10*time.Minute).
+//		Build()
+type NodeBuilder struct {
+	node          corev1.Node
+	conditions    []nodeCondition
+	referenceTime time.Time
+}
+
+// NewNode returns a NodeBuilder for a node with the given name.
+func NewNode(name string) *NodeBuilder {
+	return &NodeBuilder{
+		node: corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+		},
+		referenceTime: time.Now(),
+	}
+}
+
+// WithReferenceTime sets the time the heartbeat age of conditions is relative to, defaults to the time
+// NewNode was called. Use the time of the detector clock to get deterministic results.
+func (b *NodeBuilder) WithReferenceTime(t time.Time) *NodeBuilder {
+	b.referenceTime = t
+	return b
+}
+
+// WithLabel adds the label to the node.
+func (b *NodeBuilder) WithLabel(key, value string) *NodeBuilder {
+	if b.node.Labels == nil {
+		b.node.Labels = map[string]string{}
+	}
+	b.node.Labels[key] = value
+	return b
+}
+
+// WithAnnotation adds the annotation to the node.
+func (b *NodeBuilder) WithAnnotation(key, value string) *NodeBuilder {
+	if b.node.Annotations == nil {
+		b.node.Annotations = map[string]string{}
+	}
+	b.node.Annotations[key] = value
+	return b
+}
+
+// WithCondition adds a condition to the node which last heartbeat is heartbeatAge before the reference time.
+func (b *NodeBuilder) WithCondition(condType corev1.NodeConditionType, status corev1.ConditionStatus, heartbeatAge time.Duration) *NodeBuilder {
+	b.conditions = append(b.conditions, nodeCondition{
+		conditionType: condType,
+		status:        status,
+		heartbeatAge:  heartbeatAge,
+	})
+	return b
+}
+
+// WithRole sets the `role` label of the node, ie: `master` or `worker`.
+func (b *NodeBuilder) WithRole(role string) *NodeBuilder {
+	return b.WithLabel(labelNodeRole, role)
+}
+
+// WithCreationTime sets the creation timestamp of the node.
+func (b *NodeBuilder) WithCreationTime(t time.Time) *NodeBuilder {
+	b.node.CreationTimestamp = metav1.NewTime(t)
+	return b
+}
+
+// WithTaint adds the taint to the node.
+func (b *NodeBuilder) WithTaint(key, value string, effect corev1.TaintEffect) *NodeBuilder {
+	b.node.Spec.Taints = append(b.node.Spec.Taints, corev1.Taint{
+		Key:    key,
+		Value:  value,
+		Effect: effect,
+	})
+	return b
+}
+
+// Build returns the node. The builder can be used further without affecting returned nodes.
+func (b *NodeBuilder) Build() corev1.Node {
+	node := b.node.DeepCopy()
+	for _, c := range b.conditions {
+		node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{
+			Type:              c.conditionType,
+			Status:            c.status,
+			LastHeartbeatTime: metav1.NewTime(b.referenceTime.Add(-c.heartbeatAge)),
+		})
+	}
+	return *node
+}
@@ -0,0 +1,78 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_NodeBuilder(t *testing.T) {
+	now := time.Date(2023, 11, 9, 12, 0, 0, 0, time.UTC)
+
+	node := NewNode("worker1").
+		WithReferenceTime(now).
+		WithRole("worker").
+		WithLabel("kubernetes.io/os", "linux").
+		WithAnnotation("giantswarm.io/node-not-ready-tick", "3").
+		WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+		WithCreationTime(now.Add(-time.Hour)).
+		WithTaint("node.kubernetes.io/unschedulable", "", corev1.TaintEffectNoSchedule).
+		Build()
+
+	expected := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "worker1",
+			CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)),
+			Labels: map[string]string{
+				"role":             "worker",
+				"kubernetes.io/os": "linux",
+			},
+			Annotations: map[string]string{
+				"giantswarm.io/node-not-ready-tick": "3",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{
+					Key:    "node.kubernetes.io/unschedulable",
+					Effect: corev1.TaintEffectNoSchedule,
+				},
+			},
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{
+					Type:              corev1.NodeReady,
+					Status:            corev1.ConditionFalse,
+					LastHeartbeatTime: metav1.NewTime(now.Add(-time.Minute * 10)),
+				},
+			},
+		},
+	}
+
+	if !cmp.Equal(node, expected) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, node))
+	}
+}
+
+func Test_NodeBuilder_buildTwice(t *testing.T) {
+	builder := NewNode("worker1").WithLabel("role", "worker")
+
+	first := builder.Build()
+	first.Labels["role"] = "master"
+
+	second := builder.WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).Build()
+
+	if second.Labels["role"] != "worker" {
+		t.Fatalf("Expected role 'worker' but got '%s'.\n", second.Labels["role"])
+	}
+	if len(first.Status.Conditions) != 0 {
+		t.Fatalf("Expected '0' conditions but got '%d'.\n", len(first.Status.Conditions))
+	}
+	if len(second.Status.Conditions) != 1 {
+		t.Fatalf("Expected '1' conditions but got '%d'.\n", len(second.Status.Conditions))
+	}
+}
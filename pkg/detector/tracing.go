@@ -0,0 +1,99 @@
+package detector
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// tracerName is the instrumentation name of the tracer created from the TracerProvider.
+	tracerName = "github.com/giantswarm/badnodedetector/v3/pkg/detector"
+
+	spanDetectBadNodes = "DetectBadNodes"
+
+	spanAttributeNodeCount    = "nodeCount"
+	spanAttributeBadNodeCount = "badNodeCount"
+	spanAttributeThreshold    = "threshold"
+	spanAttributeOutcome      = "outcome"
+	spanAttributeNodeName     = "nodeName"
+
+	spanOutcomeSuccess   = "success"
+	spanOutcomeError     = "error"
+	spanOutcomeDegraded  = "degraded"
+	spanOutcomeCancelled = "cancelled"
+)
+
+// tracingClient creates a span for every request sent to the Kubernetes API, named after the operation.
+type tracingClient struct {
+	client.Client
+
+	tracer trace.Tracer
+}
+
+func (c tracingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, span := c.start(ctx, apiCallCreate, obj.GetName())
+	defer span.End()
+	return recordSpanError(span, c.Client.Create(ctx, obj, opts...))
+}
+
+func (c tracingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	ctx, span := c.start(ctx, apiCallGet, key.Name)
+	defer span.End()
+	return recordSpanError(span, c.Client.Get(ctx, key, obj))
+}
+
+func (c tracingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx, span := c.start(ctx, apiCallList, "")
+	defer span.End()
+	return recordSpanError(span, c.Client.List(ctx, list, opts...))
+}
+
+func (c tracingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, span := c.start(ctx, apiCallUpdate, obj.GetName())
+	defer span.End()
+	return recordSpanError(span, c.Client.Update(ctx, obj, opts...))
+}
+
+func (c tracingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := c.start(ctx, apiCallPatch, obj.GetName())
+	defer span.End()
+	return recordSpanError(span, c.Client.Patch(ctx, obj, patch, opts...))
+}
+
+func (c tracingClient) start(ctx context.Context, operation string, name string) (context.Context, trace.Span) {
+	ctx, span := c.tracer.Start(ctx, "k8s."+operation, trace.WithSpanKind(trace.SpanKindClient))
+	if name != "" {
+		span.SetAttributes(attribute.String(spanAttributeNodeName, name))
+	}
+	return ctx, span
+}
+
+// recordSpanError records the error on the span, if any, and returns it.
+func recordSpanError(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// endDetectionSpan sets the outcome of the run on the span.
+func endDetectionSpan(span trace.Span, result DetectBadNodesResult, err error, cancelled bool) {
+	span.SetAttributes(attribute.Int(spanAttributeBadNodeCount, len(result.BadNodes)))
+	switch {
+	case cancelled:
+		span.SetAttributes(attribute.String(spanAttributeOutcome, spanOutcomeCancelled))
+		recordSpanError(span, err)
+	case err != nil:
+		span.SetAttributes(attribute.String(spanAttributeOutcome, spanOutcomeError))
+		recordSpanError(span, err)
+	case result.DegradedMode != nil:
+		span.SetAttributes(attribute.String(spanAttributeOutcome, spanOutcomeDegraded))
+	default:
+		span.SetAttributes(attribute.String(spanAttributeOutcome, spanOutcomeSuccess))
+	}
+}
@@ -0,0 +1,177 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testSpan is the part of a recorded span compared by the tests.
+type testSpan struct {
+	Name       string
+	Parent     string
+	Attributes map[string]interface{}
+	Errors     int
+	Status     codes.Code
+}
+
+// recordedSpans returns the spans ended by the recorder in the order they have been ended,
+// with the name of their parent span.
+func recordedSpans(recorder *tracetest.SpanRecorder) []testSpan {
+	names := map[trace.SpanID]string{}
+	for _, s := range recorder.Ended() {
+		names[s.SpanContext().SpanID()] = s.Name()
+	}
+
+	var spans []testSpan
+	for _, s := range recorder.Ended() {
+		span := testSpan{
+			Name:       s.Name(),
+			Parent:     names[s.Parent().SpanID()],
+			Attributes: map[string]interface{}{},
+			Status:     s.Status().Code,
+		}
+		for _, a := range s.Attributes() {
+			span.Attributes[string(a.Key)] = a.Value.AsInterface()
+		}
+		for _, e := range s.Events() {
+			if e.Name == "exception" {
+				span.Errors++
+			}
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+func Test_DetectBadNodes_tracing(t *testing.T) {
+	testCases := []struct {
+		name          string
+		listError     error
+		expectedSpans []testSpan
+	}{
+		{
+			name: "test 0 - spans of a successful run",
+			expectedSpans: []testSpan{
+				{
+					Name:       "k8s." + apiCallList,
+					Parent:     spanDetectBadNodes,
+					Attributes: map[string]interface{}{},
+				},
+				{
+					Name:   "k8s." + apiCallUpdate,
+					Parent: spanDetectBadNodes,
+					Attributes: map[string]interface{}{
+						spanAttributeNodeName: "worker1",
+					},
+				},
+				{
+					Name: spanDetectBadNodes,
+					Attributes: map[string]interface{}{
+						spanAttributeThreshold:    int64(defaultNotReadyTickThreshold),
+						spanAttributeNodeCount:    int64(2),
+						spanAttributeBadNodeCount: int64(1),
+						spanAttributeOutcome:      spanOutcomeSuccess,
+					},
+				},
+			},
+		},
+		{
+			name:      "test 1 - failed list records the error",
+			listError: errors.New("connection refused"),
+			expectedSpans: []testSpan{
+				{
+					Name:       "k8s." + apiCallList,
+					Parent:     spanDetectBadNodes,
+					Attributes: map[string]interface{}{},
+					Errors:     1,
+					Status:     codes.Error,
+				},
+				{
+					Name: spanDetectBadNodes,
+					Attributes: map[string]interface{}{
+						spanAttributeThreshold:    int64(defaultNotReadyTickThreshold),
+						spanAttributeBadNodeCount: int64(0),
+						spanAttributeOutcome:      spanOutcomeError,
+					},
+					Errors: 1,
+					Status: codes.Error,
+				},
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			worker0 := testNode("worker0").
+				WithCondition(corev1.NodeReady, corev1.ConditionTrue, 0).
+				Build()
+			worker1 := testNode("worker1").
+				WithAnnotation(annotationNodeNotReadyTick, "5").
+				WithCondition(corev1.NodeReady, corev1.ConditionFalse, time.Minute*10).
+				Build()
+
+			logger, _ := micrologger.New(micrologger.Config{})
+			recorder := tracetest.NewSpanRecorder()
+
+			d, err := NewDetector(Config{
+				Clock:  &FakeClock{Time: testNow},
+				Logger: logger,
+				K8sClient: &errorClient{
+					Client:    fake.NewClientBuilder().WithObjects(&worker0, &worker1).Build(),
+					listError: tc.listError,
+				},
+				MaxNodeTerminationPercentage: 1,
+				TracerProvider:               sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = d.DetectBadNodes(context.Background())
+			if (err != nil) != (tc.listError != nil) {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			spans := recordedSpans(recorder)
+			if !cmp.Equal(spans, tc.expectedSpans) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedSpans, spans))
+			}
+		})
+	}
+}
+
+func Test_NewDetector_noTracerProvider(t *testing.T) {
+	logger, _ := micrologger.New(micrologger.Config{})
+
+	d, err := NewDetector(Config{
+		Logger:    logger,
+		K8sClient: fake.NewClientBuilder().Build(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := d.k8sClient.(metricsClient).Client.(tracingClient); ok {
+		t.Fatalf("expected the client to not be traced without a tracer provider")
+	}
+
+	// the noop tracer does not record spans
+	_, span := d.tracer.Start(context.Background(), spanDetectBadNodes)
+	if span.IsRecording() {
+		t.Fatalf("expected the noop tracer to not record spans")
+	}
+}
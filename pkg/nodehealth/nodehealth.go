@@ -0,0 +1,28 @@
+package nodehealth
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodeConditionSummary returns the status of every condition of the node by condition type.
+// When the node reports the same condition type multiple times the first one is used.
+func NodeConditionSummary(node corev1.Node) map[corev1.NodeConditionType]corev1.ConditionStatus {
+	summary := map[corev1.NodeConditionType]corev1.ConditionStatus{}
+	for _, c := range node.Status.Conditions {
+		if _, ok := summary[c.Type]; !ok {
+			summary[c.Type] = c.Status
+		}
+	}
+	return summary
+}
+
+// GetCondition returns the condition of the given type and true if the node reports it.
+// When the node reports the same condition type multiple times the first one is returned.
+func GetCondition(node corev1.Node, condType corev1.NodeConditionType) (corev1.NodeCondition, bool) {
+	for _, c := range node.Status.Conditions {
+		if c.Type == condType {
+			return c, true
+		}
+	}
+	return corev1.NodeCondition{}, false
+}
@@ -0,0 +1,179 @@
+package nodehealth
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_NodeConditionSummary(t *testing.T) {
+	testCases := []struct {
+		name            string
+		node            corev1.Node
+		expectedSummary map[corev1.NodeConditionType]corev1.ConditionStatus
+	}{
+		{
+			name:            "test 0 - no conditions",
+			node:            corev1.Node{},
+			expectedSummary: map[corev1.NodeConditionType]corev1.ConditionStatus{},
+		},
+		{
+			name: "test 1 - multiple conditions",
+			node: corev1.Node{
+				Status: corev1.NodeStatus{
+					Conditions: []corev1.NodeCondition{
+						{
+							Type:   corev1.NodeReady,
+							Status: corev1.ConditionTrue,
+						},
+						{
+							Type:   corev1.NodeDiskPressure,
+							Status: corev1.ConditionFalse,
+						},
+						{
+							Type:   "DiskFullKubelet",
+							Status: corev1.ConditionUnknown,
+						},
+					},
+				},
+			},
+			expectedSummary: map[corev1.NodeConditionType]corev1.ConditionStatus{
+				corev1.NodeReady:        corev1.ConditionTrue,
+				corev1.NodeDiskPressure: corev1.ConditionFalse,
+				"DiskFullKubelet":       corev1.ConditionUnknown,
+			},
+		},
+		{
+			name: "test 2 - overlapping conditions",
+			node: corev1.Node{
+				Status: corev1.NodeStatus{
+					Conditions: []corev1.NodeCondition{
+						{
+							Type:   corev1.NodeReady,
+							Status: corev1.ConditionFalse,
+						},
+						{
+							Type:   corev1.NodeReady,
+							Status: corev1.ConditionTrue,
+						},
+					},
+				},
+			},
+			expectedSummary: map[corev1.NodeConditionType]corev1.ConditionStatus{
+				corev1.NodeReady: corev1.ConditionFalse,
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			summary := NodeConditionSummary(tc.node)
+
+			if !cmp.Equal(summary, tc.expectedSummary) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedSummary, summary))
+			}
+		})
+	}
+}
+
+func Test_GetCondition(t *testing.T) {
+	testCases := []struct {
+		name              string
+		node              corev1.Node
+		condType          corev1.NodeConditionType
+		expectedCondition corev1.NodeCondition
+		expectedFound     bool
+	}{
+		{
+			name:          "test 0 - no conditions",
+			node:          corev1.Node{},
+			condType:      corev1.NodeReady,
+			expectedFound: false,
+		},
+		{
+			name: "test 1 - missing condition",
+			node: corev1.Node{
+				Status: corev1.NodeStatus{
+					Conditions: []corev1.NodeCondition{
+						{
+							Type:   corev1.NodeDiskPressure,
+							Status: corev1.ConditionFalse,
+						},
+					},
+				},
+			},
+			condType:      corev1.NodeReady,
+			expectedFound: false,
+		},
+		{
+			name: "test 2 - existing condition",
+			node: corev1.Node{
+				Status: corev1.NodeStatus{
+					Conditions: []corev1.NodeCondition{
+						{
+							Type:   corev1.NodeDiskPressure,
+							Status: corev1.ConditionFalse,
+						},
+						{
+							Type:   corev1.NodeReady,
+							Status: corev1.ConditionTrue,
+							Reason: "KubeletReady",
+						},
+					},
+				},
+			},
+			condType: corev1.NodeReady,
+			expectedCondition: corev1.NodeCondition{
+				Type:   corev1.NodeReady,
+				Status: corev1.ConditionTrue,
+				Reason: "KubeletReady",
+			},
+			expectedFound: true,
+		},
+		{
+			name: "test 3 - overlapping conditions",
+			node: corev1.Node{
+				Status: corev1.NodeStatus{
+					Conditions: []corev1.NodeCondition{
+						{
+							Type:   corev1.NodeReady,
+							Status: corev1.ConditionFalse,
+							Reason: "KubeletNotReady",
+						},
+						{
+							Type:   corev1.NodeReady,
+							Status: corev1.ConditionTrue,
+							Reason: "KubeletReady",
+						},
+					},
+				},
+			},
+			condType: corev1.NodeReady,
+			expectedCondition: corev1.NodeCondition{
+				Type:   corev1.NodeReady,
+				Status: corev1.ConditionFalse,
+				Reason: "KubeletNotReady",
+			},
+			expectedFound: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			condition, found := GetCondition(tc.node, tc.condType)
+			if found != tc.expectedFound {
+				t.Fatalf("Expected found '%t' but got '%t'.\n", tc.expectedFound, found)
+			}
+
+			if !cmp.Equal(condition, tc.expectedCondition) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedCondition, condition))
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Package nodepool groups nodes by their node pool to apply pool specific detection settings.
+package nodepool
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PoolConfig holds the detection settings of a single node pool.
+// Zero values fall back to the settings of the detector.
+type PoolConfig struct {
+	// MaxTerminationPercentage defines the maximum percentage of the nodes of the pool returned as 'marked for termination' at single run.
+	MaxTerminationPercentage float64
+	// NotReadyTickThreshold defines how many times a node of the pool must be seen as NotReady in order to return it as 'marked for termination'.
+	NotReadyTickThreshold int
+	// MaxMasterTerminations defines the maximum number of master nodes of the pool returned as 'marked for termination' at single run.
+	// It can only lower the MaxMasterTerminations of the detector, which applies across all pools.
+	MaxMasterTerminations int
+}
+
+// WithDefaults returns the config with all zero values replaced by the values of defaults.
+func (c PoolConfig) WithDefaults(defaults PoolConfig) PoolConfig {
+	if c.MaxTerminationPercentage == 0 {
+		c.MaxTerminationPercentage = defaults.MaxTerminationPercentage
+	}
+	if c.NotReadyTickThreshold == 0 {
+		c.NotReadyTickThreshold = defaults.NotReadyTickThreshold
+	}
+	if c.MaxMasterTerminations == 0 {
+		c.MaxMasterTerminations = defaults.MaxMasterTerminations
+	}
+	return c
+}
+
+// NodePool is a group of nodes sharing the same value of the node pool label.
+type NodePool struct {
+	// Name is the value of the node pool label, empty for nodes without the label.
+	Name   string
+	Nodes  []corev1.Node
+	Config PoolConfig
+}
+
+// GroupNodesByPool groups the nodes by the value of the poolLabel.
+// Nodes without the label are grouped into a pool with an empty name. The pools are sorted by name
+// and the nodes of each pool keep their order. All nodes belong to a single pool when poolLabel is empty.
+func GroupNodesByPool(nodes []corev1.Node, poolLabel string) []NodePool {
+	index := map[string]int{}
+	var pools []NodePool
+	for _, n := range nodes {
+		name := ""
+		if poolLabel != "" {
+			name = n.Labels[poolLabel]
+		}
+
+		i, ok := index[name]
+		if !ok {
+			i = len(pools)
+			index[name] = i
+			pools = append(pools, NodePool{Name: name})
+		}
+		pools[i].Nodes = append(pools[i].Nodes, n)
+	}
+
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+	return pools
+}
@@ -0,0 +1,163 @@
+package nodepool
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testPoolLabel = "giantswarm.io/machine-deployment"
+
+func Test_GroupNodesByPool(t *testing.T) {
+	testCases := []struct {
+		name           string
+		nodes          []corev1.Node
+		poolLabel      string
+		expectedGroups map[string][]string
+		expectedOrder  []string
+	}{
+		{
+			name:           "test 0 - no nodes",
+			nodes:          nil,
+			poolLabel:      testPoolLabel,
+			expectedGroups: map[string][]string{},
+			expectedOrder:  nil,
+		},
+		{
+			name: "test 1 - multiple pools",
+			nodes: []corev1.Node{
+				testNode("b1", "b"),
+				testNode("a1", "a"),
+				testNode("b2", "b"),
+				testNode("a2", "a"),
+			},
+			poolLabel: testPoolLabel,
+			expectedGroups: map[string][]string{
+				"a": {"a1", "a2"},
+				"b": {"b1", "b2"},
+			},
+			expectedOrder: []string{"a", "b"},
+		},
+		{
+			name: "test 2 - nodes without pool label",
+			nodes: []corev1.Node{
+				testNode("a1", "a"),
+				testNode("master1", ""),
+			},
+			poolLabel: testPoolLabel,
+			expectedGroups: map[string][]string{
+				"":  {"master1"},
+				"a": {"a1"},
+			},
+			expectedOrder: []string{"", "a"},
+		},
+		{
+			name: "test 3 - empty pool label",
+			nodes: []corev1.Node{
+				testNode("a1", "a"),
+				testNode("b1", "b"),
+			},
+			poolLabel: "",
+			expectedGroups: map[string][]string{
+				"": {"a1", "b1"},
+			},
+			expectedOrder: []string{""},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			pools := GroupNodesByPool(tc.nodes, tc.poolLabel)
+
+			var order []string
+			groups := map[string][]string{}
+			for _, p := range pools {
+				order = append(order, p.Name)
+				groups[p.Name] = []string{}
+				for _, n := range p.Nodes {
+					groups[p.Name] = append(groups[p.Name], n.Name)
+				}
+			}
+
+			if !cmp.Equal(order, tc.expectedOrder) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedOrder, order))
+			}
+			if !cmp.Equal(groups, tc.expectedGroups) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedGroups, groups))
+			}
+		})
+	}
+}
+
+func Test_PoolConfig_WithDefaults(t *testing.T) {
+	defaults := PoolConfig{
+		MaxTerminationPercentage: 0.1,
+		NotReadyTickThreshold:    6,
+		MaxMasterTerminations:    1,
+	}
+
+	testCases := []struct {
+		name           string
+		config         PoolConfig
+		expectedConfig PoolConfig
+	}{
+		{
+			name:           "test 0 - zero config",
+			config:         PoolConfig{},
+			expectedConfig: defaults,
+		},
+		{
+			name: "test 1 - partial config",
+			config: PoolConfig{
+				NotReadyTickThreshold: 3,
+			},
+			expectedConfig: PoolConfig{
+				MaxTerminationPercentage: 0.1,
+				NotReadyTickThreshold:    3,
+				MaxMasterTerminations:    1,
+			},
+		},
+		{
+			name: "test 2 - full config",
+			config: PoolConfig{
+				MaxTerminationPercentage: 0.5,
+				NotReadyTickThreshold:    3,
+				MaxMasterTerminations:    2,
+			},
+			expectedConfig: PoolConfig{
+				MaxTerminationPercentage: 0.5,
+				NotReadyTickThreshold:    3,
+				MaxMasterTerminations:    2,
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			config := tc.config.WithDefaults(defaults)
+			if config != tc.expectedConfig {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedConfig, config))
+			}
+		})
+	}
+}
+
+func testNode(name string, pool string) corev1.Node {
+	n := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{},
+		},
+	}
+	if pool != "" {
+		n.Labels[testPoolLabel] = pool
+	}
+	return n
+}